@@ -0,0 +1,177 @@
+// Package apperr คือ error ที่ controller และ middleware ส่งกลับให้ Fiber โดย Handler จะแปลงเป็น
+// response รูปแบบ RFC 7807 (application/problem+json) เหมือนกันทุก endpoint
+package apperr
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// MIMEProblemJSON คือ Content-Type ของ error response ทุกตัว
+const MIMEProblemJSON = "application/problem+json"
+
+// code ที่ frontend ใช้แยกประเภท error ได้โดยไม่ต้องอ่าน message
+const (
+	CodeBadRequest        = "bad_request"
+	CodeInvalidBody       = "invalid_body"
+	CodeValidation        = "validation_failed"
+	CodeInvalidCredential = "invalid_credentials"
+	CodeNotApproved       = "account_not_approved"
+	CodeUnauthorized      = "unauthorized"
+	CodeInvalidToken      = "invalid_token"
+	CodeSessionExpired    = "session_expired"
+	CodeForbidden         = "forbidden"
+	CodeNotFound          = "not_found"
+	CodeConflict          = "conflict"
+	CodeInsufficientStock = "insufficient_stock"
+	CodeOrderStatus       = "invalid_order_status"
+	CodeCouponInvalid     = "coupon_invalid"
+	CodeTooManyRequests   = "too_many_requests"
+	CodeInvalidSignature  = "invalid_signature"
+	CodePaymentProvider   = "payment_provider_error"
+	// CodeIdempotencyMismatch คือ Idempotency-Key ที่เคยใช้กับ request อื่นถูกส่งมากับ payload ที่ต่างไป
+	CodeIdempotencyMismatch = "idempotency_key_mismatch"
+	// CodeIdempotencyInProgress คือ request แรกของ Idempotency-Key นี้ยังทำงานไม่เสร็จ
+	CodeIdempotencyInProgress = "idempotency_key_in_progress"
+	// CodePreconditionFailed คือ resource ถูกแก้ไปแล้วหลังจากที่ client อ่าน (If-Match ไม่ตรงกับ ETag ปัจจุบัน)
+	CodePreconditionFailed = "precondition_failed"
+	CodeInternal           = "internal_error"
+)
+
+// Error คือ error ที่มี HTTP status, code และข้อความสำหรับผู้ใช้ ส่วน Err คือสาเหตุจริงที่จะถูก log
+// แต่ไม่ถูกส่งกลับไปให้ client
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Details any
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithCode เปลี่ยน code ให้เจาะจงกว่า code ตั้งต้นของ status นั้น
+func (e *Error) WithCode(code string) *Error {
+	e.Code = code
+	return e
+}
+
+// WithDetails แนบรายละเอียดเพิ่มเติม เช่น error ราย field
+func (e *Error) WithDetails(details any) *Error {
+	e.Details = details
+	return e
+}
+
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func BadRequest(message string) *Error {
+	return New(fiber.StatusBadRequest, CodeBadRequest, message)
+}
+
+// InvalidBody ใช้เมื่อ parse body ของ request ไม่ได้
+func InvalidBody(err error) *Error {
+	e := New(fiber.StatusBadRequest, CodeInvalidBody, "Request body is invalid.")
+	e.Err = err
+	return e
+}
+
+func Unauthorized(message string) *Error {
+	return New(fiber.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(fiber.StatusForbidden, CodeForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return New(fiber.StatusNotFound, CodeNotFound, message)
+}
+
+func Conflict(message string) *Error {
+	return New(fiber.StatusConflict, CodeConflict, message)
+}
+
+// PreconditionFailed ใช้เมื่อเงื่อนไขใน header เช่น If-Match ไม่เป็นจริง
+func PreconditionFailed(message string) *Error {
+	return New(fiber.StatusPreconditionFailed, CodePreconditionFailed, message)
+}
+
+// Internal ใช้กับความผิดพลาดฝั่ง server โดย err จะถูก log พร้อม request ID
+func Internal(message string, err error) *Error {
+	e := New(fiber.StatusInternalServerError, CodeInternal, message)
+	e.Err = err
+	return e
+}
+
+// Problem คือ body ของ error response ตาม RFC 7807 พร้อม field เสริม code, request_id และ details
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+	Details   any    `json:"details,omitempty"`
+}
+
+// Handler คือ fiber.Config.ErrorHandler ที่แปลงทุก error ให้เป็น problem+json
+// error ที่ไม่ใช่ *Error และไม่ใช่ *fiber.Error จะถูกมองเป็น internal error และไม่เปิดเผยรายละเอียด
+func Handler(c *fiber.Ctx, err error) error {
+	appErr := From(err)
+	requestID := c.GetRespHeader(fiber.HeaderXRequestID)
+
+	if appErr.Status >= fiber.StatusInternalServerError {
+		log.Printf("[%s] %s %s: %v", requestID, c.Method(), c.OriginalURL(), err)
+	}
+
+	return c.Status(appErr.Status).JSON(Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(appErr.Status),
+		Status:    appErr.Status,
+		Detail:    appErr.Message,
+		Instance:  c.OriginalURL(),
+		Code:      appErr.Code,
+		RequestID: requestID,
+		Details:   appErr.Details,
+	}, MIMEProblemJSON)
+}
+
+// From แปลง error ใด ๆ ให้เป็น *Error
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	// error จาก Fiber เอง เช่น route ไม่มีอยู่ หรือ body ใหญ่เกิน
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return New(fiberErr.Code, codeForStatus(fiberErr.Code), fiberErr.Message)
+	}
+
+	return Internal("Internal server error.", err)
+}
+
+// codeForStatus สร้าง code จากชื่อ status เช่น 405 -> method_not_allowed
+func codeForStatus(status int) string {
+	if status >= fiber.StatusInternalServerError {
+		return CodeInternal
+	}
+	return strings.ToLower(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+}
@@ -0,0 +1,529 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config คือค่าตั้งค่าทั้งหมดของแอป โหลดครั้งเดียวตอนเริ่มต้นแล้วส่งต่อไปให้ส่วนต่าง ๆ
+type Config struct {
+	App       AppConfig       `yaml:"app" toml:"app"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database"`
+	Auth      AuthConfig      `yaml:"auth" toml:"auth"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	Payment   PaymentConfig   `yaml:"payment" toml:"payment"`
+	Pricing   PricingConfig   `yaml:"pricing" toml:"pricing"`
+	Inventory InventoryConfig `yaml:"inventory" toml:"inventory"`
+	// Idempotency คือการเก็บ response ของ request ที่ส่ง Idempotency-Key มาไว้ตอบซ้ำ
+	Idempotency IdempotencyConfig `yaml:"idempotency" toml:"idempotency"`
+	// Catalog คือการ cache response ของ GET /product และ GET /product/:productId ที่เปิดให้ทุกคนอ่าน
+	Catalog CatalogConfig `yaml:"catalog" toml:"catalog"`
+}
+
+type AppConfig struct {
+	Env  string `yaml:"env" toml:"env"`
+	Port int    `yaml:"port" toml:"port"`
+	// UploadDir คือ folder ที่เก็บรูปภาพสินค้า และถูก serve ที่ /uploads
+	UploadDir string `yaml:"upload_dir" toml:"upload_dir"`
+	// ShutdownTimeout คือเวลาสูงสุดที่รอ request ที่ค้างอยู่ให้เสร็จก่อนปิด server
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+}
+
+type DatabaseConfig struct {
+	// Driver คือชนิดฐานข้อมูลที่ใช้: mysql, postgres หรือ sqlite
+	Driver string `yaml:"driver" toml:"driver"`
+	// DSN ถ้าตั้งไว้จะใช้แทนการสร้าง DSN จาก Host/Port/User/Password/Name
+	DSN      string `yaml:"dsn" toml:"dsn"`
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	// Name คือชื่อ database สำหรับ mysql/postgres หรือ path ของไฟล์สำหรับ sqlite (":memory:" คือ in-memory)
+	Name    string `yaml:"name" toml:"name"`
+	SSLMode string `yaml:"ssl_mode" toml:"ssl_mode"`
+	// AutoMigrate ให้ apply migration ที่ค้างอยู่ตอนเริ่ม server แทนการรัน migrate up เอง
+	AutoMigrate bool `yaml:"auto_migrate" toml:"auto_migrate"`
+}
+
+type AuthConfig struct {
+	AccessSecret    string        `yaml:"access_secret" toml:"access_secret"`
+	RefreshSecret   string        `yaml:"refresh_secret" toml:"refresh_secret"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl" toml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" toml:"refresh_token_ttl"`
+	SessionTimeout  time.Duration `yaml:"session_timeout" toml:"session_timeout"`
+}
+
+type RateLimitConfig struct {
+	Max        int           `yaml:"max" toml:"max"`
+	Expiration time.Duration `yaml:"expiration" toml:"expiration"`
+}
+
+type PaymentConfig struct {
+	// Provider คือ payment gateway ที่ใช้ ตอนนี้รองรับเฉพาะ fake สำหรับ local/dev/test
+	Provider string `yaml:"provider" toml:"provider"`
+	// WebhookSecret ใช้ตรวจลายเซ็นของ webhook ที่ provider ส่งมา
+	WebhookSecret string `yaml:"webhook_secret" toml:"webhook_secret"`
+	// Currency คือสกุลเงินของราคาสินค้าทั้งหมด (ISO 4217)
+	Currency string `yaml:"currency" toml:"currency"`
+}
+
+// Payment providers ที่รองรับ
+const (
+	PaymentFake = "fake"
+)
+
+// PricingConfig คือวิธีคิดภาษีและค่าส่งของร้าน ราคาทุกค่าเป็นหน่วยเดียวกับราคาสินค้า
+type PricingConfig struct {
+	// Tax คือวิธีคิดภาษี: none หรือ vat
+	Tax string `yaml:"tax" toml:"tax"`
+	// VATRate คือเปอร์เซ็นต์ภาษีมูลค่าเพิ่มที่บวกเพิ่มจากยอดหลังหักส่วนลดรวมค่าส่ง
+	VATRate float64 `yaml:"vat_rate" toml:"vat_rate"`
+	// Shipping คือวิธีคิดค่าส่ง: none, flat หรือ weight
+	Shipping string `yaml:"shipping" toml:"shipping"`
+	// ShippingFlat คือค่าส่งต่อ order เมื่อใช้ flat
+	ShippingFlat int `yaml:"shipping_flat" toml:"shipping_flat"`
+	// ShippingBase และ ShippingPerKg ใช้กับ weight: ค่าส่ง = ShippingBase + ShippingPerKg ต่อทุกกิโลกรัมที่เริ่ม
+	ShippingBase  int `yaml:"shipping_base" toml:"shipping_base"`
+	ShippingPerKg int `yaml:"shipping_per_kg" toml:"shipping_per_kg"`
+	// FreeShippingOver ถ้ามากกว่า 0 จะไม่คิดค่าส่งเมื่อยอดหลังหักส่วนลดถึงค่านี้
+	FreeShippingOver int `yaml:"free_shipping_over" toml:"free_shipping_over"`
+}
+
+// วิธีคิดภาษีและค่าส่งที่รองรับ
+const (
+	TaxNone        = "none"
+	TaxVAT         = "vat"
+	ShippingNone   = "none"
+	ShippingFlat   = "flat"
+	ShippingWeight = "weight"
+)
+
+// InventoryConfig คือการกันสต็อกให้ order ที่ยังไม่ได้ยืนยัน
+type InventoryConfig struct {
+	// ReservationTTL คือเวลาที่สินค้าถูกกันไว้ให้ order ใหม่ก่อนต้องยืนยันหรือชำระเงิน
+	ReservationTTL time.Duration `yaml:"reservation_ttl" toml:"reservation_ttl"`
+	// SweepInterval คือความถี่ที่ server ตรวจหาการกันสต็อกที่หมดเวลาแล้วเพื่อยกเลิก order และคืนสินค้า
+	SweepInterval time.Duration `yaml:"sweep_interval" toml:"sweep_interval"`
+}
+
+type IdempotencyConfig struct {
+	// TTL คือเวลาที่เก็บ response แรกไว้ หลังจากนี้คีย์เดิมจะถูกนับเป็น request ใหม่
+	TTL time.Duration `yaml:"ttl" toml:"ttl"`
+}
+
+type CatalogConfig struct {
+	// MaxAge คือ max-age ใน Cache-Control ที่ browser และ proxy เก็บ response ไว้ใช้ได้โดยไม่ต้องถามกลับมา
+	// 0 คือต้องถามกลับมาทุกครั้ง (no-cache) ซึ่งยังได้ 304 ถ้าข้อมูลไม่เปลี่ยน
+	MaxAge time.Duration `yaml:"max_age" toml:"max_age"`
+	// CacheTTL คือเวลาที่เก็บ response ไว้ใน memory ของ process นี้ 0 คือปิด cache
+	// cache ถูกล้างทุกครั้งที่ endpoint หรือ sweeper เปลี่ยนสินค้า จำนวนสินค้า หรือจำนวนที่ถูกกันไว้
+	CacheTTL time.Duration `yaml:"cache_ttl" toml:"cache_ttl"`
+	// CacheSize คือจำนวน response สูงสุดที่เก็บไว้ใน memory (แยกตาม URL รวม query string)
+	CacheSize int `yaml:"cache_size" toml:"cache_size"`
+	// RateLimit คือ limiter ของ GET /product และ GET /product/:productId ซึ่งแยกจาก limiter หลัก
+	// เพราะหน้าร้านเรียกรายการสินค้าบ่อยกว่า endpoint อื่นมาก (Max 0 คือไม่จำกัด)
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+}
+
+// Addr คืนค่า address สำหรับ app.Listen
+func (a AppConfig) Addr() string {
+	return fmt.Sprintf(":%d", a.Port)
+}
+
+// Database drivers ที่รองรับ
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// ConnectionString คืน DSN ตาม driver ที่เลือก หรือค่า DSN ที่ตั้งไว้ตรง ๆ
+func (d DatabaseConfig) ConnectionString() string {
+	if d.DSN != "" {
+		return d.DSN
+	}
+
+	switch d.Driver {
+	case DriverPostgres:
+		return fmt.Sprintf(
+			"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			d.Host,
+			d.Port,
+			d.User,
+			d.Password,
+			d.Name,
+			d.SSLMode,
+		)
+	case DriverSQLite:
+		return d.Name
+	default:
+		return fmt.Sprintf(
+			"%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=true&loc=Local",
+			d.User,
+			d.Password,
+			d.Host,
+			d.Port,
+			d.Name,
+		)
+	}
+}
+
+// InMemory บอกว่าเป็น sqlite แบบ in-memory หรือไม่
+func (d DatabaseConfig) InMemory() bool {
+	return d.Driver == DriverSQLite && d.ConnectionString() == ":memory:"
+}
+
+// Default คืนค่าเริ่มต้นที่ตรงกับค่าที่เคย hardcode ไว้ใน main.go
+func Default() Config {
+	return Config{
+		App: AppConfig{
+			Env:             "development",
+			Port:            3000,
+			UploadDir:       "./uploads",
+			ShutdownTimeout: 10 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Driver:  DriverMySQL,
+			Host:    "127.0.0.1",
+			Port:    3306,
+			User:    "root",
+			Name:    "golang_test",
+			SSLMode: "disable",
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL:  time.Minute * 15,
+			RefreshTokenTTL: time.Hour * 24,
+			SessionTimeout:  time.Minute * 10,
+		},
+		RateLimit: RateLimitConfig{
+			Max:        10,
+			Expiration: 30 * time.Second,
+		},
+		Payment: PaymentConfig{
+			Provider: PaymentFake,
+			Currency: "THB",
+		},
+		Pricing: PricingConfig{
+			Tax:      TaxNone,
+			Shipping: ShippingNone,
+		},
+		Inventory: InventoryConfig{
+			ReservationTTL: 15 * time.Minute,
+			SweepInterval:  time.Minute,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL: 24 * time.Hour,
+		},
+		Catalog: CatalogConfig{
+			CacheSize: 1000,
+			RateLimit: RateLimitConfig{
+				Max:        100,
+				Expiration: 30 * time.Second,
+			},
+		},
+	}
+}
+
+// Load โหลดค่าตั้งค่าตามลำดับ: ค่าเริ่มต้น -> ไฟล์ config จาก CONFIG_FILE (ถ้ามี) -> environment variables (รวมถึง .env)
+// แล้วตรวจสอบความถูกต้องก่อนส่งกลับ
+func Load() (*Config, error) {
+	// โหลด .env file ถ้าไม่มีไฟล์ก็ใช้ environment ของระบบแทน
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("config: loading .env: %w", err)
+	}
+
+	cfg := Default()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyEnv(&cfg); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// loadFile อ่านไฟล์ config แบบ YAML หรือ TOML ตามนามสกุลไฟล์
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: reading %s: %w", path, err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("config: unsupported file type %q (use .yaml, .yml or .toml)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config: parsing %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv เขียนทับค่าด้วย environment variables ที่ถูกตั้งไว้
+func applyEnv(cfg *Config) error {
+	var errs []error
+
+	setString := func(key string, dst *string) {
+		if v, ok := os.LookupEnv(key); ok {
+			*dst = v
+		}
+	}
+	setInt := func(key string, dst *int) {
+		if v, ok := os.LookupEnv(key); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not an integer", key, v))
+				return
+			}
+			*dst = n
+		}
+	}
+	setBool := func(key string, dst *bool) {
+		if v, ok := os.LookupEnv(key); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a boolean", key, v))
+				return
+			}
+			*dst = b
+		}
+	}
+	setFloat := func(key string, dst *float64) {
+		if v, ok := os.LookupEnv(key); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a number", key, v))
+				return
+			}
+			*dst = f
+		}
+	}
+	setDuration := func(key string, dst *time.Duration) {
+		if v, ok := os.LookupEnv(key); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a duration", key, v))
+				return
+			}
+			*dst = d
+		}
+	}
+
+	setString("APP_ENV", &cfg.App.Env)
+	setInt("APP_PORT", &cfg.App.Port)
+	setString("UPLOAD_DIR", &cfg.App.UploadDir)
+	setDuration("SHUTDOWN_TIMEOUT", &cfg.App.ShutdownTimeout)
+
+	setString("DB_DRIVER", &cfg.Database.Driver)
+	setString("DATABASE_URL", &cfg.Database.DSN)
+	setString("DB_HOST", &cfg.Database.Host)
+	setInt("DB_PORT", &cfg.Database.Port)
+	setString("DB_USER", &cfg.Database.User)
+	setString("DB_PASSWORD", &cfg.Database.Password)
+	setString("DB_NAME", &cfg.Database.Name)
+	setString("DB_SSLMODE", &cfg.Database.SSLMode)
+	setBool("DB_AUTO_MIGRATE", &cfg.Database.AutoMigrate)
+
+	setString("ACCESS_SECRET_KEY", &cfg.Auth.AccessSecret)
+	setString("REFRESH_SECRET_KEY", &cfg.Auth.RefreshSecret)
+	setDuration("ACCESS_TOKEN_TTL", &cfg.Auth.AccessTokenTTL)
+	setDuration("REFRESH_TOKEN_TTL", &cfg.Auth.RefreshTokenTTL)
+	setDuration("SESSION_TIMEOUT", &cfg.Auth.SessionTimeout)
+
+	setInt("RATE_LIMIT_MAX", &cfg.RateLimit.Max)
+	setDuration("RATE_LIMIT_EXPIRATION", &cfg.RateLimit.Expiration)
+
+	setString("PAYMENT_PROVIDER", &cfg.Payment.Provider)
+	setString("PAYMENT_WEBHOOK_SECRET", &cfg.Payment.WebhookSecret)
+	setString("PAYMENT_CURRENCY", &cfg.Payment.Currency)
+
+	setString("PRICING_TAX", &cfg.Pricing.Tax)
+	setFloat("PRICING_VAT_RATE", &cfg.Pricing.VATRate)
+	setString("PRICING_SHIPPING", &cfg.Pricing.Shipping)
+	setInt("PRICING_SHIPPING_FLAT", &cfg.Pricing.ShippingFlat)
+	setInt("PRICING_SHIPPING_BASE", &cfg.Pricing.ShippingBase)
+	setInt("PRICING_SHIPPING_PER_KG", &cfg.Pricing.ShippingPerKg)
+	setInt("PRICING_FREE_SHIPPING_OVER", &cfg.Pricing.FreeShippingOver)
+
+	setDuration("INVENTORY_RESERVATION_TTL", &cfg.Inventory.ReservationTTL)
+	setDuration("INVENTORY_SWEEP_INTERVAL", &cfg.Inventory.SweepInterval)
+
+	setDuration("IDEMPOTENCY_TTL", &cfg.Idempotency.TTL)
+
+	setDuration("CATALOG_MAX_AGE", &cfg.Catalog.MaxAge)
+	setDuration("CATALOG_CACHE_TTL", &cfg.Catalog.CacheTTL)
+	setInt("CATALOG_CACHE_SIZE", &cfg.Catalog.CacheSize)
+	setInt("CATALOG_RATE_LIMIT_MAX", &cfg.Catalog.RateLimit.Max)
+	setDuration("CATALOG_RATE_LIMIT_EXPIRATION", &cfg.Catalog.RateLimit.Expiration)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("config: invalid environment: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// validate ตรวจสอบค่าของ database ตาม driver ที่เลือก
+func (d DatabaseConfig) validate() []error {
+	var errs []error
+
+	switch d.Driver {
+	case DriverMySQL, DriverPostgres:
+		// ถ้าตั้ง DSN ไว้แล้วไม่ต้องตรวจสอบค่าแยก
+		if d.DSN != "" {
+			return nil
+		}
+		if d.Host == "" {
+			errs = append(errs, errors.New("database.host is required"))
+		}
+		if d.Port <= 0 || d.Port > 65535 {
+			errs = append(errs, fmt.Errorf("database.port must be between 1 and 65535, got %d", d.Port))
+		}
+		if d.User == "" {
+			errs = append(errs, errors.New("database.user is required"))
+		}
+		if d.Name == "" {
+			errs = append(errs, errors.New("database.name is required"))
+		}
+	case DriverSQLite:
+		if d.ConnectionString() == "" {
+			errs = append(errs, errors.New("database.name (file path or :memory:) is required for sqlite"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("database.driver must be one of mysql, postgres, sqlite, got %q", d.Driver))
+	}
+
+	return errs
+}
+
+// validate ตรวจสอบวิธีคิดภาษีและค่าส่งที่เลือกพร้อมค่าที่วิธีนั้นใช้
+func (p PricingConfig) validate() []error {
+	var errs []error
+
+	switch p.Tax {
+	case TaxNone:
+	case TaxVAT:
+		if p.VATRate <= 0 || p.VATRate > 100 {
+			errs = append(errs, fmt.Errorf("pricing.vat_rate must be greater than 0 and at most 100, got %v", p.VATRate))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("pricing.tax must be one of none, vat, got %q", p.Tax))
+	}
+
+	switch p.Shipping {
+	case ShippingNone, ShippingFlat, ShippingWeight:
+	default:
+		errs = append(errs, fmt.Errorf("pricing.shipping must be one of none, flat, weight, got %q", p.Shipping))
+	}
+	if p.ShippingFlat < 0 || p.ShippingBase < 0 || p.ShippingPerKg < 0 {
+		errs = append(errs, errors.New("pricing shipping fees must not be negative"))
+	}
+	if p.FreeShippingOver < 0 {
+		errs = append(errs, errors.New("pricing.free_shipping_over must not be negative"))
+	}
+
+	return errs
+}
+
+// Validate ตรวจสอบว่าค่าที่จำเป็นถูกตั้งไว้ครบและอยู่ในช่วงที่ใช้งานได้
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.App.Port <= 0 || c.App.Port > 65535 {
+		errs = append(errs, fmt.Errorf("app.port must be between 1 and 65535, got %d", c.App.Port))
+	}
+	if c.App.UploadDir == "" {
+		errs = append(errs, errors.New("app.upload_dir is required"))
+	}
+	if c.App.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("app.shutdown_timeout must be positive"))
+	}
+
+	errs = append(errs, c.Database.validate()...)
+
+	if c.Auth.AccessSecret == "" {
+		errs = append(errs, errors.New("auth.access_secret (ACCESS_SECRET_KEY) is required"))
+	}
+	if c.Auth.RefreshSecret == "" {
+		errs = append(errs, errors.New("auth.refresh_secret (REFRESH_SECRET_KEY) is required"))
+	}
+	if c.Auth.AccessTokenTTL <= 0 {
+		errs = append(errs, errors.New("auth.access_token_ttl must be positive"))
+	}
+	if c.Auth.RefreshTokenTTL <= 0 {
+		errs = append(errs, errors.New("auth.refresh_token_ttl must be positive"))
+	}
+	if c.Auth.SessionTimeout <= 0 {
+		errs = append(errs, errors.New("auth.session_timeout must be positive"))
+	}
+
+	if c.RateLimit.Max < 0 {
+		errs = append(errs, errors.New("rate_limit.max must not be negative"))
+	}
+	if c.RateLimit.Max > 0 && c.RateLimit.Expiration <= 0 {
+		errs = append(errs, errors.New("rate_limit.expiration must be positive when rate_limit.max is set"))
+	}
+
+	if c.Payment.Provider != PaymentFake {
+		errs = append(errs, fmt.Errorf("payment.provider must be one of fake, got %q", c.Payment.Provider))
+	}
+	if c.Payment.WebhookSecret == "" {
+		errs = append(errs, errors.New("payment.webhook_secret (PAYMENT_WEBHOOK_SECRET) is required"))
+	}
+	if len(c.Payment.Currency) != 3 {
+		errs = append(errs, fmt.Errorf("payment.currency must be a 3-letter ISO 4217 code, got %q", c.Payment.Currency))
+	}
+
+	errs = append(errs, c.Pricing.validate()...)
+
+	if c.Inventory.ReservationTTL <= 0 {
+		errs = append(errs, errors.New("inventory.reservation_ttl must be positive"))
+	}
+	if c.Inventory.SweepInterval <= 0 {
+		errs = append(errs, errors.New("inventory.sweep_interval must be positive"))
+	}
+	if c.Idempotency.TTL <= 0 {
+		errs = append(errs, errors.New("idempotency.ttl must be positive"))
+	}
+	if c.Catalog.MaxAge < 0 {
+		errs = append(errs, errors.New("catalog.max_age must not be negative"))
+	}
+	if c.Catalog.CacheTTL < 0 {
+		errs = append(errs, errors.New("catalog.cache_ttl must not be negative"))
+	}
+	if c.Catalog.CacheTTL > 0 && c.Catalog.CacheSize <= 0 {
+		errs = append(errs, errors.New("catalog.cache_size must be positive when catalog.cache_ttl is set"))
+	}
+	if c.Catalog.RateLimit.Max < 0 {
+		errs = append(errs, errors.New("catalog.rate_limit.max must not be negative"))
+	}
+	if c.Catalog.RateLimit.Max > 0 && c.Catalog.RateLimit.Expiration <= 0 {
+		errs = append(errs, errors.New("catalog.rate_limit.expiration must be positive when catalog.rate_limit.max is set"))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("config: invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
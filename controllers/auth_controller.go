@@ -1,11 +1,12 @@
 package controllers
 
 import (
+	"go-fiber-test/apperr"
 	"go-fiber-test/auth"
-	"go-fiber-test/database"
+	"go-fiber-test/config"
+	"go-fiber-test/dto"
 	m "go-fiber-test/models"
-	"os"
-	"regexp"
+	"go-fiber-test/store"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -13,109 +14,90 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-func Register(c *fiber.Ctx) error {
-	db := database.DBConn
-	var user m.User
-
-	if err := c.BodyParser(&user); err != nil {
-		return c.Status(503).SendString(err.Error())
-	}
+type AuthController struct {
+	store store.Store
+	cfg   config.AuthConfig
+}
 
-	// validate username using regexp
-	usernamePattern := `^[a-zA-Z0-9\_\-]+$`
-	usernameMatched, err := regexp.MatchString(usernamePattern, user.Username)
+func NewAuthController(s store.Store, cfg config.AuthConfig) *AuthController {
+	return &AuthController{store: s, cfg: cfg}
+}
 
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": err.Error(),
-		})
-	}
+func (h *AuthController) Register(c *fiber.Ctx) error {
+	var input dto.RegisterRequest
 
-	if !usernameMatched {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Username contains invalid characters. Only (a-z), (A-Z), (0-9), -, _",
-		})
+	// username ถูกตรวจสอบด้วย rule username ใน dto (a-z, A-Z, 0-9, -, _)
+	if err := bind(c, &input); err != nil {
+		return err
 	}
 
 	// ตรวจสอบว่ามี User นี้อยู่แล้วไหม
-	var existingUser m.User
-	if err := db.Where("Username = ?", user.Username).First(&existingUser).Error; err == nil {
-		return c.Status(fiber.StatusBadRequest).SendString("User Already Exists.")
+	if _, err := h.store.Users().GetByUsername(input.Username); err == nil {
+		return apperr.Conflict("User Already Exists.")
 	}
 
 	// Hash Password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString("Error Hashing Password.")
+		return apperr.Internal("Error Hashing Password.", err)
+	}
+
+	user := m.User{
+		Username:  input.Username,
+		Password:  string(hashedPassword),
+		FirstName: input.FirstName,
+		LastName:  input.LastName,
 	}
-	user.Password = string(hashedPassword)
 
 	// สร้างและบันทึกข้อมูลผู้ใช้ใหม่
-	if err := db.Create(&user).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString("Error Creating User.")
+	if err := h.store.Users().Create(&user); err != nil {
+		return apperr.Internal("Error Creating User.", err)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"data":    user,
+		"data":    dto.NewUserResponse(&user),
 		"message": "Register Success!!!",
 	})
 }
 
-func Login(c *fiber.Ctx) error {
-	db := database.DBConn
-	var user m.User
-	var input m.User
+func (h *AuthController) Login(c *fiber.Ctx) error {
+	var input dto.LoginRequest
 
-	if err := c.BodyParser(&input); err != nil {
-		return c.Status(503).SendString(err.Error())
+	if err := bind(c, &input); err != nil {
+		return err
 	}
 
 	// ตรวจสอบว่า User นี้มีอยู่ระบบหรือไม่
-	if err := db.Where("Username = ?", input.Username).First(&user).Error; err != nil {
-		return c.Status(fiber.StatusBadRequest).SendString("Invalid login, please try again.")
+	user, err := h.store.Users().GetByUsername(input.Username)
+	if err != nil {
+		return apperr.BadRequest("Invalid login, please try again.").WithCode(apperr.CodeInvalidCredential)
 	}
 
 	// ตรวจสอบว่า User นี้ได้รับการ Approve แล้วหรือยัง
 	if !user.Approve {
-		return c.Status(fiber.StatusBadRequest).SendString("This account has not been approved yet.")
+		return apperr.BadRequest("This account has not been approved yet.").WithCode(apperr.CodeNotApproved)
 	}
 
 	// ตรวจสอบ Password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
-		return c.Status(fiber.StatusBadRequest).SendString("Invalid login, please try again.")
+		return apperr.BadRequest("Invalid login, please try again.").WithCode(apperr.CodeInvalidCredential)
 	}
 
 	// สร้าง Access Token
-	accessSecretKey := os.Getenv("ACCESS_SECRET_KEY")
-	accessToken, err := auth.GenerateToken(user, time.Minute*15, accessSecretKey)
+	accessToken, err := auth.GenerateToken(*user, h.cfg.AccessTokenTTL, h.cfg.AccessSecret)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString("Error creating access token.")
+		return apperr.Internal("Error creating access token.", err)
 	}
 
 	// สร้าง Refresh Token
-	refreshSecretKey := os.Getenv("REFRESH_SECRET_KEY")
-	refreshToken, err := auth.GenerateToken(user, time.Hour*24, refreshSecretKey)
+	refreshToken, err := auth.GenerateToken(*user, h.cfg.RefreshTokenTTL, h.cfg.RefreshSecret)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString("Error creating refresh token.")
+		return apperr.Internal("Error creating refresh token.", err)
 	}
 
-	// ตั้งค่า originalTime และบันทึกลงใน Session
-	session := m.Session{}
-	if err := db.Where("user_id = ?", user.ID).First(&session).Error; err != nil {
-		// ถ้าไม่มี session --> สร้าง session ใหม่
-		session = m.Session{
-			UserID:     user.ID,
-			LastActive: time.Now(),
-		}
-		if err := db.Create(&session).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString("Error creating session.")
-		}
-	} else {
-		// ถ้ามี session อยู่แล้ว --> อัปเดต LastActive
-		session.LastActive = time.Now()
-		if err := db.Save(&session).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString("Error updating session.")
-		}
+	// สร้าง session ใหม่ หรือ อัปเดต LastActive ถ้ามี session อยู่แล้ว
+	if err := h.store.Sessions().Touch(user.ID, time.Now()); err != nil {
+		return apperr.Internal("Error creating session.", err)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -126,40 +108,35 @@ func Login(c *fiber.Ctx) error {
 	})
 }
 
-func Logout(c *fiber.Ctx) error {
-	db := database.DBConn
+func (h *AuthController) Logout(c *fiber.Ctx) error {
 	tokenString := c.Get("Authorization")
 
 	if tokenString == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "Authorization header missing",
-		})
+		return apperr.Unauthorized("Authorization header missing.")
 	}
 
 	tokenString = tokenString[len("Bearer "):]
 
-	// parse token เพื่ออ่าน token และดึง ACCESS_SECRET_KEY จาก env มาตรวจสอบว่า token ถูกต้องไหม
+	// parse token เพื่ออ่าน token และใช้ access secret จาก config มาตรวจสอบว่า token ถูกต้องไหม
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("ACCESS_SECRET_KEY")), nil
+		return []byte(h.cfg.AccessSecret), nil
 	})
 
 	// ตรวจสอบ token ที่ถูก parse ว่าถูกต้องหรือ (หมดอายุหรือถูกดัดแปลงไหม)
 	if err != nil || !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).SendString("Invalid or expired token")
+		return apperr.Unauthorized("Invalid or expired token").WithCode(apperr.CodeInvalidToken)
 	}
 
 	// ดึง cliams จาก JWT
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).SendString("Invalid token claims.")
+		return apperr.Unauthorized("Invalid token claims.").WithCode(apperr.CodeInvalidToken)
 	}
 
 	userID := uint(claims["UserID"].(float64))
 
-	if err := db.Delete(&m.Session{}, "user_id = ?", userID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to log out.",
-		})
+	if err := h.store.Sessions().Delete(userID); err != nil {
+		return apperr.Internal("Failed to log out.", err)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -167,11 +144,11 @@ func Logout(c *fiber.Ctx) error {
 	})
 }
 
-func RefreshToken(c *fiber.Ctx) error {
+func (h *AuthController) RefreshToken(c *fiber.Ctx) error {
 	refreshToken := c.FormValue("refreshToken")
 
 	if refreshToken == "" {
-		return c.Status(fiber.StatusUnauthorized).SendString("Refresh token required.")
+		return apperr.Unauthorized("Refresh token required.")
 	}
 
 	token, err := jwt.Parse(refreshToken, func(token *jwt.Token) (interface{}, error) {
@@ -179,31 +156,29 @@ func RefreshToken(c *fiber.Ctx) error {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid token signing method.")
 		}
-		return []byte(os.Getenv("REFRESH_SECRET_KEY")), nil
+		return []byte(h.cfg.RefreshSecret), nil
 	})
 
 	if err != nil || !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).SendString("Invalid refresh token.")
+		return apperr.Unauthorized("Invalid refresh token.").WithCode(apperr.CodeInvalidToken)
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok || claims["UserID"] == nil {
-		return c.Status(fiber.StatusUnauthorized).SendString("Invalid token claims.")
+		return apperr.Unauthorized("Invalid token claims.").WithCode(apperr.CodeInvalidToken)
 	}
 
-	userID := claims["UserID"]
+	userID, _ := claims["UserID"].(float64)
 
-	var user m.User
-	db := database.DBConn
-	if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString("User not found.")
+	user, err := h.store.Users().Get(uint(userID))
+	if err != nil {
+		return apperr.Unauthorized("User not found.").WithCode(apperr.CodeInvalidToken)
 	}
 
 	// สร้าง Access Token ใหม่
-	accessSecretKey := os.Getenv("ACCESS_SECRET_KEY")
-	newAccessToken, err := auth.GenerateToken(user, time.Minute*15, accessSecretKey)
+	newAccessToken, err := auth.GenerateToken(*user, h.cfg.AccessTokenTTL, h.cfg.AccessSecret)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString("Error creating new access token.")
+		return apperr.Internal("Error creating new access token.", err)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -211,30 +186,69 @@ func RefreshToken(c *fiber.Ctx) error {
 	})
 }
 
-func Approve(c *fiber.Ctx) error {
-	db := database.DBConn
-	var user m.User
+func (h *AuthController) Approve(c *fiber.Ctx) error {
+	users := h.store.Users()
 
 	// รับค่า ID ของ user ที่ต้องการจะ approve
-	inputID := c.FormValue("UserID")
+	var input dto.ApproveRequest
+	if err := bind(c, &input); err != nil {
+		return err
+	}
 
-	// ตรวจสอบ user ว่ามีอยู่ไหมจาก inputID
-	if err := db.Where("id = ?", inputID).First(&user).Error; err != nil {
-		return c.Status(fiber.StatusBadRequest).SendString("User not found.")
+	// ตรวจสอบ user ว่ามีอยู่ไหมจาก UserID
+	user, err := users.Get(input.UserID)
+	if err != nil {
+		return apperr.NotFound("User not found.")
+	}
+	if err := checkIfMatch(c, versionETag(user.Version)); err != nil {
+		return err
 	}
 
 	// ตรวจสอบก่อนว่า user คนนี้ได้รับการ approve หรือยัง ถ้ายังก็ approve ให้กับ user คนนั้น
 	if !user.Approve {
 		user.Approve = true
 
-		if err := db.Save(&user).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString("Failed to approve user.")
+		if err := users.Save(user); err != nil {
+			return saveError(err, "Failed to approve user.")
 		}
 	} else {
-		return c.Status(fiber.StatusBadRequest).SendString("This user has already been approved.")
+		return apperr.BadRequest("This user has already been approved.")
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message": user.FirstName + " has been approved.",
 	})
 }
+
+func (h *AuthController) UpdateRole(c *fiber.Ctx) error {
+	users := h.store.Users()
+
+	var input dto.UpdateRoleRequest
+	if err := bind(c, &input); err != nil {
+		return err
+	}
+
+	// ป้องกัน admin ลดสิทธิ์ตัวเองจนไม่เหลือ admin ที่จัดการระบบได้
+	if input.UserID == tokenUserID(c) {
+		return apperr.BadRequest("You can't change your own role.")
+	}
+
+	user, err := users.Get(input.UserID)
+	if err != nil {
+		return apperr.NotFound("User not found.")
+	}
+	if err := checkIfMatch(c, versionETag(user.Version)); err != nil {
+		return err
+	}
+
+	user.Role = input.Role
+	if err := users.Save(user); err != nil {
+		return saveError(err, "Failed to update role.")
+	}
+
+	setETag(c, versionETag(user.Version))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data":    dto.NewUserResponse(user),
+		"message": user.Username + " is now " + user.Role + ".",
+	})
+}
@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"errors"
+	"go-fiber-test/apperr"
+	"go-fiber-test/dto"
+	m "go-fiber-test/models"
+	"go-fiber-test/pricing"
+	"go-fiber-test/store"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CartController จัดการตะกร้าของ user ที่ login อยู่ ตะกร้าไม่กันสินค้าจนกว่าจะ checkout
+type CartController struct {
+	store          store.Store
+	pricing        *pricing.Calculator
+	reservationTTL time.Duration
+}
+
+func NewCartController(s store.Store, pricer *pricing.Calculator, reservationTTL time.Duration) *CartController {
+	return &CartController{store: s, pricing: pricer, reservationTTL: reservationTTL}
+}
+
+func (h *CartController) GetCart(c *fiber.Ctx) error {
+	cart, err := h.loadCart(tokenUserID(c))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"data":    cart,
+		"message": "Show cart successfully.",
+	})
+}
+
+// AddCartItem เพิ่มสินค้าลงตะกร้า ถ้ามีสินค้านี้อยู่แล้วจะบวกจำนวนเพิ่ม
+func (h *CartController) AddCartItem(c *fiber.Ctx) error {
+	var req dto.ItemRequest
+	if err := bind(c, &req); err != nil {
+		return err
+	}
+
+	userID := tokenUserID(c)
+	products, err := findProducts(h.store.Products(), []dto.ItemRequest{req})
+	if err != nil {
+		return err
+	}
+	product := products[0]
+
+	item, err := h.store.Carts().Get(userID, product.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		item = &m.CartItem{UserID: userID, ProductID: product.ID}
+	} else if err != nil {
+		return apperr.Internal("Failed to load cart.", err)
+	}
+	item.Amount += req.Amount
+
+	if err := h.saveCartItem(product, item); err != nil {
+		return err
+	}
+
+	cart, err := h.loadCart(userID)
+	if err != nil {
+		return err
+	}
+	return c.Status(201).JSON(fiber.Map{
+		"data":    cart,
+		"message": product.Product_Name + " has been added to the cart.",
+	})
+}
+
+// UpdateCartItem ตั้งจำนวนของสินค้าที่อยู่ในตะกร้าแล้ว
+func (h *CartController) UpdateCartItem(c *fiber.Ctx) error {
+	var req dto.UpdateCartItemRequest
+	if err := bind(c, &req); err != nil {
+		return err
+	}
+
+	userID := tokenUserID(c)
+	item, err := h.store.Carts().Get(userID, parseID(c.Params("productId")))
+	if err != nil {
+		return apperr.NotFound("Product is not in the cart.")
+	}
+
+	product, err := h.store.Products().Get(item.ProductID)
+	if err != nil {
+		return apperr.NotFound("Didn't find the product you were looking for.")
+	}
+
+	item.Amount = req.Amount
+	if err := h.saveCartItem(product, item); err != nil {
+		return err
+	}
+
+	cart, err := h.loadCart(userID)
+	if err != nil {
+		return err
+	}
+	return c.Status(200).JSON(fiber.Map{
+		"data":    cart,
+		"message": "Cart has been successfully updated.",
+	})
+}
+
+func (h *CartController) RemoveCartItem(c *fiber.Ctx) error {
+	userID := tokenUserID(c)
+	item, err := h.store.Carts().Get(userID, parseID(c.Params("productId")))
+	if err != nil {
+		return apperr.NotFound("Product is not in the cart.")
+	}
+
+	if err := h.store.Carts().Delete(item); err != nil {
+		return apperr.Internal("Failed to remove item from the cart.", err)
+	}
+
+	cart, err := h.loadCart(userID)
+	if err != nil {
+		return err
+	}
+	return c.Status(200).JSON(fiber.Map{
+		"data":    cart,
+		"message": "Item has been removed from the cart.",
+	})
+}
+
+func (h *CartController) ClearCart(c *fiber.Ctx) error {
+	if err := h.store.Carts().Clear(tokenUserID(c)); err != nil {
+		return apperr.Internal("Failed to clear the cart.", err)
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"message": "Cart has been cleared.",
+	})
+}
+
+// Checkout สร้าง order จากสินค้าทั้งหมดในตะกร้าด้วยการตัดสต็อกแบบเดียวกับ AddOrder แล้วล้างตะกร้า
+// ถ้าสินค้าตัวใดไม่พอ ตะกร้าจะยังอยู่เหมือนเดิม
+func (h *CartController) Checkout(c *fiber.Ctx) error {
+	// body ไม่บังคับ ส่งมาเฉพาะเมื่อต้องการใช้ coupon
+	var req dto.CheckoutRequest
+	if len(c.Body()) > 0 {
+		if err := bind(c, &req); err != nil {
+			return err
+		}
+	}
+
+	userID := tokenUserID(c)
+	var order *m.Order
+
+	err := h.store.Transaction(func(tx store.Store) error {
+		cartItems, err := tx.Carts().List(userID)
+		if err != nil {
+			return apperr.Internal("Failed to load cart.", err)
+		}
+		if len(cartItems) == 0 {
+			return apperr.BadRequest("Cart is empty.")
+		}
+
+		items := make([]dto.ItemRequest, len(cartItems))
+		for i, item := range cartItems {
+			items[i] = dto.ItemRequest{ProductID: item.ProductID, Amount: item.Amount}
+		}
+
+		order, err = placeOrder(tx, h.pricing, h.reservationTTL, strconv.FormatUint(uint64(userID), 10), items, req.Coupon)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Carts().Clear(userID); err != nil {
+			return apperr.Internal("Failed to clear the cart.", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"data":    dto.NewOrderResponse(order),
+		"message": "Checkout completed successfully.",
+	})
+}
+
+// saveCartItem บันทึกรายการในตะกร้า โดยไม่ยอมให้จำนวนเกินจำนวนที่ยังขายได้ตอนนี้
+// (สินค้ายังไม่ถูกกันไว้ จึงอาจไม่พอได้อีกตอน checkout)
+func (h *CartController) saveCartItem(product *m.Product, item *m.CartItem) error {
+	if item.Amount > product.Available() {
+		return apperr.Conflict("Product " + product.Product_Name + " is not available in sufficient quantity.").WithCode(apperr.CodeInsufficientStock)
+	}
+	if err := h.store.Carts().Save(item); err != nil {
+		return apperr.Internal("Failed to update the cart.", err)
+	}
+	return nil
+}
+
+// loadCart โหลดตะกร้าพร้อมราคาและจำนวนคงเหลือปัจจุบันของสินค้าแต่ละตัว
+func (h *CartController) loadCart(userID uint) (dto.CartResponse, error) {
+	cart := dto.CartResponse{Items: []dto.CartItemResponse{}, Available: true}
+
+	items, err := h.store.Carts().List(userID)
+	if err != nil {
+		return cart, apperr.Internal("Failed to load cart.", err)
+	}
+
+	for _, item := range items {
+		line := dto.CartItemResponse{ProductID: item.ProductID, Amount: item.Amount}
+
+		product, err := h.store.Products().Get(item.ProductID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return cart, apperr.Internal("Failed to load cart.", err)
+		}
+		if product != nil {
+			line.Product = product.Product_Name
+			line.Unit_Price = product.Price
+			line.Line_Total = product.Price * item.Amount
+			line.Stock = product.Available()
+			line.Available = product.Available() >= item.Amount
+		}
+
+		cart.Items = append(cart.Items, line)
+		cart.Total_Price += line.Line_Total
+		cart.Available = cart.Available && line.Available
+	}
+	return cart, nil
+}
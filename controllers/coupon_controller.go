@@ -0,0 +1,294 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"go-fiber-test/apperr"
+	"go-fiber-test/dto"
+	m "go-fiber-test/models"
+	"go-fiber-test/store"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CouponController struct {
+	store store.Store
+}
+
+func NewCouponController(s store.Store) *CouponController {
+	return &CouponController{store: s}
+}
+
+// couponSortKeys คือ column ที่ใช้เรียง GET /coupon ได้ (ต้องตรงกับ rule sort ใน dto.CouponListQuery)
+var couponSortKeys = sortKeys[m.Coupon]{
+	"id":         func(c *m.Coupon) any { return c.ID },
+	"code":       func(c *m.Coupon) any { return c.Code },
+	"created_at": func(c *m.Coupon) any { return c.CreatedAt },
+}
+
+func (h *CouponController) GetCoupons(c *fiber.Ctx) error {
+	var pageQuery dto.PageQuery
+	var query dto.CouponListQuery
+	if err := bindQuery(c, &pageQuery, &query); err != nil {
+		return err
+	}
+
+	page, err := newPage(pageQuery, query.Sort)
+	if err != nil {
+		return err
+	}
+
+	result, err := h.store.Coupons().List(page)
+	if err != nil {
+		return apperr.Internal("Failed to load coupons.", err)
+	}
+
+	return listResponse(c, couponSortKeys, func(c *m.Coupon) uint { return c.ID }, query.Sort, page, result,
+		dto.NewCouponResponses(result.Items), "Show all coupons.")
+}
+
+func (h *CouponController) GetCoupon(c *fiber.Ctx) error {
+	coupon, err := h.store.Coupons().Get(parseID(c.Params("couponId")))
+	if err != nil {
+		return apperr.NotFound("Coupon not found.")
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"data":    dto.NewCouponResponse(coupon),
+		"message": "Show coupon " + coupon.Code + " success.",
+	})
+}
+
+func (h *CouponController) AddCoupon(c *fiber.Ctx) error {
+	var req dto.CouponRequest
+	if err := h.bindCoupon(c, &req); err != nil {
+		return err
+	}
+
+	// code ต้องไม่ซ้ำกับ coupon ใด ๆ รวมที่ถูกลบไปแล้ว เพราะ order เก่ายังอ้างถึง code นั้นอยู่
+	exists, err := h.store.Coupons().CodeExists(req.Code)
+	if err != nil {
+		return apperr.Internal("Failed to check coupon code.", err)
+	}
+	if exists {
+		return apperr.Conflict("Coupon code " + req.Code + " already exists.")
+	}
+
+	coupon := m.Coupon{}
+	setCoupon(&coupon, &req)
+	if err := h.store.Coupons().Create(&coupon); err != nil {
+		return apperr.Internal("Failed to create coupon.", err)
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"data":    dto.NewCouponResponse(&coupon),
+		"message": "Coupon " + coupon.Code + " has been created.",
+	})
+}
+
+// UpdateCoupon แทนที่ค่าทั้งหมดของ coupon ยกเว้นจำนวนครั้งที่ถูกใช้ไปแล้ว
+func (h *CouponController) UpdateCoupon(c *fiber.Ctx) error {
+	var req dto.CouponRequest
+	if err := h.bindCoupon(c, &req); err != nil {
+		return err
+	}
+
+	coupon, err := h.store.Coupons().Get(parseID(c.Params("couponId")))
+	if err != nil {
+		return apperr.NotFound("Coupon not found.")
+	}
+
+	if req.Code != coupon.Code {
+		exists, err := h.store.Coupons().CodeExists(req.Code)
+		if err != nil {
+			return apperr.Internal("Failed to check coupon code.", err)
+		}
+		if exists {
+			return apperr.Conflict("Coupon code " + req.Code + " already exists.")
+		}
+	}
+
+	setCoupon(coupon, &req)
+	if err := h.store.Coupons().Save(coupon); err != nil {
+		return apperr.Internal("Failed to update coupon.", err)
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"data":    dto.NewCouponResponse(coupon),
+		"message": "Coupon " + coupon.Code + " has been updated.",
+	})
+}
+
+// RemoveCoupon ลบ coupon แบบ soft delete เพื่อให้ order ที่เคยใช้ยังคำนวณส่วนลดเดิมได้
+func (h *CouponController) RemoveCoupon(c *fiber.Ctx) error {
+	coupon, err := h.store.Coupons().Get(parseID(c.Params("couponId")))
+	if err != nil {
+		return apperr.NotFound("Coupon not found.")
+	}
+
+	if err := h.store.Coupons().Delete(coupon); err != nil {
+		return apperr.Internal("Failed to delete coupon.", err)
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"message": "Coupon " + coupon.Code + " has been deleted.",
+	})
+}
+
+// bindCoupon parse และตรวจสอบ body รวมถึงกฎที่ต้องดูหลาย field พร้อมกัน
+func (h *CouponController) bindCoupon(c *fiber.Ctx, req *dto.CouponRequest) error {
+	if err := bind(c, req); err != nil {
+		return err
+	}
+	req.Code = strings.ToUpper(req.Code)
+
+	var fields []dto.FieldError
+	if req.Type == m.CouponPercent && req.Value > 100 {
+		fields = append(fields, dto.FieldError{Field: "Value", Rule: "max", Message: "Value must be at most 100 for percent coupons."})
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		fields = append(fields, dto.FieldError{Field: "EndsAt", Rule: "gtfield", Message: "EndsAt must be after StartsAt."})
+	}
+	if len(fields) > 0 {
+		return dto.Invalid(fields...)
+	}
+
+	if req.ProductID != nil {
+		if _, err := h.store.Products().Get(*req.ProductID); err != nil {
+			return apperr.NotFound("Didn't find the product you were looking for.")
+		}
+	}
+	return nil
+}
+
+func setCoupon(coupon *m.Coupon, req *dto.CouponRequest) {
+	coupon.Code = req.Code
+	coupon.Type = req.Type
+	coupon.Value = req.Value
+	coupon.MinOrder = req.MinOrder
+	coupon.ProductID = req.ProductID
+	coupon.StartsAt = req.StartsAt
+	coupon.EndsAt = req.EndsAt
+	coupon.UsageLimit = req.UsageLimit
+	coupon.PerUserLimit = req.PerUserLimit
+}
+
+func couponError(message string) error {
+	return apperr.BadRequest(message).WithCode(apperr.CodeCouponInvalid)
+}
+
+// applyCoupon ตรวจเงื่อนไขของ coupon แล้วบันทึกส่วนลดลงใน order ที่ยังไม่ถูกสร้าง
+// ต้องเรียกภายใน Transaction หลังใส่ Items แล้ว เพราะจำนวนครั้งที่ใช้ถูกนับทันที
+func applyCoupon(tx store.Store, order *m.Order, code string, userID uint, now time.Time) error {
+	coupon, discount, err := checkCoupon(tx, order, code, now)
+	if err != nil {
+		return err
+	}
+
+	// Redeem lock แถวของ coupon ไว้ก่อน การนับจำนวนครั้งของ user ด้านล่างจึงไม่ซ้อนกับ request อื่น
+	err = tx.Coupons().Redeem(coupon.ID)
+	if errors.Is(err, store.ErrUsageLimit) {
+		return couponError("Coupon " + coupon.Code + " has reached its usage limit.")
+	}
+	if err != nil {
+		return apperr.Internal("Failed to redeem coupon.", err)
+	}
+
+	if err := checkPerUserLimit(tx, coupon, userID); err != nil {
+		return err
+	}
+
+	setOrderCoupon(order, coupon, discount)
+	return nil
+}
+
+// quoteCoupon ตรวจเงื่อนไขเดียวกับ applyCoupon และใส่ส่วนลดลงใน order โดยไม่นับการใช้งาน ใช้กับการประเมินราคา
+func quoteCoupon(s store.Store, order *m.Order, code string, userID uint, now time.Time) error {
+	coupon, discount, err := checkCoupon(s, order, code, now)
+	if err != nil {
+		return err
+	}
+
+	if coupon.UsageLimit > 0 && coupon.Used >= coupon.UsageLimit {
+		return couponError("Coupon " + coupon.Code + " has reached its usage limit.")
+	}
+	if err := checkPerUserLimit(s, coupon, userID); err != nil {
+		return err
+	}
+
+	setOrderCoupon(order, coupon, discount)
+	return nil
+}
+
+// checkCoupon หา coupon จาก code และตรวจวันที่ ยอดขั้นต่ำ และสินค้าที่ใช้ได้กับรายการใน order
+func checkCoupon(s store.Store, order *m.Order, code string, now time.Time) (*m.Coupon, int, error) {
+	code = strings.ToUpper(code)
+	coupon, err := s.Coupons().GetByCode(code)
+	if err != nil {
+		return nil, 0, couponError("Coupon " + code + " doesn't exist.")
+	}
+
+	switch err := coupon.ActiveAt(now); {
+	case errors.Is(err, m.ErrCouponNotStarted):
+		return nil, 0, couponError("Coupon " + code + " is not active yet.")
+	case errors.Is(err, m.ErrCouponExpired):
+		return nil, 0, couponError("Coupon " + code + " has expired.")
+	}
+
+	if order.ItemsTotal() < coupon.MinOrder {
+		return nil, 0, couponError(fmt.Sprintf("Order total must be at least %d to use coupon %s.", coupon.MinOrder, code))
+	}
+
+	discount := coupon.DiscountFor(order.Items)
+	if discount == 0 {
+		return nil, 0, couponError("Coupon " + code + " doesn't apply to any item in this order.")
+	}
+	return coupon, discount, nil
+}
+
+func checkPerUserLimit(s store.Store, coupon *m.Coupon, userID uint) error {
+	if coupon.PerUserLimit == 0 {
+		return nil
+	}
+
+	used, err := s.Coupons().CountRedemptions(coupon.ID, userID)
+	if err != nil {
+		return apperr.Internal("Failed to redeem coupon.", err)
+	}
+	if used >= int64(coupon.PerUserLimit) {
+		return couponError("You have already used coupon " + coupon.Code + " the maximum number of times.")
+	}
+	return nil
+}
+
+func setOrderCoupon(order *m.Order, coupon *m.Coupon, discount int) {
+	order.CouponID = &coupon.ID
+	order.CouponCode = coupon.Code
+	order.Discount = discount
+}
+
+// updateDiscount คำนวณส่วนลดของ coupon ที่ order ใช้อยู่ใหม่หลังแก้ไขรายการ
+// ไม่ตรวจวันที่หรือจำนวนครั้งซ้ำเพราะ coupon ถูกใช้ไปแล้วตอนสร้าง order แต่ยอดขั้นต่ำยังต้องผ่าน
+// และส่วนลดต้องไม่เหลือ 0 มิฉะนั้น order จะนับการใช้ coupon ไว้โดยไม่ได้ส่วนลด
+func updateDiscount(tx store.Store, order *m.Order) error {
+	if order.CouponID == nil {
+		return nil
+	}
+
+	coupon, err := tx.Coupons().GetAny(*order.CouponID)
+	if err != nil {
+		return apperr.Internal("Failed to load coupon of order.", err)
+	}
+
+	if order.ItemsTotal() < coupon.MinOrder {
+		return couponError(fmt.Sprintf("Order total must be at least %d to keep coupon %s.", coupon.MinOrder, coupon.Code))
+	}
+	discount := coupon.DiscountFor(order.Items)
+	if discount == 0 {
+		return couponError("Coupon " + coupon.Code + " must still give a discount on this order.")
+	}
+	order.Discount = discount
+	return nil
+}
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"bufio"
+	"go-fiber-test/dto"
+	"go-fiber-test/export"
+	m "go-fiber-test/models"
+	"go-fiber-test/store"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ExportController ให้ admin ดาวน์โหลดข้อมูลเป็น CSV หรือ XLSX ด้วย filter เดียวกับ list
+// ข้อมูลถูกโหลดจากฐานข้อมูลทีละ batch และเขียนลง response ทันที จึงไม่ต้องโหลดทั้งหมดไว้ใน memory
+type ExportController struct {
+	store store.Store
+}
+
+func NewExportController(s store.Store) *ExportController {
+	return &ExportController{store: s}
+}
+
+func (h *ExportController) ExportProducts(c *fiber.Ctx) error {
+	var format dto.ExportQuery
+	var query dto.ProductListQuery
+	if err := bindQuery(c, &format, &query); err != nil {
+		return err
+	}
+
+	products := h.store.Products()
+	filter := productFilter(query)
+	return h.stream(c, format.Format, "products", func(w export.Writer) error {
+		if err := w.WriteRow("ID", "CreatedAt", "UpdatedAt", "Product_Name", "Price", "Amount", "Weight", "Images"); err != nil {
+			return err
+		}
+		return products.Each(filter, func(batch []m.Product) error {
+			for _, p := range batch {
+				images := make([]string, len(p.Images))
+				for i, image := range p.Images {
+					images[i] = image.ImageURL
+				}
+				if err := w.WriteRow(p.ID, p.CreatedAt, p.UpdatedAt, p.Product_Name, p.Price, p.Amount, p.Weight, strings.Join(images, " ")); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// ExportOrders เขียน order หนึ่งแถวต่อสินค้าหนึ่งรายการ โดยข้อมูลของ order ซ้ำในทุกแถวของรายการใน order นั้น
+func (h *ExportController) ExportOrders(c *fiber.Ctx) error {
+	var format dto.ExportQuery
+	var query dto.OrderListQuery
+	if err := bindQuery(c, &format, &query); err != nil {
+		return err
+	}
+
+	orders := h.store.Orders()
+	filter := orderFilter(query)
+	return h.stream(c, format.Format, "orders", func(w export.Writer) error {
+		if err := w.WriteRow(
+			"OrderID", "CreatedAt", "Buyer", "Status", "PaidAt", "CouponCode",
+			"Subtotal", "Discount", "Shipping", "Tax", "Total Price",
+			"ItemID", "ProductID", "Product", "Unit Price", "Amount", "Line Total",
+		); err != nil {
+			return err
+		}
+		return orders.Each(filter, func(batch []m.Order) error {
+			for _, o := range batch {
+				order := []any{
+					o.ID, o.CreatedAt, o.Buyer, o.Status, o.PaidAt, o.CouponCode,
+					o.Subtotal, o.Discount, o.Shipping, o.Tax, o.Total_Price,
+				}
+				// order ที่ไม่มีรายการสินค้ายังต้องมีหนึ่งแถว
+				if len(o.Items) == 0 {
+					if err := w.WriteRow(order...); err != nil {
+						return err
+					}
+				}
+				for _, item := range o.Items {
+					row := append(order[:len(order):len(order)], item.ID, item.ProductID, item.Product, item.Unit_Price, item.Amount, item.Line_Total)
+					if err := w.WriteRow(row...); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+	})
+}
+
+func (h *ExportController) ExportUsers(c *fiber.Ctx) error {
+	var format dto.ExportQuery
+	var query dto.UserListQuery
+	if err := bindQuery(c, &format, &query); err != nil {
+		return err
+	}
+
+	users := h.store.Users()
+	filter := userFilter(query)
+	return h.stream(c, format.Format, "users", func(w export.Writer) error {
+		if err := w.WriteRow("ID", "CreatedAt", "UpdatedAt", "Username", "FirstName", "LastName", "Role", "Approve"); err != nil {
+			return err
+		}
+		return users.Each(filter, func(batch []m.User) error {
+			for _, u := range batch {
+				if err := w.WriteRow(u.ID, u.CreatedAt, u.UpdatedAt, u.Username, u.FirstName, u.LastName, u.Role, u.Approve); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// stream ตั้ง header ของไฟล์แล้วให้ write เขียนข้อมูลลง response ระหว่างที่ส่งออกไป
+// write ทำงานหลังจาก handler return แล้วจึงห้ามใช้ fiber.Ctx ภายใน
+func (h *ExportController) stream(c *fiber.Ctx, format, name string, write func(w export.Writer) error) error {
+	if format == "" {
+		format = export.CSV
+	}
+	filename := name + "-" + time.Now().Format("20060102-150405") + "." + format
+	requestID := c.GetRespHeader(fiber.HeaderXRequestID)
+
+	c.Set(fiber.HeaderContentType, export.ContentType(format))
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	c.Status(fiber.StatusOK).Context().SetBodyStreamWriter(func(bw *bufio.Writer) {
+		w, err := export.New(format, bw, name)
+		if err == nil {
+			err = write(w)
+		}
+		if err == nil {
+			err = w.Close()
+		}
+		// status และ header ถูกส่งไปแล้วจึงตอบ error กลับไม่ได้ client จะได้ไฟล์ที่ไม่ครบ
+		if err != nil {
+			log.Printf("[%s] export %s: %v", requestID, name, err)
+		}
+	})
+	return nil
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"context"
+	"go-fiber-test/store"
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// readinessTimeout คือเวลาสูงสุดที่รอการ ping ฐานข้อมูลใน readiness probe
+const readinessTimeout = 2 * time.Second
+
+type HealthController struct {
+	store     store.Store
+	uploadDir string
+}
+
+func NewHealthController(s store.Store, uploadDir string) *HealthController {
+	return &HealthController{store: s, uploadDir: uploadDir}
+}
+
+// Healthz บอกว่า process ยังทำงานอยู่ ไม่ตรวจสอบ dependency ใด ๆ
+func (h *HealthController) Healthz(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": "ok",
+	})
+}
+
+// Readyz บอกว่าพร้อมรับ request หรือไม่ โดยตรวจสอบการเชื่อมต่อฐานข้อมูลและสิทธิ์เขียน upload dir
+func (h *HealthController) Readyz(c *fiber.Ctx) error {
+	checks := fiber.Map{}
+	ready := true
+
+	ctx, cancel := context.WithTimeout(c.UserContext(), readinessTimeout)
+	defer cancel()
+
+	if err := h.store.Ping(ctx); err != nil {
+		checks["database"] = err.Error()
+		ready = false
+	} else {
+		checks["database"] = "ok"
+	}
+
+	if err := checkWritable(h.uploadDir); err != nil {
+		checks["uploads"] = err.Error()
+		ready = false
+	} else {
+		checks["uploads"] = "ok"
+	}
+
+	if !ready {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status": "unavailable",
+			"checks": checks,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": "ok",
+		"checks": checks,
+	})
+}
+
+// checkWritable ลองสร้างและลบไฟล์ชั่วคราวใน dir เพื่อยืนยันว่าเขียนไฟล์ได้จริง
+func checkWritable(dir string) error {
+	file, err := os.CreateTemp(dir, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	name := file.Name()
+	if err := file.Close(); err != nil {
+		os.Remove(name)
+		return err
+	}
+	return os.Remove(name)
+}
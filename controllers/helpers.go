@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"go-fiber-test/apperr"
+	"go-fiber-test/dto"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// parseID แปลง id จาก params หรือ form ให้เป็น uint ถ้าแปลงไม่ได้จะได้ 0 ซึ่งไม่มีอยู่ในฐานข้อมูล
+func parseID(value string) uint {
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
+
+// bind parse body (JSON หรือ form) ลงใน req แล้วตรวจสอบตาม validate tag ของ dto
+func bind(c *fiber.Ctx, req any) error {
+	if err := c.BodyParser(req); err != nil {
+		return apperr.InvalidBody(err)
+	}
+	return dto.Validate(req)
+}
+
+// tokenUserID ดึง UserID จาก claims ที่ถูกเซ็ตไว้ใน middleware.AuthRequired
+func tokenUserID(c *fiber.Ctx) uint {
+	claims := c.Locals("user").(jwt.MapClaims)
+	return uint(claims["UserID"].(float64))
+}
+
+// tokenActor คือ userID ใน token ในรูปที่ใช้บันทึกเป็นผู้ทำรายการ เช่น ActorID ของ stock movement
+func tokenActor(c *fiber.Ctx) *uint {
+	userID := tokenUserID(c)
+	return &userID
+}
+
+// canManageUser บอกว่าเจ้าของ token จัดการข้อมูลของ userID ได้ไหม (เป็นเจ้าของเองหรือเป็น admin)
+func canManageUser(c *fiber.Ctx, userID uint) bool {
+	claims := c.Locals("user").(jwt.MapClaims)
+	role, _ := claims["Role"].(string)
+	return userID == tokenUserID(c) || role == "admin"
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"go-fiber-test/apperr"
+	"go-fiber-test/dto"
+	"go-fiber-test/invoice"
+	m "go-fiber-test/models"
+	"go-fiber-test/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type InvoiceController struct {
+	store    store.Store
+	currency string
+}
+
+func NewInvoiceController(s store.Store, currency string) *InvoiceController {
+	return &InvoiceController{store: s, currency: currency}
+}
+
+// GetInvoice ส่งใบแจ้งหนี้ของ order เป็น PDF หรือ HTML (?format=html) ให้เจ้าของ order หรือ admin
+// ใบแจ้งหนี้ออกให้เฉพาะ order ที่ชำระเงินแล้ว เลขที่และยอดเงินถูกบันทึกตอนที่ขอครั้งแรกและใช้ชุดเดิมทุกครั้งหลังจากนั้น
+func (h *InvoiceController) GetInvoice(c *fiber.Ctx) error {
+	var query dto.InvoiceQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
+	}
+
+	order, err := h.store.Orders().Get(parseID(c.Params("orderId")))
+	if err != nil {
+		return apperr.NotFound("Order not found.")
+	}
+
+	// ตรวจสอบ userID ใน token กับ buyer ว่าตรงกันไหม (admin ดูได้ทุก order)
+	if !canManageUser(c, parseID(order.Buyer)) {
+		return apperr.Unauthorized("Unauthorized to view this page.")
+	}
+
+	// order ที่ยังไม่ได้ชำระเงินไม่ใช่ยอดขาย จึงไม่ใช้เลขที่ใบแจ้งหนี้ซึ่งต้องเรียงต่อกันทางบัญชี
+	if order.PaidAt == nil {
+		return apperr.Conflict("Invoice can only be issued for a paid order.").WithCode(apperr.CodeOrderStatus)
+	}
+
+	issued, err := h.store.Invoices().GetOrCreate(m.NewInvoice(order))
+	if err != nil {
+		return apperr.Internal("Failed to issue invoice.", err)
+	}
+
+	buyer, err := h.loadBuyer(issued.Buyer)
+	if err != nil {
+		return apperr.Internal("Failed to load buyer of order.", err)
+	}
+
+	doc := invoice.Document{
+		Number:   issued.Number(),
+		IssuedAt: issued.CreatedAt,
+		Currency: h.currency,
+		Invoice:  issued,
+		Buyer:    buyer,
+	}
+
+	var buf bytes.Buffer
+	if query.Format == dto.InvoiceHTML {
+		if err := invoice.WriteHTML(&buf, doc); err != nil {
+			return apperr.Internal("Failed to render invoice.", err)
+		}
+		c.Type("html", "utf-8")
+	} else {
+		if err := invoice.WritePDF(&buf, doc); err != nil {
+			return apperr.Internal("Failed to render invoice.", err)
+		}
+		c.Type("pdf")
+		c.Set(fiber.HeaderContentDisposition, `inline; filename="`+doc.Number+`.pdf"`)
+	}
+
+	return c.Status(200).Send(buf.Bytes())
+}
+
+// loadBuyer โหลด user ของผู้ซื้อรวมถึง user ที่ถูก soft delete ไปแล้ว คืน nil ถ้าถูกลบถาวร
+func (h *InvoiceController) loadBuyer(buyerID string) (*m.User, error) {
+	id := parseID(buyerID)
+	buyer, err := h.store.Users().Get(id)
+	if errors.Is(err, store.ErrNotFound) {
+		buyer, err = h.store.Users().GetDeleted(id)
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	return buyer, err
+}
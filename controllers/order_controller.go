@@ -1,278 +1,660 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
-	"go-fiber-test/database"
+	"go-fiber-test/apperr"
+	"go-fiber-test/dto"
 	m "go-fiber-test/models"
+	"go-fiber-test/payment"
+	"go-fiber-test/pricing"
+	"go-fiber-test/store"
 	"log"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-func GetOrders(c *fiber.Ctx) error {
-	db := database.DBConn
-	var orders []m.Order
+type OrderController struct {
+	store    store.Store
+	provider payment.Provider
+	pricing  *pricing.Calculator
+	// reservationTTL คือเวลาที่สินค้าของ order ใหม่ถูกกันไว้ก่อนต้องยืนยัน
+	reservationTTL time.Duration
+}
 
-	// ใช้ GORM Preloading เพื่อให้ GORM สามารถโหลดข้อมูลที่สัมพันธ์กับ Orders มาได้
-	db.Preload("Items").Find(&orders)
-	return c.Status(200).JSON(fiber.Map{
-		"data":    &orders,
-		"message": "Show all orders.",
-	})
+func NewOrderController(s store.Store, provider payment.Provider, pricer *pricing.Calculator, reservationTTL time.Duration) *OrderController {
+	return &OrderController{store: s, provider: provider, pricing: pricer, reservationTTL: reservationTTL}
 }
 
-func GetOrder(c *fiber.Ctx) error {
-	db := database.DBConn
-	paramUserID := c.Params("userId")
+// orderSortKeys คือ column ที่ใช้เรียง GET /order ได้ (ต้องตรงกับ rule sort ใน dto.OrderListQuery)
+var orderSortKeys = sortKeys[m.Order]{
+	"id":          func(o *m.Order) any { return o.ID },
+	"total_price": func(o *m.Order) any { return o.Total_Price },
+	"created_at":  func(o *m.Order) any { return o.CreatedAt },
+	"updated_at":  func(o *m.Order) any { return o.UpdatedAt },
+}
 
-	// ดึง claims จาก context ที่ถูกเซ็ตใน middleware
-	claims := c.Locals("user").(jwt.MapClaims)
-	tokenUserID := claims["UserID"].(float64)
+func (h *OrderController) GetOrders(c *fiber.Ctx) error {
+	var pageQuery dto.PageQuery
+	var query dto.OrderListQuery
+	if err := bindQuery(c, &pageQuery, &query); err != nil {
+		return err
+	}
+
+	page, err := newPage(pageQuery, query.Sort)
+	if err != nil {
+		return err
+	}
+
+	result, err := h.store.Orders().List(orderFilter(query), page)
+	if err != nil {
+		return apperr.Internal("Failed to load orders.", err)
+	}
+
+	return listResponse(c, orderSortKeys, func(o *m.Order) uint { return o.ID }, query.Sort, page, result,
+		dto.NewOrderResponses(result.Items), "Show all orders.")
+}
+
+// orderFilter แปลง filter ใน query string ของ GET /order เป็น store.OrderFilter
+func orderFilter(query dto.OrderListQuery) store.OrderFilter {
+	return store.OrderFilter{
+		Buyer:       query.Buyer,
+		Status:      query.Status,
+		CreatedFrom: dto.ParseTime(query.CreatedFrom, false),
+		CreatedTo:   dto.ParseTime(query.CreatedTo, true),
+	}
+}
+
+func (h *OrderController) GetOrder(c *fiber.Ctx) error {
+	paramUserID := c.Params("userId")
 
 	// ตรวจสอบ userID ใน token กับ userID จาก Params ว่าตรงกันไหม
-	if paramUserID != fmt.Sprintf("%v", uint(tokenUserID)) {
-		return c.Status(fiber.StatusUnauthorized).SendString("Unauthorized to view this page.")
+	if paramUserID != fmt.Sprintf("%v", tokenUserID(c)) {
+		return apperr.Unauthorized("Unauthorized to view this page.")
 	}
 
-	var orders []m.Order
+	orders, err := h.store.Orders().ListByBuyer(paramUserID)
+	if err != nil {
+		return apperr.Internal("Failed to load orders.", err)
+	}
 
-	db.Preload("Items").Where("Buyer = ?", paramUserID).Find(&orders)
 	return c.Status(200).JSON(fiber.Map{
-		"data":    &orders,
+		"data":    dto.NewOrderResponses(orders),
 		"message": "Show orders successfully.",
 	})
 }
 
-func AddOrder(c *fiber.Ctx) error {
-	db := database.DBConn
+func (h *OrderController) AddOrder(c *fiber.Ctx) error {
 	paramUserID := c.Params("userId")
 
-	// ดึง claims จาก context ที่ถูกเซ็ตใน middleware
-	claims := c.Locals("user").(jwt.MapClaims)
-	tokenUserID := claims["UserID"].(float64)
-
 	// ตรวจสอบ userID ใน token กับ userID จาก Params มาตรงกันไหม
-	if paramUserID != fmt.Sprintf("%v", uint(tokenUserID)) {
-		return c.Status(fiber.StatusUnauthorized).SendString("Unauthorized to view this page.")
-	}
-
-	var orderRequest struct {
-		Items []m.Item `json:"Items"`
+	if paramUserID != fmt.Sprintf("%v", tokenUserID(c)) {
+		return apperr.Unauthorized("Unauthorized to view this page.")
 	}
 
-	if err := c.BodyParser(&orderRequest); err != nil {
-		return c.Status(503).SendString(err.Error())
+	var orderRequest dto.OrderRequest
+	if err := bind(c, &orderRequest); err != nil {
+		return err
 	}
 
 	// ตรวจสอบว่า User นี้มีอยู่ระบบหรือไม่
-	var user m.User
-	if err := db.Where("id = ?", paramUserID).First(&user).Error; err != nil {
-		return c.Status(fiber.StatusBadRequest).SendString("Invalid Buyer.")
+	if _, err := h.store.Users().Get(parseID(paramUserID)); err != nil {
+		return apperr.BadRequest("Invalid Buyer.")
 	}
 
-	var total_price int
-	var updatedItems []m.Item
+	var order *m.Order
 
-	for _, item := range orderRequest.Items {
-		var product m.Product
-		if err := db.Where("Product_Name = ?", item.Product).First(&product).Error; err != nil {
-			return c.Status(fiber.StatusNotFound).SendString("Didn't find the product you were looking for.")
-		}
+	// กันสินค้าและสร้าง order ใน transaction เดียว ถ้ารายการใดไม่ผ่านสินค้าของรายการก่อนหน้าจะถูก rollback
+	err := h.store.Transaction(func(tx store.Store) error {
+		var err error
+		order, err = placeOrder(tx, h.pricing, h.reservationTTL, paramUserID, orderRequest.Items, orderRequest.Coupon)
+		return err
+	})
+	if err != nil {
+		return err
+	}
 
-		if product.Amount < item.Amount {
-			return c.Status(fiber.StatusUnauthorized).SendString(
-				"Product " + item.Product + " is not available in sufficient quantity.",
-			)
-		}
+	return c.Status(201).JSON(fiber.Map{
+		"data":    dto.NewOrderResponse(order),
+		"message": "Add the order you want successfully.",
+	})
+}
 
-		product.Amount -= item.Amount
-		if err := db.Save(&product).Error; err != nil {
-			return c.Status(500).SendString("Failed to update product quantity.")
-		}
+// QuoteOrder คำนวณยอดสินค้า ส่วนลด ค่าส่ง ภาษี และยอดสุทธิของรายการที่ส่งมาโดยไม่สร้าง order
+// ไม่ตัดสต็อกและไม่นับการใช้ coupon ราคาที่ได้จึงอาจเปลี่ยนได้ถ้าสินค้าหรือ coupon เปลี่ยนก่อนสั่งซื้อจริง
+func (h *OrderController) QuoteOrder(c *fiber.Ctx) error {
+	var orderRequest dto.OrderRequest
+	if err := bind(c, &orderRequest); err != nil {
+		return err
+	}
 
-		total_price += product.Price * item.Amount
-		updatedItems = append(updatedItems, m.Item{
-			Product: item.Product,
-			Amount:  item.Amount,
-		})
+	products, err := findProducts(h.store.Products(), orderRequest.Items)
+	if err != nil {
+		return err
 	}
 
-	order := m.Order{
-		Buyer:       paramUserID,
-		Items:       updatedItems,
-		Total_Price: total_price,
+	order := &m.Order{}
+	for i, item := range orderRequest.Items {
+		order.Items = append(order.Items, newItem(products[i], item.Amount))
 	}
 
-	if err := db.Create(&order).Error; err != nil {
-		return c.Status(500).SendString("Failed to create order.")
+	if orderRequest.Coupon != "" {
+		if err := quoteCoupon(h.store, order, orderRequest.Coupon, tokenUserID(c), time.Now()); err != nil {
+			return err
+		}
 	}
+	h.pricing.Apply(order)
 
-	return c.Status(201).JSON(fiber.Map{
-		"data":    order,
-		"message": "Add the order you want successfully.",
+	return c.Status(200).JSON(fiber.Map{
+		"data":    dto.NewQuoteResponse(order),
+		"message": "Quote the order successfully.",
 	})
 }
 
-func UpdateOrder(c *fiber.Ctx) error {
-	db := database.DBConn
-	orderId := c.Params("orderId")
+func (h *OrderController) UpdateOrder(c *fiber.Ctx) error {
+	var orderRequest dto.OrderRequest
+	if err := bind(c, &orderRequest); err != nil {
+		return err
+	}
 
-	// ตรวจสอบว่า Order ที่ต้องการ update นี้มีอยู่ในระบบหรือไม่
-	var order m.Order
-	if err := db.Preload("Items").Where("id = ?", orderId).First(&order).Error; err != nil {
-		return c.Status(fiber.StatusBadRequest).SendString("Order not found.")
+	if orderRequest.Coupon != "" {
+		return dto.Invalid(dto.FieldError{
+			Field:   "Coupon",
+			Rule:    "excluded",
+			Message: "Coupon can only be applied when the order is created.",
+		})
 	}
 
-	// ดึง claims จาก context ที่ถูกเซ็ตใน middleware
-	claims := c.Locals("user").(jwt.MapClaims)
-	tokenUserID := claims["UserID"].(float64)
+	var order *m.Order
 
-	// ตรวจสอบ userID ใน token กับ buyer ว่าตรงกันไหม
-	if order.Buyer != fmt.Sprintf("%v", uint(tokenUserID)) {
-		return c.Status(fiber.StatusUnauthorized).SendString("Unauthorized to view this page.")
-	}
+	// lock order ไว้ตลอด transaction เพื่อไม่ให้ update/delete order เดียวกันซ้อนกัน
+	err := h.store.Transaction(func(tx store.Store) error {
+		products := tx.Products()
+		orders := tx.Orders()
+
+		// ตรวจสอบว่า Order ที่ต้องการ update นี้มีอยู่ในระบบหรือไม่
+		var err error
+		order, err = orders.GetForUpdate(parseID(c.Params("orderId")))
+		if err != nil {
+			return apperr.NotFound("Order not found.")
+		}
 
-	var orderRequest struct {
-		Items []m.Item `json:"Items"`
+		// ตรวจสอบ userID ใน token กับ buyer ว่าตรงกันไหม
+		if order.Buyer != fmt.Sprintf("%v", tokenUserID(c)) {
+			return apperr.Unauthorized("Unauthorized to view this page.")
+		}
+		if err := checkIfMatch(c, versionETag(order.Version)); err != nil {
+			return err
+		}
+
+		// แก้ไขรายการสินค้าได้เฉพาะ order ที่ยังไม่ได้ชำระเงิน
+		if order.Status != m.OrderPending {
+			return apperr.Conflict("Order can only be edited while it is pending.").WithCode(apperr.CodeOrderStatus)
+		}
+		// ยอดของ payment intent ถูกกำหนดตอนสร้าง จึงแก้รายการไม่ได้ระหว่างที่ยังรอผลการชำระเงิน
+		open, err := hasOpenPayment(tx, order.ID)
+		if err != nil {
+			return err
+		}
+		if open {
+			return apperr.Conflict("Order can't be edited while a payment is in progress.").WithCode(apperr.CodeOrderStatus)
+		}
+
+		requested, err := findProducts(products, orderRequest.Items)
+		if err != nil {
+			return err
+		}
+
+		// สร้าง mapping สำหรับเก็บรายการ order ก่อนที่จะถูก update
+		originalItems := make(map[uint]m.Item)
+		for _, item := range order.Items {
+			originalItems[item.ProductID] = item
+		}
+
+		// สร้าง set โดยให้ product ที่อยู่ใน request เป็น true ทั้งหมด
+		updatedProducts := make(map[uint]bool)
+		for _, product := range requested {
+			updatedProducts[product.ID] = true
+		}
+
+		// สินค้าที่ไม่ได้อยู่ใน request คงไว้ตามเดิมทั้งจำนวนและราคาที่บันทึกไว้ตอนสั่งซื้อ
+		var updatedItems []m.Item
+		for _, item := range order.Items {
+			if !updatedProducts[item.ProductID] {
+				updatedItems = append(updatedItems, item)
+			}
+		}
+
+		// อัปเดตข้อมูล product จาก order ใหม่ที่ update มา
+		for i, item := range orderRequest.Items {
+			product := requested[i]
+
+			// กันหรือตัดสต็อกเพิ่ม (หรือคืน) เฉพาะส่วนต่างจากจำนวนเดิมใน order
+			originalItem, exists := originalItems[product.ID]
+			diff := item.Amount - originalItem.Amount
+			if order.HasReservation() {
+				err = reserveStock(products, product, diff)
+			} else {
+				move := m.StockMovement{Reason: m.StockOrderEdited, ActorID: tokenActor(c), Reference: m.OrderReference(order.ID)}
+				err = takeStock(products, product, diff, move)
+			}
+			if err != nil {
+				return err
+			}
+
+			if exists {
+				// ถ้า item นี้มีอยู่แล้วใน order, ให้ update จำนวนสินค้าโดยใช้ราคาเดิมตอนสั่งซื้อ
+				originalItem.SetAmount(item.Amount)
+				if err := orders.SaveItem(&originalItem); err != nil {
+					return apperr.Internal("Failed to update item.", err)
+				}
+				updatedItems = append(updatedItems, originalItem)
+			} else {
+				// ถ้า item นี้ยังไม่มีอยู่ใน order, ให้สร้างใหม่ด้วยราคาปัจจุบัน
+				newItem := newItem(product, item.Amount)
+				newItem.OrderID = order.ID
+				if err := orders.CreateItem(&newItem); err != nil {
+					return apperr.Internal("Failed to add new item.", err)
+				}
+				updatedItems = append(updatedItems, newItem)
+			}
+		}
+
+		// อัปเดตรายการสินค้าใน order และคำนวณส่วนลดใหม่ตามรายการที่แก้
+		order.Items = updatedItems
+		if err := updateDiscount(tx, order); err != nil {
+			return err
+		}
+		h.pricing.Apply(order)
+
+		if err := orders.Save(order); err != nil {
+			return saveError(err, "Failed to update order.")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	if err := c.BodyParser(&orderRequest); err != nil {
-		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	setETag(c, versionETag(order.Version))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data":    dto.NewOrderResponse(order),
+		"message": "Order has been successfully updated.",
+	})
+}
+
+// RemoveOrder ให้เจ้าของยกเลิก order ที่ยังไม่ได้ชำระเงิน (pending หรือชำระไม่สำเร็จ) โดยคืนสินค้าเข้าคลังและเก็บ order ไว้เป็นประวัติ
+func (h *OrderController) RemoveOrder(c *fiber.Ctx) error {
+	var order *m.Order
+
+	// คืนสต็อกและเปลี่ยนสถานะ order ใน transaction เดียว
+	err := h.store.Transaction(func(tx store.Store) error {
+		// ตรวจสอบว่า Order ที่ต้องการยกเลิกนี้มีอยู่ในระบบหรือไม่
+		var err error
+		order, err = tx.Orders().GetForUpdate(parseID(c.Params("orderId")))
+		if err != nil {
+			return apperr.NotFound("Order not found.")
+		}
+
+		// ตรวจสอบ userID ใน token กับ buyer ว่าตรงกันไหม
+		if order.Buyer != fmt.Sprintf("%v", tokenUserID(c)) {
+			return apperr.Unauthorized("Unauthorized to view this page.")
+		}
+		if err := checkIfMatch(c, versionETag(order.Version)); err != nil {
+			return err
+		}
+
+		// order ที่ชำระเงินแล้วต้องให้ admin ยกเลิกหรือคืนเงินผ่าน PUT /order/:orderId/status
+		if order.Status != m.OrderPending && order.Status != m.OrderFailed {
+			return apperr.Conflict("Only unpaid orders can be cancelled.").WithCode(apperr.CodeOrderStatus)
+		}
+
+		return changeOrderStatus(tx, order, m.OrderCancelled, tokenActor(c))
+	})
+	if err != nil {
+		return err
 	}
 
-	var total_price int
-	var total_price_nonupdate int
-	var updatedItems []m.Item
+	setETag(c, versionETag(order.Version))
+	return c.Status(200).JSON(fiber.Map{
+		"data":    dto.NewOrderResponse(order),
+		"message": "Order has been successfully cancelled.",
+	})
+}
 
-	// สร้าง mapping สำหรับเก็บรายการ order ก่อนที่จะถูก update
-	originalItems := make(map[string]m.Item)
-	for _, item := range order.Items {
-		originalItems[item.Product] = item
+// ConfirmOrder เปลี่ยนสินค้าที่กันไว้ของ order เป็นการตัดสต็อกจริง ให้เจ้าของหรือ admin เรียกได้ก่อนการกันสินค้าจะหมดเวลา
+// order ที่ชำระเงินสำเร็จจะถูกยืนยันให้อัตโนมัติ
+func (h *OrderController) ConfirmOrder(c *fiber.Ctx) error {
+	var order *m.Order
+
+	err := h.store.Transaction(func(tx store.Store) error {
+		var err error
+		order, err = tx.Orders().GetForUpdate(parseID(c.Params("orderId")))
+		if err != nil {
+			return apperr.NotFound("Order not found.")
+		}
+
+		if !canManageUser(c, parseID(order.Buyer)) {
+			return apperr.Unauthorized("Unauthorized to view this page.")
+		}
+
+		if !order.HasReservation() {
+			return apperr.Conflict("Order has no reserved products to confirm.").WithCode(apperr.CodeOrderStatus)
+		}
+		if order.ReservationExpired(time.Now()) {
+			return apperr.Conflict("Reservation of this order has expired.").WithCode(apperr.CodeOrderStatus)
+		}
+
+		if err := confirmReservation(tx, order, tokenActor(c)); err != nil {
+			return err
+		}
+		if err := tx.Orders().Save(order); err != nil {
+			return saveError(err, "Failed to update order.")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	// สร้าง set โดยให้ product ที่อยู่ใน request เป็น true ทั้งหมด
-	updatedProducts := make(map[string]bool)
-	for _, item := range orderRequest.Items {
-		updatedProducts[item.Product] = true
+	setETag(c, versionETag(order.Version))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data":    dto.NewOrderResponse(order),
+		"message": "Order has been confirmed.",
+	})
+}
+
+// UpdateOrderStatus ให้ admin เปลี่ยนสถานะ order ตามลำดับที่อนุญาตใน models.Order.TransitionTo
+func (h *OrderController) UpdateOrderStatus(c *fiber.Ctx) error {
+	var req dto.UpdateOrderStatusRequest
+	if err := bind(c, &req); err != nil {
+		return err
 	}
 
-	for productName, item := range originalItems {
-		// ตรวจสอบสินค้าที่ไม่ได้อยู่ใน request ==> updatedProducts[productName] == false
-		if !updatedProducts[productName] {
-			var product m.Product
-			if err := db.Where("Product_Name = ?", productName).First(&product).Error; err != nil {
-				return c.Status(500).SendString("Failed to retrieve product " + productName + " from the database.")
-			}
-			log.Printf("Item not included in update: Product: %s, Amount: %d, Price: %d", item.Product, item.Amount, product.Price)
-			total_price_nonupdate += product.Price * item.Amount
+	var order *m.Order
+	refunds := req.Status == m.OrderCancelled || req.Status == m.OrderRefunded
 
-			updatedItems = append(updatedItems, item)
+	err := h.store.Transaction(func(tx store.Store) error {
+		var err error
+		order, err = tx.Orders().GetForUpdate(parseID(c.Params("orderId")))
+		if err != nil {
+			return apperr.NotFound("Order not found.")
+		}
+		if err := checkIfMatch(c, versionETag(order.Version)); err != nil {
+			return err
+		}
+		// ส่งสถานะ cancelled/refunded เดิมซ้ำได้ เพื่อคืนเงินที่ค้างไว้จากครั้งก่อนที่เรียก provider ไม่สำเร็จ
+		if order.Status == req.Status && refunds {
+			return nil
+		}
+		if err := changeOrderStatus(tx, order, req.Status, tokenActor(c)); err != nil {
+			return err
 		}
+
+		// order ที่ถูกยกเลิกหรือคืนเงินหลังชำระเงินแล้วต้องคืนเงินผ่าน provider ด้วย
+		if refunds {
+			return refundPayments(tx, order.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	// อัปเดตข้อมูล product จาก order ใหม่ที่ update มา
-	for _, item := range orderRequest.Items {
-		var product m.Product
-		if err := db.Where("Product_Name = ?", item.Product).First(&product).Error; err != nil {
-			return c.Status(500).SendString("Product " + item.Product + " not found.")
+	// provider ถูกเรียกหลังสถานะ order commit แล้ว ถ้าล้มเหลว payment จะค้างที่ refunding จนกว่าจะส่งสถานะเดิมซ้ำ
+	if refunds {
+		if err := settleRefunds(c.UserContext(), h.store, h.provider, order.ID); err != nil {
+			return err
 		}
+	}
+
+	setETag(c, versionETag(order.Version))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data":    dto.NewOrderResponse(order),
+		"message": "Order status has been updated to " + order.Status + ".",
+	})
+}
+
+// placeOrder สร้าง order ให้ buyer และกันสินค้าของทุกรายการไว้เป็นเวลา ttl ใช้ร่วมกันระหว่าง AddOrder และ checkout ตะกร้า
+// สินค้าถูกตัดสต็อกจริงเมื่อ order ถูกยืนยันหรือชำระเงิน ถ้าไม่ทันเวลา ReleaseExpiredReservations จะยกเลิก order
+// ต้องเรียกภายใน Transaction เพื่อให้ order และสินค้าที่กันไว้ของรายการก่อนหน้าถูก rollback ถ้ารายการใดล้มเหลว
+func placeOrder(tx store.Store, pricer *pricing.Calculator, ttl time.Duration, buyer string, items []dto.ItemRequest, couponCode string) (*m.Order, error) {
+	products, err := findProducts(tx.Products(), items)
+	if err != nil {
+		return nil, err
+	}
 
-		// ตรวจสอบจำนวนสินค้า
-		originalAmount := originalItems[item.Product].Amount
-		if product.Amount+originalAmount < item.Amount {
-			return c.Status(400).SendString(
-				"Product " + item.Product + " is not available in sufficient quantity.",
-			)
+	now := time.Now()
+	reservedUntil := now.Add(ttl)
+	order := &m.Order{Buyer: buyer, ReservedUntil: &reservedUntil}
+	for i, item := range items {
+		if err := reserveStock(tx.Products(), products[i], item.Amount); err != nil {
+			return nil, err
 		}
+		order.Items = append(order.Items, newItem(products[i], item.Amount))
+	}
 
-		// Update จำนวนสินค้าใน Product
-		product.Amount = product.Amount + originalAmount - item.Amount
-		if err := db.Save(&product).Error; err != nil {
-			return c.Status(500).SendString("Failed to update product quantity.")
+	userID := parseID(buyer)
+	if couponCode != "" {
+		if err := applyCoupon(tx, order, couponCode, userID, now); err != nil {
+			return nil, err
 		}
+	}
+	pricer.Apply(order)
 
-		// คำนวณราคาใหม่
-		total_price += product.Price * item.Amount
+	if err := tx.Orders().Create(order); err != nil {
+		return nil, apperr.Internal("Failed to create order.", err)
+	}
 
-		if originalItems, exists := originalItems[item.Product]; exists {
-			// ถ้า item นี้มีอยู่แล้วใน order, ให้ update จำนวนสินค้า
-			originalItems.Amount = item.Amount
-			if err := db.Save(&originalItems).Error; err != nil {
-				return c.Status(500).SendString("Failed to update item.")
-			}
-			updatedItems = append(updatedItems, originalItems)
+	if order.CouponID != nil {
+		redemption := m.CouponRedemption{CouponID: *order.CouponID, UserID: userID, OrderID: order.ID}
+		if err := tx.Coupons().CreateRedemption(&redemption); err != nil {
+			return nil, apperr.Internal("Failed to redeem coupon.", err)
+		}
+	}
+	return order, nil
+}
+
+// findProducts หาสินค้าของแต่ละรายการใน request ตามลำดับเดิม จาก ProductID หรือชื่อถ้าไม่ได้ส่ง ID มา
+// และตรวจว่าไม่มีสินค้าตัวเดียวกันซ้ำในหลายรายการ
+func findProducts(products store.ProductStore, items []dto.ItemRequest) ([]*m.Product, error) {
+	found := make([]*m.Product, len(items))
+	seen := make(map[uint]bool)
+
+	for i, item := range items {
+		var product *m.Product
+		var err error
+		if item.ProductID != 0 {
+			product, err = products.Get(item.ProductID)
 		} else {
-			// ถ้า item นี้ยังไม่มีอยู่ใน order, ให้สร้างใหม่
-			newItem := m.Item{
-				Product: item.Product,
-				Amount:  item.Amount,
-				OrderID: order.ID,
-			}
-			if err := db.Create(&newItem).Error; err != nil {
-				return c.Status(500).SendString("Failed to add new item.")
-			}
-			updatedItems = append(updatedItems, newItem)
+			product, err = products.GetByName(item.Product)
 		}
+		if err != nil {
+			return nil, apperr.NotFound("Didn't find the product you were looking for.")
+		}
+
+		if seen[product.ID] {
+			return nil, dto.Invalid(dto.FieldError{
+				Field:   "Items",
+				Rule:    "unique",
+				Message: "Items must not contain the same product twice.",
+			})
+		}
+		seen[product.ID] = true
+		found[i] = product
 	}
+	return found, nil
+}
 
-	// อัปเดตรายการสินค้าใน order
-	order.Items = updatedItems
-	order.Total_Price = total_price + total_price_nonupdate
+// newItem สร้างรายการสินค้าโดยบันทึกชื่อ ราคา และน้ำหนักปัจจุบันของสินค้าไว้
+func newItem(product *m.Product, amount int) m.Item {
+	item := m.Item{
+		ProductID:  product.ID,
+		Product:    product.Product_Name,
+		Unit_Price: product.Price,
+		Weight:     product.Weight,
+	}
+	item.SetAmount(amount)
+	return item
+}
 
-	if err := db.Save(&order).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString("Failed to update order.")
+// takeStock ตัดสต็อกสินค้า (amount ติดลบคือคืนสต็อก) และบันทึกลง ledger ตาม Reason, ActorID และ Reference ของ move
+// ต้องเรียกภายใน Transaction เพื่อให้ rollback ได้ถ้ารายการถัดไปล้มเหลว
+func takeStock(products store.ProductStore, product *m.Product, amount int, move m.StockMovement) error {
+	if amount == 0 {
+		return nil
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"data":    order,
-		"message": "Order has been successfully updated.",
-	})
+	move.ProductID = product.ID
+	move.Delta = -amount
+	return stockError(product, products.AdjustStock(&move))
 }
 
-func RemoveOrder(c *fiber.Ctx) error {
-	db := database.DBConn
-	orderId := c.Params("orderId")
-	var order m.Order
+// reserveStock กันสินค้าไว้ให้ order (amount ติดลบคือปล่อยคืน)
+// ต้องเรียกภายใน Transaction เพื่อให้ rollback ได้ถ้ารายการถัดไปล้มเหลว
+func reserveStock(products store.ProductStore, product *m.Product, amount int) error {
+	if amount == 0 {
+		return nil
+	}
+	return stockError(product, products.Reserve(product.ID, amount))
+}
 
-	// ตรวจสอบว่า Order ที่ต้องการ delete นี้มีอยู่ในระบบหรือไม่
-	if err := db.Preload("Items").Where("id = ?", orderId).First(&order).Error; err != nil {
-		return c.Status(404).SendString("Order not found.")
+// stockError แปลง error จากการตัดหรือกันสต็อกของ product ให้เป็น error ที่ตอบกลับ client ได้
+func stockError(product *m.Product, err error) error {
+	if errors.Is(err, store.ErrInsufficientStock) {
+		return apperr.Conflict("Product " + product.Product_Name + " is not available in sufficient quantity.").WithCode(apperr.CodeInsufficientStock)
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return apperr.NotFound("Didn't find the product you were looking for.")
+	}
+	if err != nil {
+		return apperr.Internal("Failed to update product quantity.", err)
 	}
+	return nil
+}
 
-	// ดึง clamis จาก context ที่ถูกเซ็ตใน middleware
-	claims := c.Locals("user").(jwt.MapClaims)
-	tokenUserID := claims["UserID"].(float64)
+// changeOrderStatus เปลี่ยนสถานะ order และจัดการสต็อกตามสถานะใหม่:
+// ถ้ายกเลิกหรือคืนเงินก่อนสินค้าจะถูกส่งออกไปจะปล่อยสินค้าที่กันไว้หรือคืนสินค้าเข้าคลัง
+// ถ้าชำระเงินสำเร็จขณะที่สินค้ายังถูกกันไว้จะตัดสต็อกจริงเหมือนการยืนยัน order
+// actorID คือผู้เปลี่ยนสถานะที่ถูกบันทึกใน ledger (nil คือระบบ เช่น webhook หรือการกันสินค้าหมดเวลา)
+// ต้องเรียกภายใน Transaction หลังจาก lock order ด้วย GetForUpdate แล้ว
+func changeOrderStatus(tx store.Store, order *m.Order, status string, actorID *uint) error {
+	from := order.Status
+	// ยกเลิกหรือคืนเงินก่อนที่สินค้าจะถูกส่งออกไป สินค้ายังอยู่ในคลังจึงต้องนับกลับเข้าไป
+	restock := order.HoldsStock() && (status == m.OrderCancelled || status == m.OrderRefunded)
+
+	if err := order.TransitionTo(status, time.Now()); err != nil {
+		return apperr.Conflict(fmt.Sprintf("Order can't be changed from %s to %s.", from, status)).WithCode(apperr.CodeOrderStatus)
+	}
 
-	// ตรวจสอบ userID ใน token กับ buyer ว่าตรงกันไหม
-	if order.Buyer != fmt.Sprintf("%v", uint(tokenUserID)) {
-		return c.Status(fiber.StatusUnauthorized).SendString("Unauthorized to view this page.")
+	switch {
+	case restock && order.HasReservation():
+		if err := releaseReservation(tx, order); err != nil {
+			return err
+		}
+	case restock:
+		for _, item := range order.Items {
+			// รายการเก่าที่ migrate มาแล้วหาสินค้าไม่เจอจะไม่มี ProductID จึงไม่มีอะไรให้คืน
+			if item.ProductID == 0 {
+				continue
+			}
+			move := m.StockMovement{
+				ProductID: item.ProductID,
+				Delta:     item.Amount,
+				Reason:    m.StockOrderCancelled,
+				ActorID:   actorID,
+				Reference: m.OrderReference(order.ID),
+			}
+			if status == m.OrderRefunded {
+				move.Reason = m.StockOrderRefunded
+			}
+			if err := tx.Products().Restock(&move); err != nil {
+				return apperr.Internal("Failed to update product amount in inventory.", err)
+			}
+		}
+	case status == m.OrderPaid && order.HasReservation():
+		if err := confirmReservation(tx, order, actorID); err != nil {
+			return err
+		}
 	}
 
-	for _, item := range order.Items {
-		var product m.Product
-		if err := db.Where("Product_Name = ?", item.Product).First(&product).Error; err != nil {
-			return c.Status(500).SendString("Product " + item.Product + " not found.")
+	// order ที่ถูกยกเลิกไม่นับเป็นการใช้ coupon
+	if status == m.OrderCancelled && order.CouponID != nil {
+		if err := tx.Coupons().Release(*order.CouponID, order.ID); err != nil {
+			return apperr.Internal("Failed to release coupon.", err)
 		}
+	}
 
-		product.Amount += item.Amount
+	if err := tx.Orders().Save(order); err != nil {
+		return saveError(err, "Failed to update order.")
+	}
+	return nil
+}
 
-		if err := db.Save(&product).Error; err != nil {
-			return c.Status(500).SendString("Failed to update product amount in inventory.")
+// confirmReservation เปลี่ยนสินค้าที่กันไว้ของ order เป็นการตัดสต็อกจริงและบันทึกลง ledger
+// caller ต้องบันทึก order เองหลังจากนี้
+func confirmReservation(tx store.Store, order *m.Order, actorID *uint) error {
+	for _, item := range order.Items {
+		move := m.StockMovement{
+			ProductID: item.ProductID,
+			Delta:     -item.Amount,
+			Reason:    m.StockOrderPlaced,
+			ActorID:   actorID,
+			Reference: m.OrderReference(order.ID),
+		}
+		if err := tx.Products().ConfirmReservation(&move); err != nil {
+			return apperr.Internal("Failed to update product quantity.", err)
 		}
 	}
+	order.ReservedUntil = nil
+	return nil
+}
 
-	// ลบรายการสินค้าในคำสั่งซื้อนั้น
-	if err := db.Unscoped().Where("order_id = ?", orderId).Delete(&m.Item{}).Error; err != nil {
-		return c.Status(500).SendString("Failed to delete order items.")
+// releaseReservation ปล่อยสินค้าที่กันไว้ของ order คืน ไม่มีการบันทึกลง ledger เพราะจำนวนในคลังไม่เปลี่ยน
+// caller ต้องบันทึก order เองหลังจากนี้
+func releaseReservation(tx store.Store, order *m.Order) error {
+	for _, item := range order.Items {
+		if err := tx.Products().Release(item.ProductID, item.Amount); err != nil {
+			return apperr.Internal("Failed to release reserved products.", err)
+		}
 	}
+	order.ReservedUntil = nil
+	return nil
+}
 
-	// ลบคำสั่งซื้อ
-	if err := db.Unscoped().Delete(&order, orderId).Error; err != nil {
-		return c.Status(500).SendString("Failed to delete order.")
+// ReleaseExpiredReservations ยกเลิก order ที่การกันสินค้าหมดเวลาแล้ว ณ เวลา now และปล่อยสินค้าคืน
+// แต่ละ order ทำใน transaction ของตัวเอง order ที่ถูกยืนยันหรือยกเลิกไปก่อนระหว่างนั้นจะถูกข้าม
+// คืนจำนวน order ที่ถูกยกเลิก และคืน error เฉพาะเมื่อโหลดรายการ order ไม่ได้
+func ReleaseExpiredReservations(s store.Store, now time.Time) (int, error) {
+	expired, err := s.Orders().ListExpiredReservations(now)
+	if err != nil {
+		return 0, err
 	}
 
-	return c.Status(200).JSON(fiber.Map{
-		"message": "Order has been successfully deleted.",
-	})
+	released := 0
+	for _, candidate := range expired {
+		cancelled := false
+		err := s.Transaction(func(tx store.Store) error {
+			order, err := tx.Orders().GetForUpdate(candidate.ID)
+			if err != nil || !order.ReservationExpired(now) {
+				return err
+			}
+			if err := changeOrderStatus(tx, order, m.OrderCancelled, nil); err != nil {
+				return err
+			}
+			cancelled = true
+			return nil
+		})
+		// order ที่ยกเลิกไม่ได้ไม่ควรทำให้ order อื่นค้างการกันสินค้าไว้ จึง log แล้วทำตัวถัดไป
+		if err != nil {
+			log.Printf("Reservation sweeper: releasing order %d: %v", candidate.ID, err)
+			continue
+		}
+		// นับเฉพาะ order ที่ commit แล้ว ผู้เรียกใช้จำนวนนี้ตัดสินว่าต้องล้าง cache ของรายการสินค้าหรือไม่
+		if cancelled {
+			released++
+		}
+	}
+	return released, nil
 }
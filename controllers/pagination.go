@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"go-fiber-test/apperr"
+	"go-fiber-test/dto"
+	"go-fiber-test/store"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// defaultPageLimit คือจำนวนแถวต่อหน้าเมื่อไม่ได้ส่ง limit มา
+const defaultPageLimit = 20
+
+// sortKeys คือ column ที่ใช้เรียง list ของ resource หนึ่งได้ และวิธีอ่านค่าของ column นั้นจากแถว
+// เพื่อนำไปสร้าง cursor
+type sortKeys[T any] map[string]func(*T) any
+
+// cursorToken คือข้อมูลใน cursor ก่อนถูก encode เป็น base64 โดยผูกกับ sort ที่ใช้ตอนสร้าง
+type cursorToken struct {
+	Sort   string `json:"s"`
+	Value  any    `json:"v,omitempty"`
+	Time   bool   `json:"t,omitempty"`
+	ID     uint   `json:"id"`
+	Before bool   `json:"b,omitempty"`
+}
+
+// bindQuery parse query string ลงใน req ทุกตัวแล้วตรวจสอบตาม validate tag ของ dto
+func bindQuery(c *fiber.Ctx, reqs ...any) error {
+	for _, req := range reqs {
+		if err := c.QueryParser(req); err != nil {
+			return apperr.BadRequest("Query string is invalid.").WithCode(apperr.CodeValidation)
+		}
+		if err := dto.Validate(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newPage แปลง query ของการแบ่งหน้าและ sort เป็น store.Page
+func newPage(query dto.PageQuery, sort string) (store.Page, error) {
+	column, desc := dto.ParseSort(sort)
+	page := store.Page{
+		Limit:  query.Limit,
+		Offset: query.Offset,
+		Sort:   column,
+		Desc:   desc,
+	}
+	if page.Limit == 0 {
+		page.Limit = defaultPageLimit
+	}
+
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor, sort)
+		if err != nil {
+			return page, err
+		}
+		page.Cursor = cursor
+	}
+	return page, nil
+}
+
+func decodeCursor(value, sort string) (*store.Cursor, error) {
+	invalid := apperr.BadRequest("Cursor is invalid or was created with a different sort.").WithCode(apperr.CodeValidation)
+
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, invalid
+	}
+	var token cursorToken
+	if err := json.Unmarshal(raw, &token); err != nil || token.Sort != sort {
+		return nil, invalid
+	}
+
+	cursor := &store.Cursor{Value: token.Value, ID: token.ID, Before: token.Before}
+	switch v := token.Value.(type) {
+	case float64:
+		cursor.Value = int64(v)
+	case string:
+		if token.Time {
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return nil, invalid
+			}
+			cursor.Value = t
+		}
+	}
+	return cursor, nil
+}
+
+func encodeCursor[T any](keys sortKeys[T], sort string, item *T, id uint, before bool) string {
+	column, _ := dto.ParseSort(sort)
+	token := cursorToken{Sort: sort, ID: id, Before: before}
+	if column != "" && column != "id" {
+		token.Value = keys[column](item)
+		if t, ok := token.Value.(time.Time); ok {
+			token.Value = t.Format(time.RFC3339Nano)
+			token.Time = true
+		}
+	}
+	raw, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// listResponse สร้าง response ของ list หนึ่งหน้า พร้อม meta (total, limit, cursor) และ links ไปหน้าถัดไป/ก่อนหน้า
+func listResponse[T, R any](c *fiber.Ctx, keys sortKeys[T], id func(*T) uint, sort string, page store.Page, result store.Result[T], data []R, message string) error {
+	var next, prev, nextCursor, prevCursor string
+	items := result.Items
+
+	cursorAt := func(i int, before bool) string {
+		return encodeCursor(keys, sort, &items[i], id(&items[i]), before)
+	}
+
+	if page.Cursor == nil {
+		// offset pagination: ยังมีหน้าถัดไปถ้าแถวที่ผ่านมารวมกับหน้านี้ยังไม่ครบ total
+		if len(items) > 0 && int64(page.Offset+len(items)) < result.Total {
+			next = pageLink(c, "offset", strconv.Itoa(page.Offset+page.Limit))
+			nextCursor = cursorAt(len(items)-1, false)
+		}
+		if page.Offset > 0 {
+			prev = pageLink(c, "offset", strconv.Itoa(max(page.Offset-page.Limit, 0)))
+			if len(items) > 0 {
+				prevCursor = cursorAt(0, true)
+			}
+		}
+	} else if len(items) > 0 {
+		// keyset pagination: More บอกว่ายังมีแถวในทิศทางที่เดินอยู่ ส่วนอีกทิศทางมีแน่นอนเพราะมาจาก cursor
+		if result.More || page.Cursor.Before {
+			nextCursor = cursorAt(len(items)-1, false)
+		}
+		if result.More || !page.Cursor.Before {
+			prevCursor = cursorAt(0, true)
+		}
+	}
+	if nextCursor != "" && next == "" {
+		next = pageLink(c, "cursor", nextCursor)
+	}
+	if prevCursor != "" && prev == "" {
+		prev = pageLink(c, "cursor", prevCursor)
+	}
+
+	meta := fiber.Map{
+		"total":       result.Total,
+		"limit":       page.Limit,
+		"next_cursor": nullable(nextCursor),
+		"prev_cursor": nullable(prevCursor),
+	}
+	if page.Cursor == nil {
+		meta["offset"] = page.Offset
+	}
+	if sort != "" {
+		meta["sort"] = sort
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"data":    data,
+		"message": message,
+		"meta":    meta,
+		"links": fiber.Map{
+			"self": c.OriginalURL(),
+			"next": nullable(next),
+			"prev": nullable(prev),
+		},
+	})
+}
+
+// pageLink คืน URL ของ request ปัจจุบันที่เปลี่ยน offset หรือ cursor เป็นค่าใหม่ (และลบอีกตัวทิ้ง)
+func pageLink(c *fiber.Ctx, key, value string) string {
+	query := url.Values{}
+	for k, v := range c.Queries() {
+		query.Set(k, v)
+	}
+	query.Del("offset")
+	query.Del("cursor")
+	query.Set(key, value)
+	return c.Path() + "?" + query.Encode()
+}
+
+// nullable คืน nil แทน string ว่าง เพื่อให้ JSON เป็น null
+func nullable(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
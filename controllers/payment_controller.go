@@ -0,0 +1,269 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-fiber-test/apperr"
+	"go-fiber-test/dto"
+	m "go-fiber-test/models"
+	"go-fiber-test/payment"
+	"go-fiber-test/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PaymentController struct {
+	store    store.Store
+	provider payment.Provider
+	currency string
+}
+
+func NewPaymentController(s store.Store, provider payment.Provider, currency string) *PaymentController {
+	return &PaymentController{store: s, provider: provider, currency: currency}
+}
+
+// CreatePayment สร้าง payment intent สำหรับยอดรวมของ order ที่ยังไม่ได้ชำระเงิน
+// order จะเปลี่ยนเป็น paid เมื่อ provider ส่ง webhook มายืนยันเท่านั้น
+func (h *PaymentController) CreatePayment(c *fiber.Ctx) error {
+	var created *m.Payment
+	var clientSecret string
+
+	err := h.store.Transaction(func(tx store.Store) error {
+		order, err := tx.Orders().GetForUpdate(parseID(c.Params("orderId")))
+		if err != nil {
+			return apperr.NotFound("Order not found.")
+		}
+
+		// ตรวจสอบ userID ใน token กับ buyer ว่าตรงกันไหม
+		if order.Buyer != fmt.Sprintf("%v", tokenUserID(c)) {
+			return apperr.Unauthorized("Unauthorized to view this page.")
+		}
+
+		if !order.CanTransitionTo(m.OrderPaid) {
+			return apperr.Conflict("Only pending or failed orders can be paid.").WithCode(apperr.CodeOrderStatus)
+		}
+
+		intent, err := h.provider.CreateIntent(c.UserContext(), payment.IntentRequest{
+			OrderID:  order.ID,
+			Amount:   order.Total_Price,
+			Currency: h.currency,
+		})
+		if err != nil {
+			return providerError(err)
+		}
+
+		created = &m.Payment{
+			OrderID:     order.ID,
+			Provider:    h.provider.Name(),
+			ProviderRef: intent.ID,
+			Amount:      intent.Amount,
+			Currency:    intent.Currency,
+			Status:      m.PaymentPending,
+		}
+		if err := tx.Payments().Create(created); err != nil {
+			return apperr.Internal("Failed to create payment.", err)
+		}
+		clientSecret = intent.ClientSecret
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	response := dto.NewPaymentResponse(created)
+	response.ClientSecret = clientSecret
+	return c.Status(201).JSON(fiber.Map{
+		"data":    response,
+		"message": "Payment has been created.",
+	})
+}
+
+// Webhook รับ event จาก provider หลังตรวจลายเซ็นแล้ว และเปลี่ยนสถานะ payment กับ order ตาม event
+// event ที่ส่งซ้ำจะไม่มีผลซ้ำ เพราะ provider มักส่ง webhook เดิมอีกครั้งถ้าไม่ได้รับ 2xx
+func (h *PaymentController) Webhook(c *fiber.Ctx) error {
+	event, err := h.provider.VerifyWebhook(c.Body(), c.Get(payment.SignatureHeader))
+	if errors.Is(err, payment.ErrInvalidSignature) {
+		return apperr.Unauthorized("Webhook signature is invalid.").WithCode(apperr.CodeInvalidSignature)
+	}
+	if err != nil {
+		return apperr.InvalidBody(err)
+	}
+
+	// provider ถูกเรียกนอก transaction เสมอ สถานะ capturing/refunding ถูกบันทึกไว้ก่อน
+	// ถ้าเรียก provider หรือบันทึกผลไม่สำเร็จ webhook ที่ provider ส่งซ้ำจะทำต่อจากสถานะนั้น
+	var orderID uint
+	var capture bool
+	err = h.store.Transaction(func(tx store.Store) error {
+		record, order, err := h.loadPayment(tx, event.IntentID)
+		if err != nil {
+			return err
+		}
+		orderID = order.ID
+
+		switch event.Type {
+		case payment.EventAuthorized:
+			if record.Status == m.PaymentPending {
+				record.Status = m.PaymentCapturing
+				if err := tx.Payments().Save(record); err != nil {
+					return apperr.Internal("Failed to update payment.", err)
+				}
+			}
+			capture = record.Status == m.PaymentCapturing
+		case payment.EventSucceeded:
+			// payment ที่ค้างที่ capturing ถูกตัดเงินไปแล้วถ้า provider แจ้งว่าสำเร็จ
+			if record.Status == m.PaymentPending || record.Status == m.PaymentCapturing {
+				return paymentSucceeded(tx, record, order)
+			}
+		case payment.EventFailed:
+			if record.Status != m.PaymentPending {
+				return nil
+			}
+			record.Status = m.PaymentFailed
+			if err := tx.Payments().Save(record); err != nil {
+				return apperr.Internal("Failed to update payment.", err)
+			}
+			if order.Status == m.OrderPending {
+				return changeOrderStatus(tx, order, m.OrderFailed, nil)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if capture {
+		if err := h.capture(c.UserContext(), event.IntentID); err != nil {
+			return err
+		}
+	}
+	if err := settleRefunds(c.UserContext(), h.store, h.provider, orderID); err != nil {
+		return err
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"message": "Webhook processed.",
+	})
+}
+
+// loadPayment โหลด payment จาก ID ของ intent และ lock order ของ payment ไว้จนจบ transaction
+// payment ถูกอ่านซ้ำหลัง lock เพราะ request อื่นที่ถือ lock อยู่อาจเปลี่ยนสถานะไปแล้ว
+func (h *PaymentController) loadPayment(tx store.Store, intentID string) (*m.Payment, *m.Order, error) {
+	record, err := tx.Payments().GetByProviderRef(h.provider.Name(), intentID)
+	if err != nil {
+		return nil, nil, apperr.NotFound("Payment not found.")
+	}
+	order, err := tx.Orders().GetForUpdate(record.OrderID)
+	if err != nil {
+		return nil, nil, apperr.Internal("Failed to load order of payment.", err)
+	}
+	record, err = tx.Payments().GetByProviderRef(h.provider.Name(), intentID)
+	if err != nil {
+		return nil, nil, apperr.Internal("Failed to load payment.", err)
+	}
+	return record, order, nil
+}
+
+// capture ตัดเงินของ payment ที่บันทึกสถานะ capturing ไว้แล้ว แล้วบันทึกผลใน transaction ใหม่
+// ถ้า request อื่นบันทึกผลไปก่อนแล้วจะไม่ทำซ้ำ
+func (h *PaymentController) capture(ctx context.Context, intentID string) error {
+	if _, err := h.provider.Capture(ctx, intentID); err != nil {
+		return providerError(err)
+	}
+	return h.store.Transaction(func(tx store.Store) error {
+		record, order, err := h.loadPayment(tx, intentID)
+		if err != nil {
+			return err
+		}
+		if record.Status != m.PaymentCapturing {
+			return nil
+		}
+		return paymentSucceeded(tx, record, order)
+	})
+}
+
+// paymentSucceeded บันทึกว่าตัดเงินสำเร็จและเปลี่ยน order เป็น paid
+// ถ้า order ถูกยกเลิกหรือชำระด้วย payment อื่นไปแล้วระหว่างนั้น หรือยอดที่ตัดไม่ตรงกับยอดของ order ตอนนี้
+// จะบันทึกว่าต้องคืนเงินของ payment นี้และ order คงสถานะเดิม settleRefunds คืนเงินหลัง transaction commit
+func paymentSucceeded(tx store.Store, record *m.Payment, order *m.Order) error {
+	record.Status = m.PaymentSucceeded
+
+	if order.CanTransitionTo(m.OrderPaid) && record.Amount == order.Total_Price {
+		if err := changeOrderStatus(tx, order, m.OrderPaid, nil); err != nil {
+			return err
+		}
+	} else {
+		record.Status = m.PaymentRefunding
+	}
+
+	if err := tx.Payments().Save(record); err != nil {
+		return apperr.Internal("Failed to update payment.", err)
+	}
+	return nil
+}
+
+// refundPayments บันทึกว่าต้องคืนเงินทุก payment ของ order ที่ตัดเงินไปแล้ว ใช้เมื่อ order ถูกยกเลิกหรือคืนเงิน
+// ต้องเรียกภายใน Transaction เดียวกับการเปลี่ยนสถานะ order แล้วเรียก settleRefunds หลัง commit
+func refundPayments(tx store.Store, orderID uint) error {
+	payments, err := tx.Payments().ListByOrder(orderID)
+	if err != nil {
+		return apperr.Internal("Failed to load payments.", err)
+	}
+
+	for i := range payments {
+		if payments[i].Status != m.PaymentSucceeded {
+			continue
+		}
+		payments[i].Status = m.PaymentRefunding
+		if err := tx.Payments().Save(&payments[i]); err != nil {
+			return apperr.Internal("Failed to update payment.", err)
+		}
+	}
+	return nil
+}
+
+// settleRefunds คืนเงินผ่าน provider ให้ทุก payment ของ order ที่บันทึกสถานะ refunding ไว้แล้ว
+// เรียกหลัง transaction ที่บันทึกสถานะนั้น commit แล้ว ถ้า provider หรือการบันทึกผลล้มเหลว
+// payment จะค้างที่ refunding และการเรียกครั้งถัดไปจะคืนเงินต่อ
+func settleRefunds(ctx context.Context, s store.Store, provider payment.Provider, orderID uint) error {
+	payments, err := s.Payments().ListByOrder(orderID)
+	if err != nil {
+		return apperr.Internal("Failed to load payments.", err)
+	}
+
+	for i := range payments {
+		if payments[i].Status != m.PaymentRefunding {
+			continue
+		}
+		if _, err := provider.Refund(ctx, payments[i].ProviderRef); err != nil {
+			return providerError(err)
+		}
+		payments[i].Status = m.PaymentRefunded
+		if err := s.Payments().Save(&payments[i]); err != nil {
+			return apperr.Internal("Failed to update payment.", err)
+		}
+	}
+	return nil
+}
+
+// hasOpenPayment บอกว่า order มี payment ที่ยังรอผลจาก provider อยู่หรือไม่
+func hasOpenPayment(tx store.Store, orderID uint) (bool, error) {
+	payments, err := tx.Payments().ListByOrder(orderID)
+	if err != nil {
+		return false, apperr.Internal("Failed to load payments.", err)
+	}
+	for _, p := range payments {
+		if p.Status == m.PaymentPending || p.Status == m.PaymentCapturing {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// providerError แปลง error จาก payment provider เป็น 502 โดยไม่เปิดเผยรายละเอียดให้ client
+func providerError(err error) error {
+	e := apperr.New(fiber.StatusBadGateway, apperr.CodePaymentProvider, "Payment provider request failed.")
+	e.Err = err
+	return e
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"errors"
+	"go-fiber-test/apperr"
+	"go-fiber-test/store"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// modifiedMessage คือข้อความของ 412 เมื่อ resource ถูกแก้ไปแล้วหลังจากที่ client อ่าน
+const modifiedMessage = "Resource has been modified by another request. Please reload and try again."
+
+// versionETag คือ ETag (strong) ของ resource จาก version ในฐานข้อมูล
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// setETag ใส่ ETag ของ resource ใน response เพื่อให้ client ส่งกลับมาใน If-Match ตอนแก้ไขหรือลบ
+func setETag(c *fiber.Ctx, etag string) {
+	c.Set(fiber.HeaderETag, etag)
+}
+
+// checkIfMatch ตรวจ If-Match กับ ETag ปัจจุบันของ resource ถ้าไม่ได้ส่ง header มาจะผ่านเสมอ
+// "*" ตรงกับทุก ETag ส่วน weak ETag (W/"...") ไม่ตรงกับอะไรเลยเพราะ If-Match ต้องเทียบแบบ strong
+func checkIfMatch(c *fiber.Ctx, etag string) error {
+	header := c.Get(fiber.HeaderIfMatch)
+	if header == "" {
+		return nil
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return nil
+		}
+	}
+	return apperr.PreconditionFailed(modifiedMessage)
+}
+
+// saveError แปลง error จากการบันทึก resource ที่มี version ถ้ามี request อื่นบันทึกไปก่อนจะตอบ 412 แทน 500
+func saveError(err error, message string) error {
+	if errors.Is(err, store.ErrVersionConflict) {
+		return apperr.PreconditionFailed(modifiedMessage)
+	}
+	return apperr.Internal(message, err)
+}
@@ -1,226 +1,313 @@
 package controllers
 
 import (
-	"go-fiber-test/database"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-fiber-test/apperr"
+	"go-fiber-test/dto"
 	m "go-fiber-test/models"
+	"go-fiber-test/store"
+	"log"
+	"net/http"
 	"os"
 	"path/filepath"
-	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
-func GetProducts(c *fiber.Ctx) error {
-	db := database.DBConn
-	var products []m.Product
+type ProductController struct {
+	store     store.Store
+	uploadDir string
+}
 
-	db.Preload("Images").Find(&products)
-	return c.Status(200).JSON(fiber.Map{
-		"data":    &products,
-		"message": "Show all products.",
-	})
+func NewProductController(s store.Store, uploadDir string) *ProductController {
+	return &ProductController{store: s, uploadDir: uploadDir}
 }
 
-func GetProduct(c *fiber.Ctx) error {
-	db := database.DBConn
-	productId := c.Params("productId")
-	var product m.Product
+// imagePath คืน path ของไฟล์รูปภาพใน upload dir จาก ImageURL ที่เก็บไว้ในฐานข้อมูล
+func (h *ProductController) imagePath(imageURL string) string {
+	return filepath.Join(h.uploadDir, filepath.Base(imageURL))
+}
 
-	db.Preload("Images").Where("id = ?", productId).Find(&product)
-	return c.Status(200).JSON(fiber.Map{
-		"data":    &product,
-		"message": "Show " + product.Product_Name + " success.",
-	})
+// productSortKeys คือ column ที่ใช้เรียง GET /product ได้ (ต้องตรงกับ rule sort ใน dto.ProductListQuery)
+var productSortKeys = sortKeys[m.Product]{
+	"id":           func(p *m.Product) any { return p.ID },
+	"product_name": func(p *m.Product) any { return p.Product_Name },
+	"price":        func(p *m.Product) any { return p.Price },
+	"amount":       func(p *m.Product) any { return p.Amount },
+	"created_at":   func(p *m.Product) any { return p.CreatedAt },
+	"updated_at":   func(p *m.Product) any { return p.UpdatedAt },
+}
+
+// productListETag คือ weak ETag ของหน้ารายการสินค้า ซึ่งเปลี่ยนเมื่อสินค้าในหน้า version ของสินค้าเหล่านั้น หรือจำนวนสินค้าทั้งหมดเปลี่ยน
+func productListETag(result store.Result[m.Product]) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d %t", result.Total, result.More)
+	for i := range result.Items {
+		fmt.Fprintf(hash, " %d:%d", result.Items[i].ID, result.Items[i].Version)
+	}
+	return `W/"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// setLastModified ใส่ Last-Modified ในรูปแบบวันที่ของ HTTP
+func setLastModified(c *fiber.Ctx, t time.Time) {
+	c.Set(fiber.HeaderLastModified, t.UTC().Format(http.TimeFormat))
 }
 
-func GetProductImage(c *fiber.Ctx) error {
-	db := database.DBConn
-	productID := c.Params("product_id")
-	imageID := c.Params("image_id")
+func (h *ProductController) GetProducts(c *fiber.Ctx) error {
+	var pageQuery dto.PageQuery
+	var query dto.ProductListQuery
+	if err := bindQuery(c, &pageQuery, &query); err != nil {
+		return err
+	}
+
+	page, err := newPage(pageQuery, query.Sort)
+	if err != nil {
+		return err
+	}
 
-	var product m.Product
-	if err := db.Preload("Images").Where("id = ?", productID).First(&product).Error; err != nil {
-		return c.Status(404).SendString("Product not found.")
+	result, err := h.store.Products().List(productFilter(query), page)
+	if err != nil {
+		return apperr.Internal("Failed to load products.", err)
 	}
 
-	var images m.ProductImage
-	if err := db.Where("id = ? AND product_id =?", imageID, productID).First(&images).Error; err != nil {
-		return c.Status(404).SendString("Image not found.")
+	// หน้ารายการไม่มี Last-Modified เพราะสินค้าที่ถูกลบออกจากหน้าไม่ทำให้ UpdatedAt ของสินค้าที่เหลือเปลี่ยน
+	setETag(c, productListETag(result))
+	return listResponse(c, productSortKeys, func(p *m.Product) uint { return p.ID }, query.Sort, page, result,
+		dto.NewProductResponses(result.Items), "Show all products.")
+}
+
+// productFilter แปลง filter ใน query string ของ GET /product เป็น store.ProductFilter
+func productFilter(query dto.ProductListQuery) store.ProductFilter {
+	return store.ProductFilter{
+		MinPrice:    query.MinPrice,
+		MaxPrice:    query.MaxPrice,
+		InStock:     query.InStock,
+		CreatedFrom: dto.ParseTime(query.CreatedFrom, false),
+		CreatedTo:   dto.ParseTime(query.CreatedTo, true),
+	}
+}
+
+func (h *ProductController) GetProduct(c *fiber.Ctx) error {
+	product, err := h.store.Products().Get(parseID(c.Params("productId")))
+	if err != nil {
+		return apperr.NotFound("Product not found.")
 	}
 
+	setETag(c, versionETag(product.Version))
+	setLastModified(c, product.UpdatedAt)
 	return c.Status(200).JSON(fiber.Map{
-		"data":    &images,
-		"message": "Show images of " + product.Product_Name,
+		"data":    dto.NewProductResponse(product),
+		"message": "Show " + product.Product_Name + " success.",
 	})
 }
 
-func AddProduct(c *fiber.Ctx) error {
-	db := database.DBConn
-	var product m.Product
+func (h *ProductController) GetProductImage(c *fiber.Ctx) error {
+	productID := parseID(c.Params("product_id"))
+	imageID := parseID(c.Params("image_id"))
 
-	// อ่านค่า field ต่าง ๆ ใน Form
-	product.Product_Name = c.FormValue("Product_Name")
-
-	// อ่านค่า Price
-	price, err := strconv.Atoi(c.FormValue("Price"))
+	product, err := h.store.Products().Get(productID)
 	if err != nil {
-		return c.Status(400).SendString("Price Invalid")
+		return apperr.NotFound("Product not found.")
 	}
-	product.Price = price
 
-	// อ่านค่า Amount
-	amount, err := strconv.Atoi(c.FormValue("Amount"))
+	images, err := h.store.Products().GetImage(productID, imageID)
 	if err != nil {
-		return c.Status(400).SendString("Amount Invalid")
+		return apperr.NotFound("Image not found.")
 	}
-	product.Amount = amount
 
-	// สร้าง product ในฐานข้อมูลก่อน เพื่อให้ได้ Product ID
-	if err := db.Create(&product).Error; err != nil {
-		return c.Status(500).SendString("Failed to create product.")
+	return c.Status(200).JSON(fiber.Map{
+		"data":    dto.NewProductImageResponse(images),
+		"message": "Show images of " + product.Product_Name,
+	})
+}
+
+func (h *ProductController) AddProduct(c *fiber.Ctx) error {
+	// อ่านและตรวจสอบค่า field ต่าง ๆ ใน Form ก่อนบันทึกอะไรลงฐานข้อมูล
+	var input dto.CreateProductRequest
+	if err := bind(c, &input); err != nil {
+		return err
+	}
+	if _, err := c.MultipartForm(); err != nil {
+		return apperr.BadRequest("Failed to parse form data.")
 	}
 
-	// จัดการการ upload รูปภาพ
-	form, err := c.MultipartForm()
+	// Upload รูปภาพก่อนเริ่ม transaction เหมือน UpdateProduct ถ้าบันทึกลงฐานข้อมูลไม่สำเร็จจะลบไฟล์ทิ้ง
+	imageURLs, err := h.saveUploads(c)
 	if err != nil {
-		return c.Status(400).SendString("Failed to parse form data.")
+		return err
 	}
 
-	files := form.File["Images"]
-
-	// บันทึกเส้นทางไฟล์ในฐานข้อมูลในตาราง ProductImage
-	for _, file := range files {
-		filename := uuid.New().String() + filepath.Ext(file.Filename)
-		if err := c.SaveFile(file, filepath.Join("uploads", filename)); err != nil {
-			return c.Status(500).SendString("Upload Image Invalid")
-		}
+	product := m.Product{
+		Product_Name: input.Product_Name,
+		Price:        *input.Price,
+		Amount:       *input.Amount,
+		Weight:       input.Weight,
+	}
 
-		// สร้าง ProductImage และเชื่อมโยงกับ Product ID
-		productImage := m.ProductImage{
-			ProductID: product.ID,
-			ImageURL:  "/uploads/" + filename,
+	// product, ledger ของจำนวนตั้งต้น และรูปภาพถูกบันทึกพร้อมกันหรือไม่ถูกบันทึกเลย
+	err = h.store.Transaction(func(tx store.Store) error {
+		products := tx.Products()
+		if err := products.Create(&product, tokenActor(c)); err != nil {
+			return apperr.Internal("Failed to create product.", err)
 		}
 
-		// บันทึก ProductImage ลงในฐานข้อมูล
-		if err := db.Create(&productImage).Error; err != nil {
-			return c.Status(500).SendString("Failed to save product image.")
+		// บันทึกเส้นทางไฟล์ในฐานข้อมูลในตาราง ProductImage และเชื่อมโยงกับ Product ID
+		for _, imageURL := range imageURLs {
+			if err := products.AddImage(&m.ProductImage{ProductID: product.ID, ImageURL: imageURL}); err != nil {
+				return apperr.Internal("Failed to save product image.", err)
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		h.removeUploads(imageURLs)
+		return err
 	}
 
 	// โหลด product พร้อมกับ images
-	if err := db.Preload("Images").First(&product, product.ID).Error; err != nil {
-		return c.Status(500).SendString("Failed to load product with images.")
+	created, err := h.store.Products().Get(product.ID)
+	if err != nil {
+		return apperr.Internal("Failed to load product with images.", err)
 	}
 
+	setETag(c, versionETag(created.Version))
 	return c.Status(201).JSON(fiber.Map{
-		"data":    &product,
+		"data":    dto.NewProductResponse(created),
 		"message": "Successfully created product.",
 	})
 }
 
-func UpdateProduct(c *fiber.Ctx) error {
-	db := database.DBConn
-	productId := c.Params("productId")
-	var product m.Product
-
-	// ค้นหา product เดิมในฐานข้อมูล
-	if err := db.Preload("Images").Where("id = ?", productId).First(&product).Error; err != nil {
-		return c.Status(404).SendString("Product not Found")
+func (h *ProductController) UpdateProduct(c *fiber.Ctx) error {
+	var input dto.UpdateProductRequest
+	if err := bind(c, &input); err != nil {
+		return err
 	}
 
-	// Update field ต่าง ๆ
-	// Update Product_Name
-	nameCheck := c.FormValue("Product_Name")
-	if nameCheck != "" {
-		product.Product_Name = c.FormValue("Product_Name")
+	// Upload รูปภาพใหม่ก่อนเริ่ม transaction เพื่อไม่ให้ lock ของ product ค้างระหว่างเขียนไฟล์
+	// ถ้าบันทึกลงฐานข้อมูลไม่สำเร็จจะลบไฟล์ที่ upload ไปแล้วทิ้ง
+	imageURLs, err := h.saveUploads(c)
+	if err != nil {
+		return err
 	}
 
-	// Update Price
-	priceCheck := c.FormValue("Price")
-	if priceCheck != "" {
-		price, err := strconv.Atoi(c.FormValue("Price"))
+	var product *m.Product
+	err = h.store.Transaction(func(tx store.Store) error {
+		products := tx.Products()
+
+		// ค้นหา product เดิมในฐานข้อมูลและ lock ไว้จนบันทึกเสร็จ
+		var err error
+		product, err = products.GetForUpdate(parseID(c.Params("productId")))
 		if err != nil {
-			return c.Status(400).SendString("Price Invalid")
+			return apperr.NotFound("Product not Found")
 		}
-		product.Price = price
-	}
 
-	// Update Amount
-	amountCheck := c.FormValue("Amount")
-	if amountCheck != "" {
-		amount, err := strconv.Atoi(c.FormValue("Amount"))
-		if err != nil {
-			return c.Status(400).SendString("Amount Invalid")
+		// ถ้า client ส่ง If-Match มา product ต้องยังไม่ถูกแก้ตั้งแต่ที่ client อ่านไป
+		if err := checkIfMatch(c, versionETag(product.Version)); err != nil {
+			return err
 		}
-		product.Amount = amount
-	}
 
-	// Upload Images
-	form, err := c.MultipartForm()
-	// ตรวจสอบว่ามีไฟล์ใหม่ถูก upload มาไหม
-	if err == nil && form != nil {
-		// ตรวจสอบว่ามีไฟล์ใหม่ถูก upload มาไหม
-		if len(form.File["Images"]) > 0 {
-			// บันทึกรูปภาพใหม่
-			files := form.File["Images"]
-			for _, file := range files {
-				filename := uuid.New().String() + filepath.Ext(file.Filename)
-				if err := c.SaveFile(file, filepath.Join("uploads", filename)); err != nil {
-					return c.Status(500).SendString("Failed to upload new image.")
-				}
-
-				// บันทึกเส้นทางไฟล์ใหม่ในฐานข้อมูล
-				productImage := m.ProductImage{
-					ProductID: product.ID,
-					ImageURL:  "/uploads/" + filename,
-				}
-
-				// บันทึก ProductImage ลงในฐานข้อมูล
-				if err := db.Create(&productImage).Error; err != nil {
-					return c.Status(500).SendString("Failed to save product image.")
-				}
-
-				product.Images = append(product.Images, productImage)
+		// Update field ต่าง ๆ เฉพาะที่ส่งมา
+		if input.Product_Name != nil {
+			product.Product_Name = *input.Product_Name
+		}
+		if input.Price != nil {
+			product.Price = *input.Price
+		}
+		if input.Weight != nil {
+			product.Weight = *input.Weight
+		}
+		if err := products.Save(product); err != nil {
+			return saveError(err, "Failed to update product.")
+		}
+
+		// บันทึกเส้นทางไฟล์ใหม่ในฐานข้อมูล
+		for _, imageURL := range imageURLs {
+			if err := products.AddImage(&m.ProductImage{ProductID: product.ID, ImageURL: imageURL}); err != nil {
+				return apperr.Internal("Failed to save product image.", err)
 			}
 		}
-	}
 
-	// บันทึกการเปลี่ยนแปลงในฐานข้อมูล
-	if err := db.Save(&product).Error; err != nil {
-		return c.Status(500).SendString("Failed to update product.")
+		// จำนวนสินค้าที่ส่งมาถูกบันทึกเป็น adjustment ใน ledger ตามส่วนต่างจากจำนวนปัจจุบัน
+		if input.Amount != nil {
+			move := m.StockMovement{ProductID: product.ID, Reason: m.StockAdjustment, ActorID: tokenActor(c)}
+			err := products.SetStock(&move, *input.Amount)
+			if errors.Is(err, store.ErrInsufficientStock) {
+				return apperr.Conflict("Amount of " + product.Product_Name + " cannot be lower than the quantity reserved by open orders.").WithCode(apperr.CodeInsufficientStock)
+			}
+			if err != nil {
+				return apperr.Internal("Failed to update product quantity.", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		h.removeUploads(imageURLs)
+		return err
 	}
 
 	// โหลด product พร้อมกับ images ที่อัปเดตแล้ว
-	if err := db.Preload("Images").First(&product, product.ID).Error; err != nil {
-		return c.Status(500).SendString("Failed to load updated product with images.")
+	updated, err := h.store.Products().Get(product.ID)
+	if err != nil {
+		return apperr.Internal("Failed to load updated product with images.", err)
 	}
 
-	return c.Status(201).JSON(fiber.Map{
-		"data":    &product,
-		"message": product.Product_Name + " has been successfully updated.",
+	setETag(c, versionETag(updated.Version))
+	return c.Status(200).JSON(fiber.Map{
+		"data":    dto.NewProductResponse(updated),
+		"message": updated.Product_Name + " has been successfully updated.",
 	})
 }
 
-func SoftDeleteProduct(c *fiber.Ctx) error {
-	db := database.DBConn
-	productId := c.Params("productId")
-	var product m.Product
+// saveUploads บันทึกไฟล์ใน field Images ของ multipart form ลง uploadDir แล้วคืน URL ของแต่ละไฟล์
+// request ที่ไม่มี form หรือไม่มีไฟล์จะได้ slice ว่าง ถ้าบันทึกไฟล์ใดไม่ได้จะลบไฟล์ที่บันทึกไปแล้วก่อนคืน error
+func (h *ProductController) saveUploads(c *fiber.Ctx) ([]string, error) {
+	form, err := c.MultipartForm()
+	if err != nil || form == nil {
+		return nil, nil
+	}
 
-	if err := db.Preload("Images").Where("id = ?", productId).First(&product).Error; err != nil {
-		return c.Status(404).SendString("Product not found.")
+	var imageURLs []string
+	for _, file := range form.File["Images"] {
+		filename := uuid.New().String() + filepath.Ext(file.Filename)
+		if err := c.SaveFile(file, filepath.Join(h.uploadDir, filename)); err != nil {
+			h.removeUploads(imageURLs)
+			return nil, apperr.Internal("Failed to upload new image.", err)
+		}
+		imageURLs = append(imageURLs, "/uploads/"+filename)
 	}
+	return imageURLs, nil
+}
 
-	// soft delete images ในฐานข้อมูล
-	if err := db.Where("product_id = ?", product.ID).Delete(&m.ProductImage{}).Error; err != nil {
-		return c.Status(500).SendString("Failed to remove image data.")
+// removeUploads ลบไฟล์ที่ upload ไว้แต่ไม่ได้ถูกบันทึกลงฐานข้อมูล ไฟล์ที่ลบไม่ได้จะถูก log ไว้
+func (h *ProductController) removeUploads(imageURLs []string) {
+	for _, imageURL := range imageURLs {
+		if err := os.Remove(h.imagePath(imageURL)); err != nil {
+			log.Printf("Failed to remove unused upload %s: %v", imageURL, err)
+		}
+	}
+}
+
+func (h *ProductController) SoftDeleteProduct(c *fiber.Ctx) error {
+	products := h.store.Products()
+
+	product, err := products.Get(parseID(c.Params("productId")))
+	if err != nil {
+		return apperr.NotFound("Product not found.")
+	}
+	if err := checkIfMatch(c, versionETag(product.Version)); err != nil {
+		return err
 	}
 
 	productName := product.Product_Name
 
-	// soft delete product
-	if err := db.Where("id = ?", productId).Delete(&product).Error; err != nil {
-		return c.Status(500).SendString("Failed to delete product.")
+	// soft delete product พร้อม images
+	if err := products.SoftDelete(product); err != nil {
+		return saveError(err, "Failed to delete product.")
 	}
 
 	return c.Status(201).JSON(fiber.Map{
@@ -228,59 +315,55 @@ func SoftDeleteProduct(c *fiber.Ctx) error {
 	})
 }
 
-func RestoreProduct(c *fiber.Ctx) error {
-	db := database.DBConn
-	productId := c.Params("productId")
-	var product m.Product
-
-	if err := db.Unscoped().Preload("Images").Where("id = ?", productId).First(&product).Error; err != nil {
-		return c.Status(404).SendString("Can't find the product you want to restore.")
+func (h *ProductController) RestoreProduct(c *fiber.Ctx) error {
+	productID := parseID(c.Params("productId"))
+	deleted, err := h.store.Products().GetDeleted(productID)
+	if errors.Is(err, store.ErrNotFound) {
+		return apperr.NotFound("Can't find the product you want to restore.")
 	}
-
-	// restore images ในฐานข้อมูล
-	for _, image := range product.Images {
-		if err := db.Unscoped().Where("product_id = ?", product.ID).First(&m.ProductImage{}).Update("deleted_at", nil).Error; err != nil {
-			return c.Status(500).SendString("Failed to restore images : " + strconv.Itoa(int(image.ID)))
-		}
+	if err != nil {
+		return apperr.Internal("Failed to load product.", err)
+	}
+	if err := checkIfMatch(c, versionETag(deleted.Version)); err != nil {
+		return err
 	}
 
-	// restore product
-	if err := db.Unscoped().Where("id = ?", productId).First(&product).Update("deleted_at", nil).Error; err != nil {
-		return c.Status(500).SendString("Failed to restore product.")
+	// Restore ตรวจ version ที่อ่านมาอีกครั้งใน UPDATE ถ้ามี request อื่นกู้คืนหรือแก้ไปก่อนจะได้ 412
+	product, err := h.store.Products().Restore(productID, deleted.Version)
+	if err != nil {
+		return saveError(err, "Failed to restore product.")
 	}
 
+	setETag(c, versionETag(product.Version))
 	return c.Status(201).JSON(fiber.Map{
-		"data":    &product,
+		"data":    dto.NewProductResponse(product),
 		"message": "Restore " + product.Product_Name + " successfully.",
 	})
 }
 
-func HardDeleteProduct(c *fiber.Ctx) error {
-	db := database.DBConn
-	productId := c.Params("productId")
-	var product m.Product
+func (h *ProductController) HardDeleteProduct(c *fiber.Ctx) error {
+	products := h.store.Products()
 
-	if err := db.Unscoped().Preload("Images").Where("id = ? AND deleted_at IS NOT NULL", productId).First(&product).Error; err != nil {
-		return c.Status(404).SendString("Product not found.")
+	product, err := products.GetDeleted(parseID(c.Params("productId")))
+	if err != nil {
+		return apperr.NotFound("Product not found.")
+	}
+	if err := checkIfMatch(c, versionETag(product.Version)); err != nil {
+		return err
 	}
 
 	// ลบรูปภาพออกจากระบบ (ลบใน folder uploads)
 	for _, img := range product.Images {
-		imagePath := "." + img.ImageURL
-		if err := os.Remove(imagePath); err != nil {
-			return c.Status(500).SendString("Failed to remove image.")
+		if err := os.Remove(h.imagePath(img.ImageURL)); err != nil {
+			return apperr.Internal("Failed to remove image.", err)
 		}
 	}
 
-	// hard delete images ในฐานข้อมูล
-	if err := db.Unscoped().Where("product_id = ? AND deleted_at IS NOT NULL", product.ID).Delete(&m.ProductImage{}).Error; err != nil {
-		return c.Status(500).SendString("Failed to remove image data.")
-	}
-
 	productName := product.Product_Name
 
-	if err := db.Unscoped().Delete(&product).Error; err != nil {
-		return c.Status(500).SendString("Failed to remove product.")
+	// hard delete product และ images ในฐานข้อมูล
+	if err := products.HardDelete(product); err != nil {
+		return apperr.Internal("Failed to remove product.", err)
 	}
 
 	return c.Status(201).JSON(fiber.Map{
@@ -288,38 +371,37 @@ func HardDeleteProduct(c *fiber.Ctx) error {
 	})
 }
 
-func RemoveImage(c *fiber.Ctx) error {
-	db := database.DBConn
-	productID := c.Params("product_id")
-	imageID := c.Params("image_id")
+func (h *ProductController) RemoveImage(c *fiber.Ctx) error {
+	products := h.store.Products()
+	productID := parseID(c.Params("product_id"))
+	imageID := parseID(c.Params("image_id"))
 
-	var product m.Product
-	if err := db.Preload("Images").Where("id = ?", productID).First(&product).Error; err != nil {
-		return c.Status(404).SendString("Product not found.")
+	if _, err := products.Get(productID); err != nil {
+		return apperr.NotFound("Product not found.")
 	}
 
-	var image m.ProductImage
-	if err := db.Where("id = ? AND product_id = ?", imageID, productID).First(&image).Error; err != nil {
-		return c.Status(404).SendString("Image not found.")
+	image, err := products.GetImage(productID, imageID)
+	if err != nil {
+		return apperr.NotFound("Image not found.")
 	}
 
 	// ลบรูปภาพออกจากระบบ (ลบใน folder uploads)
-	imagePath := "." + image.ImageURL
-	if err := os.Remove(imagePath); err != nil {
-		return c.Status(500).SendString("Failed to remove image file.")
+	if err := os.Remove(h.imagePath(image.ImageURL)); err != nil {
+		return apperr.Internal("Failed to remove image file.", err)
 	}
 
 	// hard delete images ในฐานข้อมูล
-	if err := db.Unscoped().Delete(&image).Error; err != nil {
-		return c.Status(500).SendString("Failed to delete image record.")
+	if err := products.DeleteImage(image); err != nil {
+		return apperr.Internal("Failed to delete image record.", err)
 	}
 
-	if err := db.Preload("Images").First(&product, product.ID).Error; err != nil {
-		return c.Status(500).SendString("Failed to load updated product with images.")
+	product, err := products.Get(productID)
+	if err != nil {
+		return apperr.Internal("Failed to load updated product with images.", err)
 	}
 
 	return c.Status(200).JSON(fiber.Map{
-		"data":    &product,
+		"data":    dto.NewProductResponse(product),
 		"message": "Image has been successfully removed.",
 	})
 }
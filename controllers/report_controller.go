@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"go-fiber-test/apperr"
+	"go-fiber-test/dto"
+	"go-fiber-test/store"
+	"math"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ReportController คือรายงานยอดขายสำหรับ admin ยอดขายนับเฉพาะ order ที่ชำระเงินแล้วและไม่ถูกยกเลิกหรือคืนเงิน
+type ReportController struct {
+	store store.Store
+}
+
+func NewReportController(s store.Store) *ReportController {
+	return &ReportController{store: s}
+}
+
+// GetSales คือจำนวน order ยอดขาย และยอดเฉลี่ยต่อ order แยกตามวัน สัปดาห์ หรือเดือน
+// ยอดขายคือราคาสินค้าหลังหักส่วนลด ไม่รวมภาษีและค่าส่ง
+func (h *ReportController) GetSales(c *fiber.Ctx) error {
+	filter, err := bindReport(c)
+	if err != nil {
+		return err
+	}
+
+	points, err := h.store.Reports().Sales(filter)
+	if err != nil {
+		return apperr.Internal("Failed to load sales report.", err)
+	}
+	summary, err := h.store.Reports().Summary(filter)
+	if err != nil {
+		return apperr.Internal("Failed to load sales report.", err)
+	}
+
+	report := dto.SalesReportResponse{
+		Interval: filter.Interval,
+		Summary:  salesSummary(summary),
+		Points:   make([]dto.SalesPointResponse, len(points)),
+	}
+	for i, p := range points {
+		report.Points[i] = dto.SalesPointResponse{
+			Period:            p.Period,
+			Orders:            p.Orders,
+			Revenue:           p.Revenue,
+			AverageOrderValue: averageOrderValue(p.Revenue, p.Orders),
+		}
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"data":    report,
+		"message": "Show sales report.",
+	})
+}
+
+// GetSummary คือยอดรวม ยอดเฉลี่ยต่อ order และจำนวนลูกค้าใหม่ของทั้งช่วงเวลา
+func (h *ReportController) GetSummary(c *fiber.Ctx) error {
+	filter, err := bindReport(c)
+	if err != nil {
+		return err
+	}
+
+	summary, err := h.store.Reports().Summary(filter)
+	if err != nil {
+		return apperr.Internal("Failed to load sales summary.", err)
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"data":    salesSummary(summary),
+		"message": "Show sales summary.",
+	})
+}
+
+// GetTopProducts คือสินค้าที่ขายได้มากที่สุดตามจำนวนชิ้นหรือยอดขาย
+func (h *ReportController) GetTopProducts(c *fiber.Ctx) error {
+	var query dto.TopProductsQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
+	}
+	filter, err := bindReport(c)
+	if err != nil {
+		return err
+	}
+
+	if query.By == "" {
+		query.By = "units"
+	}
+	if query.Limit == 0 {
+		query.Limit = 10
+	}
+
+	products, err := h.store.Reports().TopProducts(filter, query.By, query.Limit)
+	if err != nil {
+		return apperr.Internal("Failed to load top products.", err)
+	}
+
+	out := make([]dto.ProductSalesResponse, len(products))
+	for i, p := range products {
+		out[i] = dto.ProductSalesResponse{ProductID: p.ProductID, Product: p.Product, Units: p.Units, Revenue: p.Revenue}
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"data":    out,
+		"message": "Show top products by " + query.By + ".",
+	})
+}
+
+// GetCustomers คือจำนวนลูกค้าใหม่ (ลูกค้าที่มี order แรกที่นับเป็นยอดขาย) แยกตามช่วง
+func (h *ReportController) GetCustomers(c *fiber.Ctx) error {
+	filter, err := bindReport(c)
+	if err != nil {
+		return err
+	}
+
+	points, err := h.store.Reports().NewCustomers(filter)
+	if err != nil {
+		return apperr.Internal("Failed to load customers report.", err)
+	}
+
+	report := dto.CustomersReportResponse{
+		Interval: filter.Interval,
+		Points:   make([]dto.CustomerPointResponse, len(points)),
+	}
+	for i, p := range points {
+		report.Points[i] = dto.CustomerPointResponse{Period: p.Period, Customers: p.Customers}
+		report.Total += p.Customers
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"data":    report,
+		"message": "Show new customers report.",
+	})
+}
+
+// bindReport อ่านช่วงเวลาและความละเอียดของรายงานจาก query string
+func bindReport(c *fiber.Ctx) (store.ReportFilter, error) {
+	var query dto.ReportQuery
+	if err := bindQuery(c, &query); err != nil {
+		return store.ReportFilter{}, err
+	}
+
+	filter := store.ReportFilter{
+		Interval: query.Interval,
+		From:     dto.ParseTime(query.From, false),
+		To:       dto.ParseTime(query.To, true),
+	}
+	if filter.Interval == "" {
+		filter.Interval = store.IntervalDay
+	}
+	return filter, nil
+}
+
+func salesSummary(summary store.SalesSummary) dto.SalesSummaryResponse {
+	return dto.SalesSummaryResponse{
+		Orders:            summary.Orders,
+		Revenue:           summary.Revenue,
+		AverageOrderValue: averageOrderValue(summary.Revenue, summary.Orders),
+		NewCustomers:      summary.NewCustomers,
+	}
+}
+
+// averageOrderValue คือยอดขายเฉลี่ยต่อ order ปัดเป็นทศนิยมสองตำแหน่ง
+func averageOrderValue(revenue, orders int64) float64 {
+	if orders == 0 {
+		return 0
+	}
+	return math.Round(float64(revenue)/float64(orders)*100) / 100
+}
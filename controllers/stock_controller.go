@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"go-fiber-test/apperr"
+	"go-fiber-test/dto"
+	m "go-fiber-test/models"
+	"go-fiber-test/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// StockController ให้ admin ดูและแก้สต็อกของสินค้าผ่าน ledger (ตาราง stock_movements)
+type StockController struct {
+	store store.Store
+}
+
+func NewStockController(s store.Store) *StockController {
+	return &StockController{store: s}
+}
+
+// stockSortKeys คือ column ที่ใช้เรียง GET /product/:productId/stock ได้ (ต้องตรงกับ rule sort ใน dto.StockHistoryQuery)
+var stockSortKeys = sortKeys[m.StockMovement]{
+	"id":         func(s *m.StockMovement) any { return s.ID },
+	"created_at": func(s *m.StockMovement) any { return s.CreatedAt },
+}
+
+// GetStockHistory คืนประวัติการเปลี่ยนแปลงสต็อกของสินค้า รวมถึงสินค้าที่ถูก soft delete ไปแล้ว
+func (h *StockController) GetStockHistory(c *fiber.Ctx) error {
+	var pageQuery dto.PageQuery
+	var query dto.StockHistoryQuery
+	if err := bindQuery(c, &pageQuery, &query); err != nil {
+		return err
+	}
+
+	page, err := newPage(pageQuery, query.Sort)
+	if err != nil {
+		return err
+	}
+
+	product, err := h.findProduct(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := h.store.Products().Movements(product.ID, page)
+	if err != nil {
+		return apperr.Internal("Failed to load stock history.", err)
+	}
+
+	return listResponse(c, stockSortKeys, func(s *m.StockMovement) uint { return s.ID }, query.Sort, page, result,
+		dto.NewStockMovementResponses(result.Items), "Show stock history of "+product.Product_Name+".")
+}
+
+// AdjustStock เพิ่มหรือลดสต็อกของสินค้าด้วยมือ เช่นรับสินค้าเข้าหรือแก้ยอดหลังนับสต็อก
+func (h *StockController) AdjustStock(c *fiber.Ctx) error {
+	var req dto.StockAdjustRequest
+	if err := bind(c, &req); err != nil {
+		return err
+	}
+	if req.Reason == m.StockRestock && req.Delta < 0 {
+		return dto.Invalid(dto.FieldError{Field: "Delta", Rule: "gt", Message: "Delta must be positive for restock."})
+	}
+
+	product, err := h.store.Products().Get(parseID(c.Params("productId")))
+	if err != nil {
+		return apperr.NotFound("Product not found.")
+	}
+
+	move := m.StockMovement{
+		ProductID: product.ID,
+		Delta:     req.Delta,
+		Reason:    req.Reason,
+		ActorID:   tokenActor(c),
+		Note:      req.Note,
+	}
+	err = h.store.Products().AdjustStock(&move)
+	if errors.Is(err, store.ErrInsufficientStock) {
+		return apperr.Conflict("Product " + product.Product_Name + " doesn't have enough quantity to remove.").WithCode(apperr.CodeInsufficientStock)
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return apperr.NotFound("Product not found.")
+	}
+	if err != nil {
+		return apperr.Internal("Failed to update product quantity.", err)
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"data":    dto.NewStockMovementResponse(&move),
+		"message": fmt.Sprintf("%s now has %d in stock.", product.Product_Name, move.Balance),
+	})
+}
+
+// GetStockReconcile เทียบจำนวนสินค้ากับผลรวมใน ledger โดยไม่แก้ไขอะไร
+func (h *StockController) GetStockReconcile(c *fiber.Ctx) error {
+	product, err := h.findProduct(c)
+	if err != nil {
+		return err
+	}
+
+	ledger, err := h.store.Products().LedgerBalance(product.ID)
+	if err != nil {
+		return apperr.Internal("Failed to load stock ledger.", err)
+	}
+
+	message := "Stock of " + product.Product_Name + " matches the ledger."
+	if ledger != product.Amount {
+		message = "Stock of " + product.Product_Name + " doesn't match the ledger."
+	}
+	return c.Status(200).JSON(fiber.Map{
+		"data":    dto.NewStockReconcileResponse(product, ledger),
+		"message": message,
+	})
+}
+
+// ReconcileStock ตั้งจำนวนสินค้าให้เท่ากับผลรวมใน ledger ซึ่งถือเป็นยอดที่ถูกต้อง
+func (h *StockController) ReconcileStock(c *fiber.Ctx) error {
+	product, err := h.findProduct(c)
+	if err != nil {
+		return err
+	}
+
+	before := product.Amount
+	product.Amount, err = h.store.Products().Reconcile(product.ID)
+	if err != nil {
+		return apperr.Internal("Failed to reconcile product quantity.", err)
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"data":    dto.NewStockReconcileResponse(product, product.Amount),
+		"message": fmt.Sprintf("Stock of %s has been reconciled from %d to %d.", product.Product_Name, before, product.Amount),
+	})
+}
+
+// findProduct หาสินค้าจาก productId รวมถึงสินค้าที่ถูก soft delete ไปแล้วซึ่งยังถูกคืนสต็อกได้
+func (h *StockController) findProduct(c *fiber.Ctx) (*m.Product, error) {
+	id := parseID(c.Params("productId"))
+	product, err := h.store.Products().Get(id)
+	if err == nil {
+		return product, nil
+	}
+	if product, err = h.store.Products().GetDeleted(id); err == nil {
+		return product, nil
+	}
+	return nil, apperr.NotFound("Product not found.")
+}
@@ -1,117 +1,174 @@
 package controllers
 
 import (
-	"go-fiber-test/database"
+	"errors"
+	"go-fiber-test/apperr"
+	"go-fiber-test/dto"
 	m "go-fiber-test/models"
+	"go-fiber-test/store"
+	"slices"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func GetUsers(c *fiber.Ctx) error {
-	db := database.DBConn
-	var users []m.User
+type UserController struct {
+	store store.Store
+}
 
-	db.Find(&users)
-	return c.Status(200).JSON(fiber.Map{
-		"data": &users,
-	})
+func NewUserController(s store.Store) *UserController {
+	return &UserController{store: s}
+}
+
+// userSortKeys คือ column ที่ใช้เรียง GET /user ได้ (ต้องตรงกับ rule sort ใน dto.UserListQuery)
+var userSortKeys = sortKeys[m.User]{
+	"id":         func(u *m.User) any { return u.ID },
+	"username":   func(u *m.User) any { return u.Username },
+	"created_at": func(u *m.User) any { return u.CreatedAt },
+	"updated_at": func(u *m.User) any { return u.UpdatedAt },
+}
+
+func (h *UserController) GetUsers(c *fiber.Ctx) error {
+	var pageQuery dto.PageQuery
+	var query dto.UserListQuery
+	if err := bindQuery(c, &pageQuery, &query); err != nil {
+		return err
+	}
+
+	page, err := newPage(pageQuery, query.Sort)
+	if err != nil {
+		return err
+	}
+
+	result, err := h.store.Users().List(userFilter(query), page)
+	if err != nil {
+		return apperr.Internal("Failed to load users.", err)
+	}
+
+	return listResponse(c, userSortKeys, func(u *m.User) uint { return u.ID }, query.Sort, page, result,
+		dto.NewUserResponses(result.Items), "Show all users.")
+}
+
+// userFilter แปลง filter ใน query string ของ GET /user เป็น store.UserFilter
+func userFilter(query dto.UserListQuery) store.UserFilter {
+	return store.UserFilter{
+		Role:        query.Role,
+		Approved:    query.Approved,
+		CreatedFrom: dto.ParseTime(query.CreatedFrom, false),
+		CreatedTo:   dto.ParseTime(query.CreatedTo, true),
+	}
 }
 
-func UpdateUser(c *fiber.Ctx) error {
-	db := database.DBConn
-	userId := c.Params("userId")
-	var user m.User
+func (h *UserController) UpdateUser(c *fiber.Ctx) error {
+	users := h.store.Users()
 
-	if err := db.Where("id = ?", userId).First(&user).Error; err != nil {
-		return c.Status(fiber.StatusBadRequest).SendString("User not found.")
+	user, err := users.Get(parseID(c.Params("userId")))
+	if err != nil {
+		return apperr.NotFound("User not found.")
+	}
+
+	// user แก้ไขได้เฉพาะข้อมูลของตัวเอง ส่วน Role และ Approve เปลี่ยนได้ผ่าน endpoint ของ admin เท่านั้น
+	if !canManageUser(c, user.ID) {
+		return apperr.Unauthorized("Unauthorized to view this page.")
+	}
+	if err := checkIfMatch(c, versionETag(user.Version)); err != nil {
+		return err
+	}
+
+	var input dto.UpdateUserRequest
+	if err := bind(c, &input); err != nil {
+		return err
 	}
 
 	// update username
-	usernameCheck := c.FormValue("Username")
-	if usernameCheck != "" {
-		user.Username = c.FormValue("Username")
+	if input.Username != "" && input.Username != user.Username {
+		if _, err := users.GetByUsername(input.Username); err == nil {
+			return apperr.Conflict("User Already Exists.")
+		}
+		user.Username = input.Username
 	}
 
 	// update password
-	passwordCheck := c.FormValue("Password")
-	if passwordCheck != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(c.FormValue("Password")), bcrypt.DefaultCost)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString("Error hashing password.")
+	if input.Password != "" {
+		// เทียบ password ใหม่กับ hash เดิมก่อน hash เพื่อไม่ให้ใช้ password เดิมซ้ำ
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err == nil {
+			return apperr.BadRequest("Please use a different password.")
 		}
-		newPassword := string(hashedPassword)
 
-		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(newPassword)); err == nil {
-			return c.Status(fiber.StatusBadRequest).SendString("Please use a different password.")
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return apperr.Internal("Error hashing password.", err)
 		}
-
-		user.Password = newPassword
+		user.Password = string(hashedPassword)
 	}
 
 	// update Firstname
-	firstNameCheck := c.FormValue("FirstName")
-	if firstNameCheck != "" {
-		user.FirstName = c.FormValue("FirstName")
+	if input.FirstName != "" {
+		user.FirstName = input.FirstName
 	}
 
 	// update Lastname
-	lastNameCheck := c.FormValue("LastName")
-	if lastNameCheck != "" {
-		user.LastName = c.FormValue("LastName")
+	if input.LastName != "" {
+		user.LastName = input.LastName
 	}
 
-	if err := db.Save(&user).Error; err != nil {
-		return c.Status(500).SendString("Failed to update user.")
+	if err := users.Save(user); err != nil {
+		return saveError(err, "Failed to update user.")
 	}
 
+	setETag(c, versionETag(user.Version))
 	return c.Status(201).JSON(fiber.Map{
-		"data":    &user,
+		"data":    dto.NewUserResponse(user),
 		"message": "Updated user successfully.",
 	})
 }
 
-func SoftDeleteUser(c *fiber.Ctx) error {
-	db := database.DBConn
-	userId := c.Params("userId")
-	var user m.User
-
+func (h *UserController) SoftDeleteUser(c *fiber.Ctx) error {
 	// ตรวจสอบการมีอยู่ของ user
-	if err := db.Where("id = ?", userId).First(&user).Error; err != nil {
-		return c.Status(404).SendString("User not found.")
+	user, err := h.store.Users().Get(parseID(c.Params("userId")))
+	if err != nil {
+		return apperr.NotFound("User not found.")
 	}
 	username := user.Username
 
-	// ลบ order ของ user คนนั้นและคืนจำนวนสินค้ากลับไปยังคลัง
-	var order m.Order
-	if err := db.Preload("Items").Where("Buyer = ?", userId).First(&order).Error; err != nil {
-		return c.Status(404).SendString("Order not found.")
+	if !canManageUser(c, user.ID) {
+		return apperr.Unauthorized("Unauthorized to view this page.")
+	}
+	if err := checkIfMatch(c, versionETag(user.Version)); err != nil {
+		return err
 	}
 
-	for _, item := range order.Items {
-		var product m.Product
-		if err := db.Where("Product_Name = ?", item.Product).First(&product).Error; err != nil {
-			return c.Status(500).SendString("Product " + item.Product + " not found.")
+	// ยกเลิก order ที่ยังไม่ได้ชำระเงินแต่ยังกันหรือตัดสต็อกไว้ (pending และ failed) ของ user คนนั้น คืนจำนวนสินค้ากลับไปยังคลัง
+	// และ soft delete user ใน transaction เดียว order ที่ชำระเงินแล้วถูกเก็บไว้ตามเดิมเพื่อให้ admin ดำเนินการต่อ
+	err = h.store.Transaction(func(tx store.Store) error {
+		userOrders, err := tx.Orders().ListByBuyer(strconv.FormatUint(uint64(user.ID), 10))
+		if err != nil {
+			return apperr.Internal("Failed to load orders.", err)
 		}
 
-		product.Amount += item.Amount
-
-		if err := db.Save(&product).Error; err != nil {
-			return c.Status(500).SendString("Failed to update product amount in inventory.")
+		for _, listed := range userOrders {
+			// โหลดใหม่พร้อม lock เพราะ webhook หรือ sweeper อาจเปลี่ยนสถานะ order ไปแล้วหลังจากที่ list มา
+			order, err := tx.Orders().GetForUpdate(listed.ID)
+			if err != nil {
+				return apperr.Internal("Failed to load order.", err)
+			}
+			if !order.HoldsStock() || slices.Contains(m.SalesStatuses, order.Status) {
+				continue
+			}
+			if err := changeOrderStatus(tx, order, m.OrderCancelled, tokenActor(c)); err != nil {
+				return err
+			}
 		}
-	}
 
-	if err := db.Unscoped().Where("order_id = ?", order.ID).Delete(&m.Item{}).Error; err != nil {
-		return c.Status(500).SendString("Failed to delete order items.")
-	}
-
-	if err := db.Unscoped().Where("id = ?", order.ID).Delete(&order).Error; err != nil {
-		return c.Status(500).SendString("Failed to delete order.")
-	}
-
-	// soft delete user
-	if err := db.Delete(&user).Error; err != nil {
-		return c.Status(500).SendString("Failed to user.")
+		// soft delete user
+		if err := tx.Users().SoftDelete(user); err != nil {
+			return saveError(err, "Failed to delete user.")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	return c.Status(200).JSON(fiber.Map{
@@ -119,33 +176,56 @@ func SoftDeleteUser(c *fiber.Ctx) error {
 	})
 }
 
-func RestoreUser(c *fiber.Ctx) error {
-	db := database.DBConn
-	userId := c.Params("userId")
-	var user m.User
+func (h *UserController) RestoreUser(c *fiber.Ctx) error {
+	userID := parseID(c.Params("userId"))
+	deleted, err := h.store.Users().GetDeleted(userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return apperr.NotFound("Can't find the user you want to restore.")
+	}
+	if err != nil {
+		return apperr.Internal("Failed to load user.", err)
+	}
+	if err := checkIfMatch(c, versionETag(deleted.Version)); err != nil {
+		return err
+	}
 
-	if err := db.Unscoped().Where("id = ?", userId).First(&user).Update("deleted_at", nil).Error; err != nil {
-		return c.Status(500).SendString("Failed to restore user.")
+	// Restore ตรวจ version ที่อ่านมาอีกครั้งใน UPDATE ถ้ามี request อื่นกู้คืนหรือแก้ไปก่อนจะได้ 412
+	user, err := h.store.Users().Restore(userID, deleted.Version)
+	if err != nil {
+		return saveError(err, "Failed to restore user.")
 	}
 
+	setETag(c, versionETag(user.Version))
 	return c.Status(200).JSON(fiber.Map{
-		"data":    &user,
+		"data":    dto.NewUserResponse(user),
 		"message": "Restore " + user.Username + " successfully.",
 	})
 }
 
-func HardDeleteUser(c *fiber.Ctx) error {
-	db := database.DBConn
-	userId := c.Params("userId")
-	var user m.User
+func (h *UserController) HardDeleteUser(c *fiber.Ctx) error {
+	users := h.store.Users()
 
-	if err := db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", userId).First(&user).Error; err != nil {
-		return c.Status(404).SendString("User not found.")
+	user, err := users.GetDeleted(parseID(c.Params("userId")))
+	if err != nil {
+		return apperr.NotFound("User not found.")
+	}
+	if err := checkIfMatch(c, versionETag(user.Version)); err != nil {
+		return err
 	}
 	username := user.Username
 
-	if err := db.Unscoped().Delete(&user).Error; err != nil {
-		return c.Status(500).SendString("Failed to remove user.")
+	// ตะกร้าของ user ที่ถูกลบถาวรไม่มีใครใช้ได้อีก จึงลบไปพร้อมกัน
+	err = h.store.Transaction(func(tx store.Store) error {
+		if err := tx.Carts().Clear(user.ID); err != nil {
+			return apperr.Internal("Failed to clear the cart.", err)
+		}
+		if err := tx.Users().HardDelete(user); err != nil {
+			return apperr.Internal("Failed to remove user.", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	return c.Status(200).JSON(fiber.Map{
@@ -1,11 +1,78 @@
 package database
 
 import (
-	_ "gorm.io/driver/mysql"
+	"fmt"
+	"go-fiber-test/config"
+	"strings"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-var (
-	// สร้างตัวแปรชื่อ DBConn เป็นประเภท gorm.DB เพื่อเอาไปรับการ Connect
-	DBConn *gorm.DB
-)
+// Dialector เลือก GORM dialector ตาม driver ที่ตั้งไว้ใน config
+func Dialector(cfg config.DatabaseConfig) (gorm.Dialector, error) {
+	dsn := cfg.ConnectionString()
+
+	switch cfg.Driver {
+	case config.DriverMySQL:
+		return mysql.Open(dsn), nil
+	case config.DriverPostgres:
+		return postgres.Open(dsn), nil
+	case config.DriverSQLite:
+		return sqlite.Open(sqlitePragmas(dsn)), nil
+	default:
+		return nil, fmt.Errorf("database: unsupported driver %q", cfg.Driver)
+	}
+}
+
+// Open เปิดการเชื่อมต่อฐานข้อมูลตาม config
+func Open(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	dialector, err := Dialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("database: opening %s: %w", cfg.Driver, err)
+	}
+
+	if cfg.Driver == config.DriverSQLite {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+
+		// sqlite แบบ in-memory แต่ละ connection จะได้ฐานข้อมูลแยกกัน จึงต้องใช้ connection เดียว
+		if cfg.InMemory() {
+			sqlDB.SetMaxOpenConns(1)
+		}
+	}
+
+	return db, nil
+}
+
+// sqlitePragmas เพิ่ม pragma ให้ทุก connection ของ sqlite: เปิด foreign key ให้ตรงกับ mysql/postgres
+// และรอ lock แทนการ error ทันทีเมื่อมีการเขียนพร้อมกัน
+// transaction เริ่มด้วย BEGIN IMMEDIATE เพื่อจอง lock สำหรับเขียนตั้งแต่ต้น ถ้าเริ่มแบบ deferred
+// transaction ที่อ่านแล้วค่อยเขียนจะได้ SQLITE_BUSY ทันทีเมื่อมีอีก connection เขียนอยู่ โดยไม่รอ busy_timeout
+func sqlitePragmas(dsn string) string {
+	var params []string
+	if !strings.Contains(dsn, "_pragma=") {
+		params = append(params, "_pragma=foreign_keys(1)", "_pragma=busy_timeout(5000)")
+	}
+	if !strings.Contains(dsn, "_txlock=") {
+		params = append(params, "_txlock=immediate")
+	}
+	if len(params) == 0 {
+		return dsn
+	}
+
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + strings.Join(params, "&")
+}
@@ -0,0 +1,26 @@
+package dto
+
+// UpdateCartItemRequest คือ body ของ PUT /cart/items/:productId
+type UpdateCartItemRequest struct {
+	Amount int `json:"Amount" validate:"gt=0"`
+}
+
+// CartItemResponse คือสินค้าหนึ่งรายการในตะกร้า ราคาและจำนวนคงเหลือเป็นค่าปัจจุบันของสินค้า
+// Available เป็น false เมื่อสินค้าถูกลบไปแล้วหรือมีไม่พอกับจำนวนในตะกร้า
+type CartItemResponse struct {
+	ProductID  uint   `json:"ProductID"`
+	Product    string `json:"Product"`
+	Unit_Price int    `json:"Unit Price"`
+	Amount     int    `json:"Amount"`
+	Line_Total int    `json:"Line Total"`
+	Stock      int    `json:"Stock"`
+	Available  bool   `json:"Available"`
+}
+
+// CartResponse คือตะกร้าของ user พร้อมยอดรวมตามราคาปัจจุบัน
+// Available เป็น true เมื่อทุกรายการ checkout ได้
+type CartResponse struct {
+	Items       []CartItemResponse `json:"Items"`
+	Total_Price int                `json:"Total Price"`
+	Available   bool               `json:"Available"`
+}
@@ -0,0 +1,74 @@
+package dto
+
+import (
+	m "go-fiber-test/models"
+	"time"
+)
+
+// CouponRequest คือ body ของ POST /coupon และ PUT /coupon/:couponId
+// PUT แทนที่ค่าทั้งหมดของ coupon จึงล้าง ProductID หรือวันที่ได้ด้วยการไม่ส่งมา
+// Code ถูกเก็บเป็นตัวพิมพ์ใหญ่เสมอ
+type CouponRequest struct {
+	Code         string     `json:"Code" validate:"required,max=64,alphanum"`
+	Type         string     `json:"Type" validate:"required,oneof=percent fixed"`
+	Value        int        `json:"Value" validate:"gt=0"`
+	MinOrder     int        `json:"MinOrder" validate:"gte=0"`
+	ProductID    *uint      `json:"ProductID" validate:"omitnil,gt=0"`
+	StartsAt     *time.Time `json:"StartsAt"`
+	EndsAt       *time.Time `json:"EndsAt"`
+	UsageLimit   int        `json:"UsageLimit" validate:"gte=0"`
+	PerUserLimit int        `json:"PerUserLimit" validate:"gte=0"`
+}
+
+// CheckoutRequest คือ body (ไม่บังคับ) ของ POST /cart/checkout
+type CheckoutRequest struct {
+	Coupon string `json:"Coupon" validate:"omitempty,max=64"`
+}
+
+// CouponListQuery คือการเรียงของ GET /coupon
+type CouponListQuery struct {
+	Sort string `query:"sort" validate:"omitempty,sort=id code created_at"`
+}
+
+// CouponResponse คือ coupon ที่ส่งกลับให้ admin
+type CouponResponse struct {
+	ID           uint       `json:"ID"`
+	CreatedAt    time.Time  `json:"CreatedAt"`
+	UpdatedAt    time.Time  `json:"UpdatedAt"`
+	Code         string     `json:"Code"`
+	Type         string     `json:"Type"`
+	Value        int        `json:"Value"`
+	MinOrder     int        `json:"MinOrder"`
+	ProductID    *uint      `json:"ProductID"`
+	StartsAt     *time.Time `json:"StartsAt"`
+	EndsAt       *time.Time `json:"EndsAt"`
+	UsageLimit   int        `json:"UsageLimit"`
+	PerUserLimit int        `json:"PerUserLimit"`
+	Used         int        `json:"Used"`
+}
+
+func NewCouponResponse(coupon *m.Coupon) CouponResponse {
+	return CouponResponse{
+		ID:           coupon.ID,
+		CreatedAt:    coupon.CreatedAt,
+		UpdatedAt:    coupon.UpdatedAt,
+		Code:         coupon.Code,
+		Type:         coupon.Type,
+		Value:        coupon.Value,
+		MinOrder:     coupon.MinOrder,
+		ProductID:    coupon.ProductID,
+		StartsAt:     coupon.StartsAt,
+		EndsAt:       coupon.EndsAt,
+		UsageLimit:   coupon.UsageLimit,
+		PerUserLimit: coupon.PerUserLimit,
+		Used:         coupon.Used,
+	}
+}
+
+func NewCouponResponses(coupons []m.Coupon) []CouponResponse {
+	out := make([]CouponResponse, len(coupons))
+	for i := range coupons {
+		out[i] = NewCouponResponse(&coupons[i])
+	}
+	return out
+}
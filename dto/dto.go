@@ -0,0 +1,135 @@
+// Package dto คือ struct ของ request แต่ละ endpoint พร้อม validate tag ซึ่ง controller ใช้แทนการ
+// parse เข้า model โดยตรง Validate จะคืน apperr ที่มี error ราย field อยู่ใน details
+package dto
+
+import (
+	"errors"
+	"fmt"
+	"go-fiber-test/apperr"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// usernamePattern คือตัวอักษรที่อนุญาตใน username
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// ใช้ชื่อ field ตาม json tag (ซึ่งตรงกับ form tag) หรือ query tag เพื่อให้ชื่อใน error ตรงกับที่ client ส่งมา
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "query"} {
+			name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+
+	v.RegisterValidation("username", func(fl validator.FieldLevel) bool {
+		return usernamePattern.MatchString(fl.Field().String())
+	})
+	v.RegisterValidation("sort", validSort)
+	v.RegisterValidation("timestamp", validTimestamp)
+
+	return v
+}
+
+// FieldError คือ error ของ field หนึ่งตัวใน request
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Validate ตรวจสอบ req ตาม validate tag ถ้าไม่ผ่านจะคืน apperr code validation_failed
+// พร้อมรายการ FieldError ใน details
+func Validate(req any) error {
+	err := validate.Struct(req)
+	if err == nil {
+		return nil
+	}
+
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return apperr.Internal("Failed to validate request.", err)
+	}
+
+	fields := make([]FieldError, 0, len(errs))
+	for _, fe := range errs {
+		fields = append(fields, FieldError{
+			Field:   fieldPath(fe),
+			Rule:    fe.Tag(),
+			Message: message(fe),
+		})
+	}
+	return Invalid(fields...)
+}
+
+// Invalid คืน error รูปแบบเดียวกับ Validate สำหรับกฎที่ต้องตรวจกับข้อมูลในฐานข้อมูลและเขียนเป็น tag ไม่ได้
+func Invalid(fields ...FieldError) error {
+	return apperr.BadRequest("Request validation failed.").
+		WithCode(apperr.CodeValidation).
+		WithDetails(fields)
+}
+
+// fieldPath ตัดชื่อ struct นำหน้าออก เช่น OrderRequest.Items[0].Amount -> Items[0].Amount
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return ns
+}
+
+func message(fe validator.FieldError) string {
+	field := fieldPath(fe)
+	unit := ""
+	switch fe.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return field + " is required."
+	case "required_without":
+		return fmt.Sprintf("%s is required when %s is not provided.", field, fe.Param())
+	case "min":
+		return fmt.Sprintf("%s must be at least %s%s.", field, fe.Param(), unit)
+	case "max":
+		return fmt.Sprintf("%s must be at most %s%s.", field, fe.Param(), unit)
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s.", field, fe.Param())
+	case "gte":
+		return fmt.Sprintf("%s must be greater than or equal to %s.", field, fe.Param())
+	case "username":
+		return field + " contains invalid characters. Only (a-z), (A-Z), (0-9), -, _"
+	case "sort":
+		return fmt.Sprintf("%s must be one of %s (prefix with - for descending order).", field, strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "timestamp":
+		return field + " must be a date (2006-01-02) or an RFC 3339 timestamp."
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s.", field, strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "number":
+		return field + " must be a number."
+	case "alphanum":
+		return field + " must contain only letters and digits."
+	case "excluded_with":
+		return fmt.Sprintf("%s can't be used together with %s.", field, strings.ToLower(fe.Param()))
+	case "unique":
+		return fmt.Sprintf("%s must not contain the same %s twice.", field, fe.Param())
+	}
+	return fmt.Sprintf("%s failed the %s rule.", field, fe.Tag())
+}
@@ -0,0 +1,96 @@
+package dto
+
+import (
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// dateLayout คือรูปแบบวันที่แบบไม่มีเวลาที่รับได้ใน filter ช่วงวันที่ (นอกเหนือจาก RFC 3339)
+const dateLayout = "2006-01-02"
+
+// PageQuery คือ query parameter สำหรับแบ่งหน้าที่ใช้ร่วมกันทุก list
+// ส่ง cursor เพื่อใช้ keyset pagination หรือ offset เพื่อใช้ offset pagination
+type PageQuery struct {
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Offset int    `query:"offset" validate:"omitempty,min=0"`
+	Cursor string `query:"cursor" validate:"excluded_with=Offset"`
+}
+
+// ProductListQuery คือ filter และการเรียงของ GET /product
+type ProductListQuery struct {
+	Sort        string `query:"sort" validate:"omitempty,sort=id product_name price amount created_at updated_at"`
+	MinPrice    *int   `query:"min_price" validate:"omitnil,gte=0"`
+	MaxPrice    *int   `query:"max_price" validate:"omitnil,gte=0"`
+	InStock     *bool  `query:"in_stock"`
+	CreatedFrom string `query:"created_from" validate:"omitempty,timestamp"`
+	CreatedTo   string `query:"created_to" validate:"omitempty,timestamp"`
+}
+
+// OrderListQuery คือ filter และการเรียงของ GET /order
+type OrderListQuery struct {
+	Sort        string `query:"sort" validate:"omitempty,sort=id total_price created_at updated_at"`
+	Buyer       string `query:"buyer" validate:"omitempty,number"`
+	Status      string `query:"status" validate:"omitempty,oneof=pending failed paid processing shipped delivered cancelled refunded"`
+	CreatedFrom string `query:"created_from" validate:"omitempty,timestamp"`
+	CreatedTo   string `query:"created_to" validate:"omitempty,timestamp"`
+}
+
+// UserListQuery คือ filter และการเรียงของ GET /user
+type UserListQuery struct {
+	Sort        string `query:"sort" validate:"omitempty,sort=id username created_at updated_at"`
+	Role        string `query:"role" validate:"omitempty,oneof=user admin"`
+	Approved    *bool  `query:"approved"`
+	CreatedFrom string `query:"created_from" validate:"omitempty,timestamp"`
+	CreatedTo   string `query:"created_to" validate:"omitempty,timestamp"`
+}
+
+// ExportQuery คือรูปแบบไฟล์ของ GET /product/export, /order/export และ /user/export ค่าเริ่มต้นคือ csv
+// filter ใช้ query เดียวกับ list ของแต่ละ endpoint แต่แถวจะเรียงตาม id เสมอ
+type ExportQuery struct {
+	Format string `query:"format" validate:"omitempty,oneof=csv xlsx"`
+}
+
+// ParseSort แยก sort เช่น "-price" เป็นชื่อ column และทิศทาง (ขึ้นต้นด้วย - คือเรียงจากมากไปน้อย)
+func ParseSort(sort string) (column string, desc bool) {
+	if strings.HasPrefix(sort, "-") {
+		return sort[1:], true
+	}
+	return sort, false
+}
+
+// ParseTime แปลงค่าจาก filter ช่วงวันที่ ถ้าเป็นวันที่อย่างเดียวและ endOfDay เป็น true จะได้เวลาเริ่มต้นของวันถัดไป
+// เพื่อให้ created_to=2024-01-31 รวมทั้งวันที่ 31 ด้วย คืน nil ถ้า value ว่าง
+func ParseTime(value string, endOfDay bool) *time.Time {
+	if value == "" {
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t
+	}
+	t, err := time.ParseInLocation(dateLayout, value, time.Local)
+	if err != nil {
+		return nil
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t
+}
+
+// validSort ตรวจสอบว่า sort (ตัด - นำหน้าออกแล้ว) อยู่ในรายการ column ที่ param กำหนด
+func validSort(fl validator.FieldLevel) bool {
+	column, _ := ParseSort(fl.Field().String())
+	for _, allowed := range strings.Fields(fl.Param()) {
+		if column == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// validTimestamp ตรวจสอบว่าเป็นวันที่ (2006-01-02) หรือเวลาแบบ RFC 3339
+func validTimestamp(fl validator.FieldLevel) bool {
+	return ParseTime(fl.Field().String(), false) != nil
+}
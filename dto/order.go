@@ -0,0 +1,150 @@
+package dto
+
+import (
+	m "go-fiber-test/models"
+	"time"
+)
+
+// ItemRequest คือสินค้าหนึ่งรายการใน order ระบุสินค้าด้วย ProductID หรือชื่อสินค้าอย่างใดอย่างหนึ่ง
+// ถ้าส่งมาทั้งคู่จะใช้ ProductID
+type ItemRequest struct {
+	ProductID uint   `json:"ProductID"`
+	Product   string `json:"Product" validate:"required_without=ProductID"`
+	Amount    int    `json:"Amount" validate:"gt=0"`
+}
+
+// OrderRequest คือ body ของ POST /order/:userId, POST /order/quote และ PUT /order/:orderId
+// สินค้าแต่ละตัวต้องมีได้รายการเดียวใน request ซึ่ง controller ตรวจหลังจากหาสินค้าเจอแล้ว
+// Coupon ใช้ได้เฉพาะตอนสร้าง order ส่วน PUT จะคำนวณส่วนลดของ coupon เดิมใหม่ตามรายการที่แก้
+type OrderRequest struct {
+	Items  []ItemRequest `json:"Items" validate:"required,min=1,dive"`
+	Coupon string        `json:"Coupon" validate:"omitempty,max=64"`
+}
+
+// UpdateOrderStatusRequest คือ body ของ PUT /order/:orderId/status
+type UpdateOrderStatusRequest struct {
+	Status string `json:"Status" validate:"required,oneof=pending failed paid processing shipped delivered cancelled refunded"`
+}
+
+// รูปแบบของใบแจ้งหนี้
+const (
+	InvoicePDF  = "pdf"
+	InvoiceHTML = "html"
+)
+
+// InvoiceQuery คือ query string ของ GET /order/:orderId/invoice ค่าเริ่มต้นคือ pdf
+type InvoiceQuery struct {
+	Format string `query:"format" validate:"omitempty,oneof=pdf html"`
+}
+
+// ItemResponse คือสินค้าหนึ่งรายการใน order ที่ส่งกลับให้ client
+type ItemResponse struct {
+	ID         uint   `json:"ID"`
+	ProductID  uint   `json:"ProductID"`
+	Product    string `json:"Product"`
+	Unit_Price int    `json:"Unit Price"`
+	Amount     int    `json:"Amount"`
+	Line_Total int    `json:"Line Total"`
+	Weight     int    `json:"Weight"`
+	OrderID    uint   `json:"OrderID"`
+}
+
+// OrderResponse คือ order ที่ส่งกลับให้ client
+type OrderResponse struct {
+	ID           uint           `json:"ID"`
+	CreatedAt    time.Time      `json:"CreatedAt"`
+	UpdatedAt    time.Time      `json:"UpdatedAt"`
+	Version      int            `json:"Version"`
+	Buyer        string         `json:"Buyer"`
+	Items        []ItemResponse `json:"Items"`
+	Subtotal     int            `json:"Subtotal"`
+	CouponCode   string         `json:"CouponCode"`
+	Discount     int            `json:"Discount"`
+	Shipping     int            `json:"Shipping"`
+	Tax          int            `json:"Tax"`
+	Total_Price  int            `json:"Total Price"`
+	Status       string         `json:"Status"`
+	PaidAt       *time.Time     `json:"PaidAt"`
+	FailedAt     *time.Time     `json:"FailedAt"`
+	ProcessingAt *time.Time     `json:"ProcessingAt"`
+	ShippedAt    *time.Time     `json:"ShippedAt"`
+	DeliveredAt  *time.Time     `json:"DeliveredAt"`
+	CancelledAt  *time.Time     `json:"CancelledAt"`
+	RefundedAt   *time.Time     `json:"RefundedAt"`
+	// ReservedUntil คือเวลาที่ต้องยืนยันหรือชำระเงินก่อนสินค้าที่กันไว้จะถูกปล่อยคืนและ order ถูกยกเลิก
+	ReservedUntil *time.Time `json:"ReservedUntil"`
+}
+
+// QuoteResponse คือยอดของรายการสินค้าที่ประเมินจาก POST /order/quote โดยยังไม่ได้สร้าง order
+type QuoteResponse struct {
+	Items       []ItemResponse `json:"Items"`
+	Subtotal    int            `json:"Subtotal"`
+	CouponCode  string         `json:"CouponCode"`
+	Discount    int            `json:"Discount"`
+	Shipping    int            `json:"Shipping"`
+	Tax         int            `json:"Tax"`
+	Total_Price int            `json:"Total Price"`
+}
+
+func newItemResponses(items []m.Item) []ItemResponse {
+	out := make([]ItemResponse, len(items))
+	for i, item := range items {
+		out[i] = ItemResponse{
+			ID:         item.ID,
+			ProductID:  item.ProductID,
+			Product:    item.Product,
+			Unit_Price: item.Unit_Price,
+			Amount:     item.Amount,
+			Line_Total: item.Line_Total,
+			Weight:     item.Weight,
+			OrderID:    item.OrderID,
+		}
+	}
+	return out
+}
+
+func NewQuoteResponse(order *m.Order) QuoteResponse {
+	return QuoteResponse{
+		Items:       newItemResponses(order.Items),
+		Subtotal:    order.Subtotal,
+		CouponCode:  order.CouponCode,
+		Discount:    order.Discount,
+		Shipping:    order.Shipping,
+		Tax:         order.Tax,
+		Total_Price: order.Total_Price,
+	}
+}
+
+func NewOrderResponse(order *m.Order) OrderResponse {
+	return OrderResponse{
+		ID:            order.ID,
+		CreatedAt:     order.CreatedAt,
+		UpdatedAt:     order.UpdatedAt,
+		Version:       order.Version,
+		Buyer:         order.Buyer,
+		Items:         newItemResponses(order.Items),
+		Subtotal:      order.Subtotal,
+		CouponCode:    order.CouponCode,
+		Discount:      order.Discount,
+		Shipping:      order.Shipping,
+		Tax:           order.Tax,
+		Total_Price:   order.Total_Price,
+		Status:        order.Status,
+		PaidAt:        order.PaidAt,
+		FailedAt:      order.FailedAt,
+		ProcessingAt:  order.ProcessingAt,
+		ShippedAt:     order.ShippedAt,
+		DeliveredAt:   order.DeliveredAt,
+		CancelledAt:   order.CancelledAt,
+		RefundedAt:    order.RefundedAt,
+		ReservedUntil: order.ReservedUntil,
+	}
+}
+
+func NewOrderResponses(orders []m.Order) []OrderResponse {
+	out := make([]OrderResponse, len(orders))
+	for i := range orders {
+		out[i] = NewOrderResponse(&orders[i])
+	}
+	return out
+}
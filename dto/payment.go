@@ -0,0 +1,35 @@
+package dto
+
+import (
+	m "go-fiber-test/models"
+	"time"
+)
+
+// PaymentResponse คือ payment ที่ส่งกลับให้ client ส่วน ClientSecret มีเฉพาะตอนสร้าง
+// เพื่อให้ frontend นำไปยืนยันการจ่ายเงินกับ provider
+type PaymentResponse struct {
+	ID           uint      `json:"ID"`
+	CreatedAt    time.Time `json:"CreatedAt"`
+	UpdatedAt    time.Time `json:"UpdatedAt"`
+	OrderID      uint      `json:"OrderID"`
+	Provider     string    `json:"Provider"`
+	ProviderRef  string    `json:"ProviderRef"`
+	Amount       int       `json:"Amount"`
+	Currency     string    `json:"Currency"`
+	Status       string    `json:"Status"`
+	ClientSecret string    `json:"ClientSecret,omitempty"`
+}
+
+func NewPaymentResponse(payment *m.Payment) PaymentResponse {
+	return PaymentResponse{
+		ID:          payment.ID,
+		CreatedAt:   payment.CreatedAt,
+		UpdatedAt:   payment.UpdatedAt,
+		OrderID:     payment.OrderID,
+		Provider:    payment.Provider,
+		ProviderRef: payment.ProviderRef,
+		Amount:      payment.Amount,
+		Currency:    payment.Currency,
+		Status:      payment.Status,
+	}
+}
@@ -0,0 +1,87 @@
+package dto
+
+import (
+	m "go-fiber-test/models"
+	"time"
+)
+
+// CreateProductRequest คือ field ใน multipart form ของ POST /product (รูปภาพอ่านแยกจาก field Images)
+type CreateProductRequest struct {
+	Product_Name string `json:"Product_Name" form:"Product_Name" validate:"required,max=255"`
+	Price        *int   `json:"Price" form:"Price" validate:"required,gte=0"`
+	Amount       *int   `json:"Amount" form:"Amount" validate:"required,gte=0"`
+	// Weight คือน้ำหนักต่อชิ้นเป็นกรัม ไม่ส่งมาคือ 0
+	Weight int `json:"Weight" form:"Weight" validate:"gte=0"`
+}
+
+// UpdateProductRequest คือ field ใน multipart form ของ PUT /product/:productId
+// field ที่เป็น nil คือไม่ได้ส่งมาและจะไม่ถูกเปลี่ยน
+type UpdateProductRequest struct {
+	Product_Name *string `json:"Product_Name" form:"Product_Name" validate:"omitnil,min=1,max=255"`
+	Price        *int    `json:"Price" form:"Price" validate:"omitnil,gte=0"`
+	Amount       *int    `json:"Amount" form:"Amount" validate:"omitnil,gte=0"`
+	Weight       *int    `json:"Weight" form:"Weight" validate:"omitnil,gte=0"`
+}
+
+// ProductImageResponse คือรูปภาพของสินค้าที่ส่งกลับให้ client
+type ProductImageResponse struct {
+	ID        uint      `json:"ID"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+	ProductID uint      `json:"product_id"`
+	ImageURL  string    `json:"image_url"`
+}
+
+// ProductResponse คือสินค้าที่ส่งกลับให้ client
+type ProductResponse struct {
+	ID           uint      `json:"ID"`
+	CreatedAt    time.Time `json:"CreatedAt"`
+	UpdatedAt    time.Time `json:"UpdatedAt"`
+	Version      int       `json:"Version"`
+	Product_Name string    `json:"Product_Name"`
+	Price        int       `json:"Price"`
+	// Amount คือจำนวนในคลัง ส่วน Available คือจำนวนที่ยังขายได้หลังหักสินค้าที่ถูกกันไว้ให้ order ที่ยังไม่ยืนยัน
+	Amount    int                    `json:"Amount"`
+	Reserved  int                    `json:"Reserved"`
+	Available int                    `json:"Available"`
+	Weight    int                    `json:"Weight"`
+	Images    []ProductImageResponse `json:"Images"`
+}
+
+func NewProductImageResponse(image *m.ProductImage) ProductImageResponse {
+	return ProductImageResponse{
+		ID:        image.ID,
+		CreatedAt: image.CreatedAt,
+		UpdatedAt: image.UpdatedAt,
+		ProductID: image.ProductID,
+		ImageURL:  image.ImageURL,
+	}
+}
+
+func NewProductResponse(product *m.Product) ProductResponse {
+	images := make([]ProductImageResponse, len(product.Images))
+	for i := range product.Images {
+		images[i] = NewProductImageResponse(&product.Images[i])
+	}
+	return ProductResponse{
+		ID:           product.ID,
+		CreatedAt:    product.CreatedAt,
+		UpdatedAt:    product.UpdatedAt,
+		Version:      product.Version,
+		Product_Name: product.Product_Name,
+		Price:        product.Price,
+		Amount:       product.Amount,
+		Reserved:     product.Reserved,
+		Available:    product.Available(),
+		Weight:       product.Weight,
+		Images:       images,
+	}
+}
+
+func NewProductResponses(products []m.Product) []ProductResponse {
+	out := make([]ProductResponse, len(products))
+	for i := range products {
+		out[i] = NewProductResponse(&products[i])
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package dto
+
+// ReportQuery คือช่วงเวลาของรายงาน from รวมขอบเขต ส่วน to ถ้าส่งมาเป็นวันที่จะรวมทั้งวันนั้นด้วย
+// interval ค่าเริ่มต้นคือ day
+type ReportQuery struct {
+	Interval string `query:"interval" validate:"omitempty,oneof=day week month"`
+	From     string `query:"from" validate:"omitempty,timestamp"`
+	To       string `query:"to" validate:"omitempty,timestamp"`
+}
+
+// TopProductsQuery คือการเรียงและจำนวนสินค้าของ GET /report/top-products ค่าเริ่มต้นคือ units และ 10
+type TopProductsQuery struct {
+	By    string `query:"by" validate:"omitempty,oneof=units revenue"`
+	Limit int    `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+// SalesSummaryResponse คือยอดขายรวมของทั้งช่วงเวลา Revenue และ AverageOrderValue เป็นยอดสุทธิหลังหักส่วนลด
+// ไม่รวมภาษีและค่าส่ง
+type SalesSummaryResponse struct {
+	Orders            int64   `json:"Orders"`
+	Revenue           int64   `json:"Revenue"`
+	AverageOrderValue float64 `json:"AverageOrderValue"`
+	NewCustomers      int64   `json:"NewCustomers"`
+}
+
+// SalesPointResponse คือยอดขายในหนึ่งช่วง Period คือวันแรกของช่วง Revenue นับแบบเดียวกับ SalesSummaryResponse
+type SalesPointResponse struct {
+	Period            string  `json:"Period"`
+	Orders            int64   `json:"Orders"`
+	Revenue           int64   `json:"Revenue"`
+	AverageOrderValue float64 `json:"AverageOrderValue"`
+}
+
+// SalesReportResponse คือยอดขายแยกตามช่วงพร้อมยอดรวม
+type SalesReportResponse struct {
+	Interval string               `json:"Interval"`
+	Summary  SalesSummaryResponse `json:"Summary"`
+	Points   []SalesPointResponse `json:"Points"`
+}
+
+// ProductSalesResponse คือยอดขายของสินค้าหนึ่งตัว
+type ProductSalesResponse struct {
+	ProductID uint   `json:"ProductID"`
+	Product   string `json:"Product"`
+	Units     int64  `json:"Units"`
+	Revenue   int64  `json:"Revenue"`
+}
+
+// CustomerPointResponse คือจำนวนลูกค้าใหม่ในหนึ่งช่วง
+type CustomerPointResponse struct {
+	Period    string `json:"Period"`
+	Customers int64  `json:"Customers"`
+}
+
+// CustomersReportResponse คือจำนวนลูกค้าใหม่แยกตามช่วงพร้อมยอดรวม
+type CustomersReportResponse struct {
+	Interval string                  `json:"Interval"`
+	Total    int64                   `json:"Total"`
+	Points   []CustomerPointResponse `json:"Points"`
+}
@@ -0,0 +1,72 @@
+package dto
+
+import (
+	m "go-fiber-test/models"
+	"time"
+)
+
+// StockHistoryQuery คือการเรียงของ GET /product/:productId/stock
+type StockHistoryQuery struct {
+	Sort string `query:"sort" validate:"omitempty,sort=id created_at"`
+}
+
+// StockAdjustRequest คือ body ของ POST /product/:productId/stock
+// restock ต้องเป็นการเพิ่มสินค้าเสมอ ส่วน adjustment เพิ่มหรือลดก็ได้
+type StockAdjustRequest struct {
+	Delta  int    `json:"Delta" validate:"required"`
+	Reason string `json:"Reason" validate:"required,oneof=restock adjustment"`
+	Note   string `json:"Note" validate:"max=255"`
+}
+
+// StockMovementResponse คือการเปลี่ยนแปลงสต็อกหนึ่งครั้งใน ledger
+type StockMovementResponse struct {
+	ID        uint      `json:"ID"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	ProductID uint      `json:"ProductID"`
+	Delta     int       `json:"Delta"`
+	Balance   int       `json:"Balance"`
+	Reason    string    `json:"Reason"`
+	ActorID   *uint     `json:"ActorID"`
+	Reference string    `json:"Reference"`
+	Note      string    `json:"Note"`
+}
+
+func NewStockMovementResponse(move *m.StockMovement) StockMovementResponse {
+	return StockMovementResponse{
+		ID:        move.ID,
+		CreatedAt: move.CreatedAt,
+		ProductID: move.ProductID,
+		Delta:     move.Delta,
+		Balance:   move.Balance,
+		Reason:    move.Reason,
+		ActorID:   move.ActorID,
+		Reference: move.Reference,
+		Note:      move.Note,
+	}
+}
+
+func NewStockMovementResponses(moves []m.StockMovement) []StockMovementResponse {
+	responses := make([]StockMovementResponse, len(moves))
+	for i := range moves {
+		responses[i] = NewStockMovementResponse(&moves[i])
+	}
+	return responses
+}
+
+// StockReconcileResponse เทียบจำนวนสินค้าที่เก็บไว้กับผลรวมใน ledger
+type StockReconcileResponse struct {
+	ProductID uint `json:"ProductID"`
+	Amount    int  `json:"Amount"`
+	Ledger    int  `json:"Ledger"`
+	// Difference คือ Amount - Ledger ถ้าไม่เป็นศูนย์แปลว่ามีการแก้ amount โดยไม่ผ่าน ledger
+	Difference int `json:"Difference"`
+}
+
+func NewStockReconcileResponse(product *m.Product, ledger int) StockReconcileResponse {
+	return StockReconcileResponse{
+		ProductID:  product.ID,
+		Amount:     product.Amount,
+		Ledger:     ledger,
+		Difference: product.Amount - ledger,
+	}
+}
@@ -0,0 +1,74 @@
+package dto
+
+import (
+	m "go-fiber-test/models"
+	"time"
+)
+
+// RegisterRequest คือ body ของ POST /user/register
+type RegisterRequest struct {
+	Username  string `json:"Username" form:"Username" validate:"required,max=50,username"`
+	Password  string `json:"Password" form:"Password" validate:"required,min=6,max=20"`
+	FirstName string `json:"FirstName" form:"FirstName" validate:"required,max=100"`
+	LastName  string `json:"LastName" form:"LastName" validate:"required,max=100"`
+}
+
+// LoginRequest คือ body ของ POST /user/login
+type LoginRequest struct {
+	Username string `json:"Username" form:"Username" validate:"required"`
+	Password string `json:"Password" form:"Password" validate:"required"`
+}
+
+// UpdateUserRequest คือ body ของ PUT /user/:userId field ที่ไม่ได้ส่งมาจะไม่ถูกเปลี่ยน
+type UpdateUserRequest struct {
+	Username  string `json:"Username" form:"Username" validate:"omitempty,max=50,username"`
+	Password  string `json:"Password" form:"Password" validate:"omitempty,min=6,max=20"`
+	FirstName string `json:"FirstName" form:"FirstName" validate:"omitempty,max=100"`
+	LastName  string `json:"LastName" form:"LastName" validate:"omitempty,max=100"`
+}
+
+// ApproveRequest คือ body ของ PUT /user/approve
+type ApproveRequest struct {
+	UserID uint `json:"UserID" form:"UserID" validate:"required"`
+}
+
+// UpdateRoleRequest คือ body ของ PUT /user/role ซึ่งมีแค่ admin ที่เรียกได้
+type UpdateRoleRequest struct {
+	UserID uint   `json:"UserID" form:"UserID" validate:"required"`
+	Role   string `json:"Role" form:"Role" validate:"required,oneof=user admin"`
+}
+
+// UserResponse คือข้อมูล user ที่ส่งกลับให้ client โดยไม่มี password hash
+type UserResponse struct {
+	ID        uint      `json:"ID"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+	Version   int       `json:"Version"`
+	Username  string    `json:"Username"`
+	FirstName string    `json:"FirstName"`
+	LastName  string    `json:"LastName"`
+	Role      string    `json:"Role"`
+	Approve   bool      `json:"Approve"`
+}
+
+func NewUserResponse(user *m.User) UserResponse {
+	return UserResponse{
+		ID:        user.ID,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+		Version:   user.Version,
+		Username:  user.Username,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Role:      user.Role,
+		Approve:   user.Approve,
+	}
+}
+
+func NewUserResponses(users []m.User) []UserResponse {
+	out := make([]UserResponse, len(users))
+	for i := range users {
+		out[i] = NewUserResponse(&users[i])
+	}
+	return out
+}
@@ -0,0 +1,43 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+type csvWriter struct {
+	w   *csv.Writer
+	row []string
+}
+
+// NewCSV สร้าง Writer ที่เขียน CSV
+func NewCSV(w io.Writer) Writer {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) WriteRow(cells ...any) error {
+	c.row = c.row[:0]
+	for _, cell := range cells {
+		value, number := text(cell)
+		if !number {
+			value = escapeFormula(value)
+		}
+		c.row = append(c.row, value)
+	}
+	return c.w.Write(c.row)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// escapeFormula ใส่ ' นำหน้าข้อความที่ spreadsheet จะตีความเป็น formula เช่นชื่อสินค้าที่ขึ้นต้นด้วย =
+// เพื่อไม่ให้ข้อมูลที่ผู้ใช้กรอกถูกรันเป็น formula ตอนเปิดไฟล์
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
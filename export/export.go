@@ -0,0 +1,69 @@
+// Package export เขียนข้อมูลแบบตารางออกเป็น CSV หรือ XLSX ทีละแถว โดยไม่ต้องเก็บทั้งไฟล์ไว้ใน memory
+package export
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// รูปแบบไฟล์ที่รองรับ
+const (
+	CSV  = "csv"
+	XLSX = "xlsx"
+)
+
+// Writer เขียนแถวของตารางลงใน io.Writer ปลายทาง ต้องเรียก Close เพื่อเขียนส่วนท้ายของไฟล์
+// ค่าในแต่ละ cell เป็น string, bool, ตัวเลขจำนวนเต็ม, time.Time หรือ *time.Time (nil คือ cell ว่าง)
+type Writer interface {
+	WriteRow(cells ...any) error
+	Close() error
+}
+
+// New สร้าง Writer ตาม format (CSV หรือ XLSX) sheet คือชื่อ worksheet ซึ่งใช้กับ XLSX เท่านั้น
+func New(format string, w io.Writer, sheet string) (Writer, error) {
+	switch format {
+	case CSV:
+		return NewCSV(w), nil
+	case XLSX:
+		return NewXLSX(w, sheet)
+	default:
+		return nil, fmt.Errorf("export: unsupported format %q", format)
+	}
+}
+
+// ContentType คือ MIME type ของไฟล์แต่ละรูปแบบ
+func ContentType(format string) string {
+	if format == XLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// text แปลงค่าของ cell เป็นข้อความ number เป็น true ถ้าค่าเป็นตัวเลขซึ่งไม่ต้องป้องกัน formula
+func text(cell any) (value string, number bool) {
+	switch v := cell.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, false
+	case bool:
+		return strconv.FormatBool(v), false
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), true
+	case time.Time:
+		return v.Format(time.RFC3339), false
+	case *time.Time:
+		if v == nil {
+			return "", false
+		}
+		return v.Format(time.RFC3339), false
+	default:
+		return fmt.Sprint(v), false
+	}
+}
@@ -0,0 +1,119 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+)
+
+// ไฟล์ประกอบของ workbook ที่มี worksheet เดียว ซึ่งเขียนก่อนข้อมูลของ sheet
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// xlsxWriter เขียน workbook แบบ Office Open XML ที่มี worksheet เดียว
+// ข้อความถูกเขียนเป็น inline string ทำให้เขียนแถวต่อท้ายไปเรื่อย ๆ ได้โดยไม่ต้องเก็บ shared strings ไว้
+type xlsxWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+}
+
+// NewXLSX สร้าง Writer ที่เขียน XLSX โดยเขียนส่วนหัวของ workbook ลงใน w ทันที
+func NewXLSX(w io.Writer, sheet string) (Writer, error) {
+	z := zip.NewWriter(w)
+
+	var name xmlText
+	name.write(sheet)
+	files := []struct{ path, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="` + string(name) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	}
+	for _, file := range files {
+		f, err := z.Create(file.path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, file.body); err != nil {
+			return nil, err
+		}
+	}
+
+	// sheet เป็นไฟล์สุดท้ายใน zip จึงเขียนต่อได้จนกว่าจะ Close
+	f, err := z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	x := &xlsxWriter{zip: z, sheet: bufio.NewWriter(f)}
+	if _, err := x.sheet.WriteString(xlsxSheetStart); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *xlsxWriter) WriteRow(cells ...any) error {
+	var row xmlText
+	row = append(row, "<row>"...)
+	for _, cell := range cells {
+		value, number := text(cell)
+		switch {
+		case value == "":
+			row = append(row, "<c/>"...)
+		case number:
+			row = append(row, "<c><v>"...)
+			row = append(row, value...)
+			row = append(row, "</v></c>"...)
+		default:
+			row = append(row, `<c t="inlineStr"><is><t xml:space="preserve">`...)
+			row.write(value)
+			row = append(row, "</t></is></c>"...)
+		}
+	}
+	row = append(row, "</row>"...)
+	_, err := x.sheet.Write(row)
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := x.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
+
+// xmlText คือ buffer ที่ escape ข้อความให้ใช้ใน XML ได้
+type xmlText []byte
+
+func (b *xmlText) Write(p []byte) (int, error) {
+	*b = append(*b, p...)
+	return len(p), nil
+}
+
+func (b *xmlText) write(s string) {
+	// EscapeText เขียนลง buffer ใน memory จึงไม่มี error
+	_ = xml.EscapeText(b, []byte(s))
+}
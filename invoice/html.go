@@ -0,0 +1,54 @@
+package invoice
+
+import (
+	"html/template"
+	"io"
+)
+
+var htmlTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"date": formatDate,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Number}}</title>
+<style>
+body { font-family: sans-serif; margin: 40px; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { padding: 6px 8px; border-bottom: 1px solid #ddd; text-align: left; }
+.num { text-align: right; }
+.totals td { border: none; }
+.grand td { font-weight: bold; border-top: 2px solid #222; }
+</style>
+</head>
+<body>
+<h1>Invoice</h1>
+<p>
+Invoice No: {{.Number}}<br>
+Issued: {{date .IssuedAt}}<br>
+Order: #{{.Invoice.OrderID}} ({{date .Invoice.OrderedAt}}){{with .PaidAt}}<br>
+Paid: {{.}}{{end}}
+</p>
+<h2>Bill To</h2>
+<p>{{.BuyerName}}</p>
+<table>
+<thead><tr><th>Product</th><th class="num">Unit Price</th><th class="num">Qty</th><th class="num">Amount</th></tr></thead>
+<tbody>
+{{- range .Invoice.Lines}}
+<tr><td>{{.Product}}</td><td class="num">{{$.Money .Unit_Price}}</td><td class="num">{{.Amount}}</td><td class="num">{{$.Money .Line_Total}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+<table class="totals">
+{{- range .Totals}}
+<tr{{if .Grand}} class="grand"{{end}}><td class="num">{{.Label}}</td><td class="num">{{.Amount}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// WriteHTML เขียนใบแจ้งหนี้เป็นหน้า HTML ที่พิมพ์ได้
+func WriteHTML(w io.Writer, doc Document) error {
+	return htmlTemplate.Execute(w, doc)
+}
@@ -0,0 +1,88 @@
+// Package invoice สร้างเอกสารใบแจ้งหนี้ของ order เป็น PDF และ HTML จากข้อมูลชุดเดียวกัน
+package invoice
+
+import (
+	m "go-fiber-test/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Document คือข้อมูลทั้งหมดที่แสดงในใบแจ้งหนี้หนึ่งใบ
+type Document struct {
+	Number   string
+	IssuedAt time.Time
+	Currency string
+	// Invoice คือยอดเงินและรายการสินค้าที่บันทึกไว้ตอนออกใบแจ้งหนี้ ไม่ใช่ order ปัจจุบัน
+	Invoice *m.Invoice
+	// Buyer เป็น nil ถ้า user ถูกลบถาวรไปแล้ว เอกสารจะแสดงแค่ ID ของผู้ซื้อ
+	Buyer *m.User
+}
+
+// Line คือบรรทัดยอดรวมท้ายเอกสาร
+type Line struct {
+	Label  string
+	Amount string
+	// Grand คือบรรทัดยอดสุทธิซึ่งแสดงตัวหนา
+	Grand bool
+}
+
+// BuyerName คือชื่อผู้ซื้อที่แสดงในเอกสาร
+func (d Document) BuyerName() string {
+	if d.Buyer == nil {
+		return "Customer #" + d.Invoice.Buyer
+	}
+	name := strings.TrimSpace(d.Buyer.FirstName + " " + d.Buyer.LastName)
+	if name == "" {
+		return d.Buyer.Username
+	}
+	return name + " (" + d.Buyer.Username + ")"
+}
+
+// Totals คือยอดสินค้า ส่วนลด ค่าส่ง ภาษี และยอดสุทธิตามลำดับ โดยข้ามส่วนลดถ้าไม่มี
+func (d Document) Totals() []Line {
+	invoice := d.Invoice
+	lines := []Line{{Label: "Subtotal", Amount: d.Money(invoice.Subtotal)}}
+	if invoice.Discount > 0 {
+		label := "Discount"
+		if invoice.CouponCode != "" {
+			label += " (" + invoice.CouponCode + ")"
+		}
+		lines = append(lines, Line{Label: label, Amount: "-" + d.Money(invoice.Discount)})
+	}
+	return append(lines,
+		Line{Label: "Shipping", Amount: d.Money(invoice.Shipping)},
+		Line{Label: "Tax", Amount: d.Money(invoice.Tax)},
+		Line{Label: "Total", Amount: d.Money(invoice.Total_Price), Grand: true},
+	)
+}
+
+// Money จัดรูปแบบจำนวนเงินพร้อมตัวคั่นหลักพันและสกุลเงิน เช่น 1,250 THB
+func (d Document) Money(amount int) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := strconv.Itoa(amount)
+	var b strings.Builder
+	for i, r := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return sign + b.String() + " " + d.Currency
+}
+
+// PaidAt คือวันที่ชำระเงินสำหรับแสดงในเอกสาร ว่างสำหรับใบแจ้งหนี้เก่าที่ออกก่อนการชำระเงิน
+func (d Document) PaidAt() string {
+	if d.Invoice.PaidAt == nil {
+		return ""
+	}
+	return formatDate(*d.Invoice.PaidAt)
+}
+
+func formatDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
@@ -0,0 +1,77 @@
+package invoice
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// WritePDF เขียนใบแจ้งหนี้เป็นไฟล์ PDF ขนาด A4
+// ใช้ font มาตรฐานของ PDF ซึ่งรองรับเฉพาะอักษรละติน (cp1252) อักษรอื่นเช่นภาษาไทยจะแสดงไม่ได้
+// ถ้าชื่อสินค้าหรือผู้ซื้อเป็นภาษาอื่นให้ใช้ฉบับ HTML แทน
+func WritePDF(w io.Writer, doc Document) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Invoice "+doc.Number, true)
+	pdf.SetMargins(20, 20, 20)
+	pdf.AddPage()
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.CellFormat(0, 12, "INVOICE", "", 1, "L", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 10)
+	header := []string{
+		"Invoice No: " + doc.Number,
+		"Issued: " + formatDate(doc.IssuedAt),
+		fmt.Sprintf("Order: #%d (%s)", doc.Invoice.OrderID, formatDate(doc.Invoice.OrderedAt)),
+	}
+	if paidAt := doc.PaidAt(); paidAt != "" {
+		header = append(header, "Paid: "+paidAt)
+	}
+	for _, line := range header {
+		pdf.CellFormat(0, 5, tr(line), "", 1, "L", false, 0, "")
+	}
+
+	pdf.Ln(6)
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(0, 6, "Bill To", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 5, tr(doc.BuyerName()), "", 1, "L", false, 0, "")
+
+	// ตารางรายการสินค้า: ชื่อสินค้า ราคาต่อชิ้น จำนวน และยอดของรายการ
+	widths := []float64{80, 35, 20, 35}
+	pdf.Ln(6)
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(235, 235, 235)
+	for i, header := range []string{"Product", "Unit Price", "Qty", "Amount"} {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		pdf.CellFormat(widths[i], 7, header, "B", 0, align, true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 10)
+	for _, item := range doc.Invoice.Lines {
+		pdf.CellFormat(widths[0], 7, tr(item.Product), "B", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 7, doc.Money(item.Unit_Price), "B", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[2], 7, strconv.Itoa(item.Amount), "B", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], 7, doc.Money(item.Line_Total), "B", 1, "R", false, 0, "")
+	}
+
+	pdf.Ln(4)
+	for _, line := range doc.Totals() {
+		border := ""
+		if line.Grand {
+			pdf.SetFont("Helvetica", "B", 11)
+			border = "T"
+		}
+		pdf.CellFormat(widths[0]+widths[1]+widths[2], 6, tr(line.Label), border, 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], 6, line.Amount, border, 1, "R", false, 0, "")
+	}
+
+	return pdf.Output(w)
+}
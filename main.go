@@ -1,56 +1,194 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"go-fiber-test/apperr"
+	"go-fiber-test/config"
+	"go-fiber-test/controllers"
 	"go-fiber-test/database"
-	m "go-fiber-test/models"
+	"go-fiber-test/middleware"
+	"go-fiber-test/migrations"
 	"go-fiber-test/routes"
+	"go-fiber-test/store"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/limiter"
-	"github.com/joho/godotenv"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
-func initDatabase() {
-	dsn := fmt.Sprintf(
-		"%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=true&loc=Local",
-		"root",
-		"",
-		"127.0.0.1",
-		"3306",
-		"golang_test",
-	)
-	var err error
-	database.DBConn, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+func initDatabase(cfg config.DatabaseConfig) *gorm.DB {
+	db, err := database.Open(cfg)
 	if err != nil {
 		panic(err)
 	}
-	fmt.Println("Database connected!")
-	database.DBConn.AutoMigrate(&m.Product{}, &m.ProductImage{}, &m.User{}, &m.Order{}, &m.Item{}, &m.Session{})
-	fmt.Println("AutoMigrate executed")
+	fmt.Println("Database connected! (" + cfg.Driver + ")")
+	return db
+}
+
+// checkMigrations apply migration ที่ค้างอยู่ถ้าเปิด auto_migrate ไว้ ไม่อย่างนั้นจะไม่ยอมเริ่ม server
+// จนกว่าจะรัน migrate up เพื่อไม่ให้ code ทำงานกับ schema ที่ไม่ตรงกัน
+func checkMigrations(db *gorm.DB, cfg config.DatabaseConfig) {
+	if cfg.AutoMigrate {
+		done, err := migrations.Up(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Applied %d migration(s)\n", len(done))
+		return
+	}
+
+	pending, err := migrations.Pending(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(pending) > 0 {
+		log.Fatalf("database has %d pending migration(s), run `migrate up` or set DB_AUTO_MIGRATE=true", len(pending))
+	}
+}
+
+// runMigrate จัดการคำสั่ง migrate up | down [steps] | status
+func runMigrate(db *gorm.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up | down [steps] | status")
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := migrations.Up(db)
+		for _, m := range done {
+			fmt.Printf("applied  %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Println("nothing to migrate")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("migrate down: steps must be a positive integer, got %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := migrations.Down(db, steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("nothing to revert")
+		}
+	case "status":
+		statuses, err := migrations.StatusOf(db)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			if s.Applied {
+				fmt.Printf("[x] %04d_%s (applied %s)\n", s.Version, s.Name, s.AppliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Printf("[ ] %04d_%s\n", s.Version, s.Name)
+			}
+		}
+	default:
+		return fmt.Errorf("migrate: unknown command %q (use up, down or status)", args[0])
+	}
+	return nil
+}
+
+// sweep ยกเลิก order ที่การกันสินค้าหมดเวลาแล้วและลบ Idempotency-Key ที่หมดอายุทุก ๆ interval จนกว่า ctx จะถูกยกเลิก
+// ถ้ามีสินค้าถูกปล่อยคืนจะล้าง cache ของรายการสินค้าด้วย
+func sweep(ctx context.Context, st store.Store, catalog *middleware.CatalogCache, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			released, err := controllers.ReleaseExpiredReservations(st, now)
+			if err != nil {
+				log.Println("Reservation sweeper:", err)
+			}
+			if released > 0 {
+				catalog.Purge()
+				log.Printf("Reservation sweeper: cancelled %d expired order(s)\n", released)
+			}
+			if _, err := st.Idempotency().DeleteExpired(now); err != nil {
+				log.Println("Idempotency sweeper:", err)
+			}
+		}
+	}
 }
 
 func main() {
-	// โหลด .env file
-	err := godotenv.Load()
+	// โหลดค่าตั้งค่าจาก env, .env และไฟล์ config แล้วตรวจสอบก่อนเริ่มทำงาน
+	cfg, err := config.Load()
 	if err != nil {
-		fmt.Println("Error loading .env file")
+		log.Fatal(err)
+	}
+
+	db := initDatabase(cfg.Database)
+
+	// go run . migrate up | down [steps] | status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(db, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	app := fiber.New()
-	initDatabase()
+	checkMigrations(db, cfg.Database)
 
-	// ใช้ limiter middleware จาก go fiber
-	app.Use(limiter.New(limiter.Config{
-		Max:        10,
-		Expiration: 30 * time.Second, // จำกัดให้สามารถส่งคำขอได้สูงสุด 10 ครั้งในทุก ๆ 30 วินาที
-	}))
+	app := fiber.New(fiber.Config{
+		// error ทุกตัวจาก handler และ middleware ถูกตอบกลับเป็น problem+json รูปแบบเดียวกัน
+		ErrorHandler: apperr.Handler,
+	})
 
-	routes.Routes(app)
-	app.Static("/uploads", "./uploads")
+	// สร้าง store จากการเชื่อมต่อฐานข้อมูลแล้วส่งต่อให้ controller ผ่าน routes
+	st := store.New(db)
+	// รายการสินค้าที่เปิดให้ทุกคนอ่านถูก cache ได้ และถูกล้างเมื่อ route หรือ sweeper เปลี่ยนสินค้าหรือสต็อก
+	catalog := middleware.NewCatalogCache(cfg.Catalog)
+	routes.Routes(app, cfg, st, catalog)
 
-	app.Listen(":3000")
+	// ปล่อยสินค้าที่ถูกกันไว้ให้ order ที่ไม่ได้ยืนยันภายในเวลาและลบข้อมูลที่หมดอายุ
+	sweepCtx, stopSweeper := context.WithCancel(context.Background())
+	go sweep(sweepCtx, st, catalog, cfg.Inventory.SweepInterval)
+
+	go func() {
+		if err := app.Listen(cfg.App.Addr()); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	// รอสัญญาณ SIGINT/SIGTERM แล้วปิด server แบบรอ request ที่ค้างอยู่ให้เสร็จก่อน
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+
+	fmt.Println("Shutting down...")
+	stopSweeper()
+	if err := app.ShutdownWithTimeout(cfg.App.ShutdownTimeout); err != nil {
+		log.Println("Server shutdown:", err)
+	}
+
+	// ปิด connection pool ของฐานข้อมูลหลังจากไม่มี request ค้างแล้ว
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			log.Println("Database close:", err)
+		}
+	}
+	fmt.Println("Server stopped")
 }
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"go-fiber-test/config"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CatalogCache ใส่ Cache-Control และตอบ 304 ให้ GET ของรายการสินค้าที่เปิดให้ทุกคนอ่าน
+// และถ้าตั้ง cfg.CacheTTL ไว้จะเก็บ response ไว้ใน memory ของ process นี้แยกตาม URL
+// handler ต้องใส่ ETag (และ Last-Modified ถ้ามี) ใน response เอง
+type CatalogCache struct {
+	cfg config.CatalogConfig
+
+	mu      sync.Mutex
+	entries map[string]catalogEntry
+	// generation เพิ่มทุกครั้งที่ cache ถูกล้าง response ที่โหลดจากฐานข้อมูลก่อนการล้างจะไม่ถูกเก็บ
+	generation uint64
+}
+
+// catalogEntry คือ response 200 หนึ่งตัวที่เก็บไว้ใน memory
+type catalogEntry struct {
+	contentType  string
+	etag         string
+	lastModified string
+	body         []byte
+	expiresAt    time.Time
+}
+
+func NewCatalogCache(cfg config.CatalogConfig) *CatalogCache {
+	return &CatalogCache{cfg: cfg, entries: make(map[string]catalogEntry)}
+}
+
+// Cache ใช้กับ GET route ของสินค้า ตอบจาก memory ถ้ามี response ที่ยังไม่หมดอายุ
+// และเปลี่ยน response เป็น 304 เมื่อ If-None-Match หรือ If-Modified-Since ตรงกับ response ปัจจุบัน
+func (cc *CatalogCache) Cache(c *fiber.Ctx) error {
+	key := c.OriginalURL()
+	if entry, ok := cc.get(key, time.Now()); ok {
+		c.Set(fiber.HeaderETag, entry.etag)
+		if entry.lastModified != "" {
+			c.Set(fiber.HeaderLastModified, entry.lastModified)
+		}
+		cc.setCacheControl(c)
+		if notModified(c) {
+			c.Status(fiber.StatusNotModified)
+			return nil
+		}
+		c.Set(fiber.HeaderContentType, entry.contentType)
+		return c.Status(fiber.StatusOK).Send(entry.body)
+	}
+
+	generation := cc.currentGeneration()
+	if err := c.Next(); err != nil {
+		return err
+	}
+	if c.Response().StatusCode() != fiber.StatusOK {
+		return nil
+	}
+
+	cc.setCacheControl(c)
+	// HEAD ไม่มี body จึงไม่เก็บ
+	if c.Method() == fiber.MethodGet {
+		cc.put(key, generation, catalogEntry{
+			contentType:  strings.Clone(c.GetRespHeader(fiber.HeaderContentType)),
+			etag:         strings.Clone(c.GetRespHeader(fiber.HeaderETag)),
+			lastModified: strings.Clone(c.GetRespHeader(fiber.HeaderLastModified)),
+			body:         append([]byte(nil), c.Response().Body()...),
+		})
+	}
+	if notModified(c) {
+		c.Status(fiber.StatusNotModified)
+		c.Response().ResetBody()
+	}
+	return nil
+}
+
+// Invalidate ใช้กับ route ที่แก้ข้อมูลสินค้า ล้าง cache หลัง handler ทำงานเสร็จ
+// ล้างแม้ handler จะคืน error เพราะข้อมูลบางส่วนอาจถูกบันทึกไปแล้ว
+func (cc *CatalogCache) Invalidate(c *fiber.Ctx) error {
+	err := c.Next()
+	cc.Purge()
+	return err
+}
+
+// Purge ล้าง response ทั้งหมดที่เก็บไว้
+func (cc *CatalogCache) Purge() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.entries = make(map[string]catalogEntry)
+	cc.generation++
+}
+
+func (cc *CatalogCache) setCacheControl(c *fiber.Ctx) {
+	if cc.cfg.MaxAge <= 0 {
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+		return
+	}
+	c.Set(fiber.HeaderCacheControl, "public, max-age="+strconv.Itoa(int(cc.cfg.MaxAge.Seconds())))
+}
+
+func (cc *CatalogCache) currentGeneration() uint64 {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.generation
+}
+
+func (cc *CatalogCache) get(key string, now time.Time) (catalogEntry, bool) {
+	if cc.cfg.CacheTTL <= 0 {
+		return catalogEntry{}, false
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	entry, ok := cc.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		return catalogEntry{}, false
+	}
+	return entry, true
+}
+
+// put เก็บ entry ถ้า cache ยังไม่ถูกล้างตั้งแต่ generation ที่เริ่มโหลด ถ้า cache เต็มจะลบตัวที่หมดอายุก่อน
+// และถ้ายังเต็มอยู่จะไม่เก็บ
+func (cc *CatalogCache) put(key string, generation uint64, entry catalogEntry) {
+	if cc.cfg.CacheTTL <= 0 || entry.etag == "" {
+		return
+	}
+	now := time.Now()
+	entry.expiresAt = now.Add(cc.cfg.CacheTTL)
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if generation != cc.generation {
+		return
+	}
+	if _, exists := cc.entries[key]; !exists && len(cc.entries) >= cc.cfg.CacheSize {
+		for k, e := range cc.entries {
+			if !now.Before(e.expiresAt) {
+				delete(cc.entries, k)
+			}
+		}
+		if len(cc.entries) >= cc.cfg.CacheSize {
+			return
+		}
+	}
+	cc.entries[key] = entry
+}
+
+// notModified ตรวจ conditional GET ตาม RFC 9110 ถ้ามี If-None-Match จะเทียบ ETag แบบ weak และไม่สนใจ If-Modified-Since
+// ถ้าไม่มีจะเทียบ If-Modified-Since กับ Last-Modified ของ response
+func notModified(c *fiber.Ctx) bool {
+	if noneMatch := c.Get(fiber.HeaderIfNoneMatch); noneMatch != "" {
+		etag := c.GetRespHeader(fiber.HeaderETag)
+		if etag == "" {
+			return false
+		}
+		for _, tag := range strings.Split(noneMatch, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	modifiedSince := c.Get(fiber.HeaderIfModifiedSince)
+	lastModified := c.GetRespHeader(fiber.HeaderLastModified)
+	if modifiedSince == "" || lastModified == "" {
+		return false
+	}
+	since, err := http.ParseTime(modifiedSince)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return !modified.After(since)
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"go-fiber-test/apperr"
+	"go-fiber-test/config"
+	m "go-fiber-test/models"
+	"go-fiber-test/store"
+	"hash"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// IdempotencyKeyHeader คือ header ที่ client ส่งคีย์ของ request มา ส่ง request ซ้ำด้วยคีย์เดิมจะได้ response แรกกลับไป
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader ถูกตั้งเป็น true ใน response ที่ตอบซ้ำจาก request แรก
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// Idempotency เก็บ response แรกของ request ที่ส่ง Idempotency-Key มาไว้ตาม cfg.TTL แยกตาม user
+// แล้วตอบ response เดิมเมื่อ client ส่ง request เดิมซ้ำ ถ้าคีย์เดิมถูกส่งมากับ request ที่ต่างไปจะตอบ 422
+// ต้องอยู่หลัง AuthRequired request ที่ไม่มี header นี้ทำงานตามปกติ
+// response ที่ล้มเหลว (handler คืน error หรือ status 5xx) ไม่ถูกเก็บ client จึงส่งซ้ำด้วยคีย์เดิมได้
+func Idempotency(cfg config.IdempotencyConfig, keys store.IdempotencyStore) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyKeyHeader)
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return apperr.BadRequest("Idempotency-Key must be at most 255 characters.").WithCode(apperr.CodeValidation)
+		}
+
+		claims, ok := c.Locals("user").(jwt.MapClaims)
+		if !ok {
+			return apperr.Unauthorized("Invalid token claims.").WithCode(apperr.CodeInvalidToken)
+		}
+
+		fingerprint, err := requestFingerprint(c)
+		if err != nil {
+			return apperr.BadRequest("Failed to parse form data.")
+		}
+
+		now := time.Now()
+		record, created, err := keys.Begin(&m.IdempotencyKey{
+			UserID:      uint(claims["UserID"].(float64)),
+			Key:         key,
+			Fingerprint: fingerprint,
+			ExpiresAt:   now.Add(cfg.TTL),
+		}, now)
+		if err != nil {
+			return apperr.Internal("Failed to check Idempotency-Key.", err)
+		}
+
+		if !created {
+			switch {
+			case record.Fingerprint != fingerprint:
+				return apperr.New(fiber.StatusUnprocessableEntity, apperr.CodeIdempotencyMismatch,
+					"Idempotency-Key has already been used with a different request.")
+			case !record.Completed:
+				return apperr.Conflict("A request with this Idempotency-Key is still being processed.").WithCode(apperr.CodeIdempotencyInProgress)
+			}
+			c.Set(IdempotentReplayedHeader, "true")
+			c.Set(fiber.HeaderContentType, record.ContentType)
+			return c.Status(record.Status).Send(record.Body)
+		}
+
+		if err := c.Next(); err != nil || c.Response().StatusCode() >= fiber.StatusInternalServerError {
+			if abandonErr := keys.Abandon(record); abandonErr != nil {
+				log.Printf("[%s] idempotency: releasing key: %v", c.GetRespHeader(fiber.HeaderXRequestID), abandonErr)
+			}
+			return err
+		}
+
+		record.Status = c.Response().StatusCode()
+		record.ContentType = string(c.Response().Header.ContentType())
+		record.Body = append([]byte(nil), c.Response().Body()...)
+		// request สำเร็จไปแล้ว ถ้าบันทึก response ไม่ได้ก็ยังตอบกลับตามปกติ ส่วน request ซ้ำจะได้ 409 จนกว่าคีย์จะหมดอายุ
+		if err := keys.Complete(record); err != nil {
+			log.Printf("[%s] idempotency: saving response: %v", c.GetRespHeader(fiber.HeaderXRequestID), err)
+		}
+		return nil
+	}
+}
+
+// requestFingerprint คือ hash ของ method, path และ body ของ request
+// multipart form ถูก hash จากค่าของแต่ละ field และไฟล์แทน body ดิบ เพราะ boundary สุ่มใหม่ทุกครั้งที่ client สร้าง form
+func requestFingerprint(c *fiber.Ctx) (string, error) {
+	h := sha256.New()
+	writeField(h, c.Method())
+	writeField(h, c.Path())
+
+	if !strings.HasPrefix(string(c.Request().Header.ContentType()), fiber.MIMEMultipartForm) {
+		h.Write(c.Body())
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return "", err
+	}
+	for _, name := range sortedKeys(form.Value) {
+		writeField(h, name)
+		for _, value := range form.Value[name] {
+			writeField(h, value)
+		}
+	}
+	for _, name := range sortedKeys(form.File) {
+		writeField(h, name)
+		for _, file := range form.File[name] {
+			writeField(h, file.Filename)
+			f, err := file.Open()
+			if err != nil {
+				return "", err
+			}
+			_, err = io.Copy(h, f)
+			f.Close()
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeField เขียนค่าตามด้วยตัวคั่น เพื่อไม่ให้ค่าที่ต่อกันต่างกันได้ hash เดียวกัน
+func writeField(h hash.Hash, value string) {
+	io.WriteString(h, value)
+	h.Write([]byte{0})
+}
+
+func sortedKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,144 +1,117 @@
 package middleware
 
 import (
-	"go-fiber-test/database"
-	m "go-fiber-test/models"
-	"go-fiber-test/session"
-	"os"
+	"errors"
+	"go-fiber-test/apperr"
+	"go-fiber-test/config"
+	"go-fiber-test/store"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthRequired(c *fiber.Ctx) error {
-	// ดึง Authorization header
-	tokenString := c.Get("Authorization")
-
-	if tokenString == "" {
-		return c.Status(fiber.StatusUnauthorized).SendString("Authorization header missing.")
-	}
-
-	// ตัด Bearer ออกให้เหลือแค่ token
-	tokenString = tokenString[len("Bearer "):]
-
-	// parse token เพื่ออ่าน token และดึง ACCESS_SECRET_KEY จาก env มาตรวจสอบว่า token ถูกต้องไหม
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// ตรวจสอบ signing method (for security)
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid token signing method.")
-		}
-		return []byte(os.Getenv("ACCESS_SECRET_KEY")), nil
-	})
-
-	// ตรวจสอบ token ที่ถูก parse ว่าถูกต้องหรือ (หมดอายุหรือถูกดัดแปลงไหม)
-	if err != nil || !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).SendString("Invalid or expired token")
-	}
-
-	// ดึง cliams จาก JWT
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).SendString("Invalid token claims.")
-	}
-
-	// เซ็ต claims ลงใน context เพื่อใช้งานใน controller
-	c.Locals("user", claims)
-
-	// สร้างตัวแปรมาเก็บค่าเวลาเดิมก่อนที่จะส่ง request (ครั้งแรกที่ login จะเป็นค่าเวลาเป็นเวลาปัจจุบันก่อน)
-	originalSession := m.Session{}
-	db := database.DBConn
-	userID := uint(claims["UserID"].(float64))
-	if err := db.Where("user_id = ?", userID).First(&originalSession).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Could not retrieve session data.",
-		})
-	}
-
-	// update LastActive เมื่อเริ่มส่ง request
-	if err := session.UpdateSessionActivity(userID); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Could not update session activity.",
-		})
-	}
-
-	// หลังจาก update ค่า LastActive ก็สร้างตัวแปรมาเก็บค่าเวลาตอนที่ส่ง request
-	dbSession := m.Session{}
-	if err := db.Where("user_id = ?", userID).First(&dbSession).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Could not retrieve session data.",
-		})
-	}
-
-	// ตรวจสอบว่า session หมดเวลาแล้วหรือยัง
-	sessionTimeout := time.Minute * 10
-	lastActive := dbSession.LastActive
-	originalTime := originalSession.LastActive
-	timeDifference := lastActive.Sub(originalTime)
-
-	if timeDifference > sessionTimeout {
-		// ลบ session ออกจากฐานข้อมูล
-		if err := db.Delete(&dbSession).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"message": "Could not delete expired session.",
-			})
-		}
-
-		// แจ้งเตือนให้ผู้ใช้ login ใหม่
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "Session expired, Please login again.",
-		})
-	}
-
-	// update LastActive หลังจากตรวจสอบ session timeout
-	dbSession.LastActive = time.Now()
-	if err := db.Save(&dbSession).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Could not update session activity.",
-		})
-	}
-
-	return c.Next()
-}
-
-func RoleRequired(requiredRole string) fiber.Handler {
+func AuthRequired(cfg config.AuthConfig, sessions store.SessionStore) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		// ดึง Authorization header
 		tokenString := c.Get("Authorization")
 
 		if tokenString == "" {
-			return c.Status(fiber.StatusUnauthorized).SendString("Authorization header missing.")
+			return apperr.Unauthorized("Authorization header missing.")
 		}
 
-		tokenString = tokenString[len("Bearer "):]
+		// ตัด Bearer ออกให้เหลือแค่ token ถ้าเป็น scheme อื่นหรือสั้นกว่า prefix ถือว่าไม่ได้ส่ง token มา
+		tokenString, ok := strings.CutPrefix(tokenString, "Bearer ")
+		if !ok {
+			return apperr.Unauthorized("Authorization header must use the Bearer scheme.")
+		}
 
-		// parse token เพื่ออ่าน token และดึง ACCESS_SECRET_KEY จาก env มาตรวจสอบว่า token ถูกต้องไหม
+		// parse token เพื่ออ่าน token และใช้ access secret จาก config มาตรวจสอบว่า token ถูกต้องไหม
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 			// ตรวจสอบ signing method (for security)
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid token signing method.")
 			}
-			return []byte(os.Getenv("ACCESS_SECRET_KEY")), nil
+			return []byte(cfg.AccessSecret), nil
 		})
 
 		// ตรวจสอบ token ที่ถูก parse ว่าถูกต้องหรือ (หมดอายุหรือถูกดัดแปลงไหม)
 		if err != nil || !token.Valid {
-			return c.Status(fiber.StatusUnauthorized).SendString("Invalid or expired token")
+			return apperr.Unauthorized("Invalid or expired token").WithCode(apperr.CodeInvalidToken)
 		}
 
-		// ดึง Claims จาก JWT
+		// ดึง cliams จาก JWT
 		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok || !token.Valid {
-			return c.Status(fiber.StatusUnauthorized).SendString("Invalid token claims.")
+		if !ok {
+			return apperr.Unauthorized("Invalid token claims.").WithCode(apperr.CodeInvalidToken)
+		}
+
+		// เซ็ต claims ลงใน context เพื่อใช้งานใน controller
+		c.Locals("user", claims)
+
+		// ดึง session เดิมเพื่อดูเวลาที่ส่ง request ครั้งล่าสุด (ครั้งแรกที่ login จะเป็นเวลาตอน login)
+		userID := uint(claims["UserID"].(float64))
+		dbSession, err := sessions.Get(userID)
+		if errors.Is(err, store.ErrNotFound) {
+			// ไม่มี session แปลว่า logout ไปแล้วหรือ session หมดอายุและถูกลบไปแล้ว
+			return apperr.Unauthorized("Session not found, Please login again.").WithCode(apperr.CodeSessionExpired)
+		}
+		if err != nil {
+			return apperr.Internal("Could not retrieve session data.", err)
+		}
+
+		// ตรวจสอบว่า session หมดเวลาแล้วหรือยัง
+		now := time.Now()
+		timeDifference := now.Sub(dbSession.LastActive)
+
+		if timeDifference > cfg.SessionTimeout {
+			// ลบ session ออกจากฐานข้อมูล
+			if err := sessions.Delete(userID); err != nil {
+				return apperr.Internal("Could not delete expired session.", err)
+			}
+
+			// แจ้งเตือนให้ผู้ใช้ login ใหม่
+			return apperr.Unauthorized("Session expired, Please login again.").WithCode(apperr.CodeSessionExpired)
+		}
+
+		// update LastActive หลังจากตรวจสอบ session timeout
+		if err := sessions.Touch(userID, now); err != nil {
+			return apperr.Internal("Could not update session activity.", err)
+		}
+
+		return c.Next()
+	}
+}
+
+func RoleRequired(requiredRole string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// ดึง Claims ที่ถูกเซ็ตไว้ใน AuthRequired
+		claims, ok := c.Locals("user").(jwt.MapClaims)
+		if !ok {
+			return apperr.Unauthorized("Invalid token claims.").WithCode(apperr.CodeInvalidToken)
 		}
 
 		// ดึง Role จาก Claims และตรวจสอบว่าตรงกับ requiredRole หรือไม่
-		userRole := claims["Role"].(string)
+		userRole, _ := claims["Role"].(string)
 		if userRole != requiredRole {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"message": "Access denied for this role.",
-			})
+			return apperr.Forbidden("Access denied for this role.")
 		}
 
 		return c.Next()
 	}
 }
+
+// RateLimiter จำกัดจำนวนคำขอต่อช่วงเวลาตามค่าตั้งค่า ยกเว้น request ที่ skip คืน true (เช่น health probe)
+// skip เป็น nil ได้ถ้าไม่มี request ไหนได้รับการยกเว้น
+func RateLimiter(cfg config.RateLimitConfig, skip func(c *fiber.Ctx) bool) fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:        cfg.Max,
+		Expiration: cfg.Expiration,
+		Next:       skip,
+		LimitReached: func(c *fiber.Ctx) error {
+			return apperr.New(fiber.StatusTooManyRequests, apperr.CodeTooManyRequests, "Too many requests, please try again later.")
+		},
+	})
+}
@@ -0,0 +1,94 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// struct ในไฟล์นี้เป็นสำเนาของ models ณ เวลาที่สร้าง migration
+// ห้ามแก้ไขตาม models ในภายหลัง ให้เพิ่ม migration ใหม่แทน
+
+type productImage0001 struct {
+	gorm.Model
+	ProductID uint
+	ImageURL  string
+}
+
+func (productImage0001) TableName() string { return "product_images" }
+
+type product0001 struct {
+	gorm.Model
+	Product_Name string
+	Price        int
+	Amount       int
+	Images       []productImage0001 `gorm:"foreignKey:ProductID"`
+}
+
+func (product0001) TableName() string { return "products" }
+
+type user0001 struct {
+	gorm.Model
+	Username  string
+	Password  string
+	FirstName string
+	LastName  string
+	Role      string
+	Approve   bool
+}
+
+func (user0001) TableName() string { return "users" }
+
+type item0001 struct {
+	gorm.Model
+	Product string
+	Amount  int
+	OrderID uint
+}
+
+func (item0001) TableName() string { return "items" }
+
+type order0001 struct {
+	gorm.Model
+	Buyer       string
+	Items       []item0001 `gorm:"foreignKey:OrderID"`
+	Total_Price int
+}
+
+func (order0001) TableName() string { return "orders" }
+
+type session0001 struct {
+	UserID     uint `gorm:"primaryKey"`
+	LastActive time.Time
+}
+
+func (session0001) TableName() string { return "sessions" }
+
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "initial_schema",
+		// ใช้ AutoMigrate กับ struct ที่ถูก freeze ไว้ เพื่อให้ฐานข้อมูลเดิมที่เคยถูก AutoMigrate ตอน boot
+		// ถูกนับเป็น baseline ได้โดยไม่ error ว่าตารางมีอยู่แล้ว
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AutoMigrate(
+				&product0001{},
+				&productImage0001{},
+				&user0001{},
+				&order0001{},
+				&item0001{},
+				&session0001{},
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(
+				&session0001{},
+				&item0001{},
+				&order0001{},
+				&user0001{},
+				&productImage0001{},
+				&product0001{},
+			)
+		},
+	})
+}
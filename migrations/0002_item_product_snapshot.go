@@ -0,0 +1,65 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// item0002 เพิ่ม ProductID และราคา ณ เวลาที่สั่งซื้อให้กับ item ซึ่งเดิมอ้างอิงสินค้าด้วยชื่ออย่างเดียว
+type item0002 struct {
+	gorm.Model
+	ProductID  uint `gorm:"index"`
+	Product    string
+	Unit_Price int
+	Amount     int
+	Line_Total int
+	OrderID    uint
+}
+
+func (item0002) TableName() string { return "items" }
+
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "item_product_snapshot",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AutoMigrate(&item0002{}); err != nil {
+				return err
+			}
+			return backfillItems0002(tx)
+		},
+		Down: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			if migrator.HasIndex(&item0002{}, "ProductID") {
+				if err := migrator.DropIndex(&item0002{}, "ProductID"); err != nil {
+					return err
+				}
+			}
+			for _, column := range []string{"ProductID", "Unit_Price", "Line_Total"} {
+				if err := migrator.DropColumn(&item0002{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
+
+// pick0002 เลือก column ของสินค้าที่ชื่อตรงกับ item ถ้ามีหลายสินค้าชื่อซ้ำกัน ให้ใช้ตัวที่ยังไม่ถูกลบและใหม่ที่สุด
+const pick0002 = `(SELECT products.%s FROM products WHERE products.product_name = items.product
+	ORDER BY CASE WHEN products.deleted_at IS NULL THEN 0 ELSE 1 END, products.id DESC LIMIT 1)`
+
+// backfillItems0002 ผูก item เดิมเข้ากับสินค้าที่มีชื่อตรงกัน (รวมสินค้าที่ถูก soft delete)
+// ราคาตอนสั่งซื้อจริงไม่ได้ถูกบันทึกไว้ จึงใช้ราคาปัจจุบันของสินค้าแทน ส่วน Total_Price ของ order
+// ไม่ถูกแก้เพราะเป็นยอดที่ถูกคิดไปแล้วจริง item ที่หาสินค้าไม่เจอจะมี ProductID และราคาเป็น 0
+// ทำใน UPDATE เดียวให้ฐานข้อมูลจับคู่เอง เพื่อไม่ต้องโหลดสินค้าและ item ทั้งหมดขึ้นมาใน memory
+func backfillItems0002(tx *gorm.DB) error {
+	price := "COALESCE(" + fmt.Sprintf(pick0002, "price") + ", 0)"
+	return tx.Unscoped().Model(&item0002{}).Where("product_id IS NULL").Updates(map[string]any{
+		"product_id": gorm.Expr("COALESCE(" + fmt.Sprintf(pick0002, "id") + ", 0)"),
+		"unit_price": gorm.Expr(price),
+		// คิดจากราคาโดยตรงแทน unit_price เพราะ mysql ใช้ค่าใหม่ของ column ที่ SET ไปก่อนหน้าใน UPDATE เดียวกัน
+		"line_total": gorm.Expr(price + " * items.amount"),
+	}).Error
+}
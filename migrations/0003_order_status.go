@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// order0003 เพิ่มสถานะของ order และเวลาที่เปลี่ยนเข้าสู่แต่ละสถานะ
+type order0003 struct {
+	gorm.Model
+	Buyer        string
+	Total_Price  int
+	Status       string `gorm:"size:20;index"`
+	PaidAt       *time.Time
+	ProcessingAt *time.Time
+	ShippedAt    *time.Time
+	DeliveredAt  *time.Time
+	CancelledAt  *time.Time
+	RefundedAt   *time.Time
+}
+
+func (order0003) TableName() string { return "orders" }
+
+var orderStatusColumns0003 = []string{
+	"Status", "PaidAt", "ProcessingAt", "ShippedAt", "DeliveredAt", "CancelledAt", "RefundedAt",
+}
+
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "order_status",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AutoMigrate(&order0003{}); err != nil {
+				return err
+			}
+			// order ที่มีอยู่ก่อนยังไม่ได้ชำระเงิน จึงนับเป็น pending ซึ่งยังแก้ไขและยกเลิกได้เหมือนเดิม
+			return tx.Unscoped().Model(&order0003{}).
+				Where("status IS NULL OR status = ''").
+				Update("status", "pending").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			if migrator.HasIndex(&order0003{}, "Status") {
+				if err := migrator.DropIndex(&order0003{}, "Status"); err != nil {
+					return err
+				}
+			}
+			for _, column := range orderStatusColumns0003 {
+				if err := migrator.DropColumn(&order0003{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
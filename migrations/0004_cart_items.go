@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type cartItem0004 struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uint `gorm:"uniqueIndex:idx_cart_items_user_product"`
+	ProductID uint `gorm:"uniqueIndex:idx_cart_items_user_product"`
+	Amount    int
+}
+
+func (cartItem0004) TableName() string { return "cart_items" }
+
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "cart_items",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AutoMigrate(&cartItem0004{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&cartItem0004{})
+		},
+	})
+}
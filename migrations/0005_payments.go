@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type payment0005 struct {
+	gorm.Model
+	OrderID     uint   `gorm:"index"`
+	Provider    string `gorm:"size:50"`
+	ProviderRef string `gorm:"size:255;uniqueIndex"`
+	Amount      int
+	Currency    string `gorm:"size:3"`
+	Status      string `gorm:"size:20"`
+}
+
+func (payment0005) TableName() string { return "payments" }
+
+// order0005 เพิ่มเวลาที่การชำระเงินของ order ล้มเหลว
+type order0005 struct {
+	gorm.Model
+	FailedAt *time.Time
+}
+
+func (order0005) TableName() string { return "orders" }
+
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "payments",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&order0005{}, "FailedAt"); err != nil {
+				return err
+			}
+			return tx.Migrator().AutoMigrate(&payment0005{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&payment0005{}); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&order0005{}, "FailedAt")
+		},
+	})
+}
@@ -0,0 +1,72 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type coupon0006 struct {
+	gorm.Model
+	Code         string `gorm:"size:64;uniqueIndex"`
+	Type         string `gorm:"size:10"`
+	Value        int
+	MinOrder     int
+	ProductID    *uint `gorm:"index"`
+	StartsAt     *time.Time
+	EndsAt       *time.Time
+	UsageLimit   int
+	PerUserLimit int
+	Used         int
+}
+
+func (coupon0006) TableName() string { return "coupons" }
+
+type couponRedemption0006 struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	CouponID  uint `gorm:"index"`
+	UserID    uint `gorm:"index"`
+	OrderID   uint `gorm:"uniqueIndex"`
+}
+
+func (couponRedemption0006) TableName() string { return "coupon_redemptions" }
+
+// order0006 เพิ่ม coupon ที่ใช้และส่วนลดที่ได้ให้กับ order
+type order0006 struct {
+	gorm.Model
+	CouponID   *uint
+	CouponCode string `gorm:"size:64"`
+	Discount   int
+}
+
+func (order0006) TableName() string { return "orders" }
+
+var orderCouponColumns0006 = []string{"CouponID", "CouponCode", "Discount"}
+
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "coupons",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AutoMigrate(&coupon0006{}, &couponRedemption0006{}); err != nil {
+				return err
+			}
+			for _, column := range orderCouponColumns0006 {
+				if err := tx.Migrator().AddColumn(&order0006{}, column); err != nil {
+					return err
+				}
+			}
+			// order เดิมไม่มีส่วนลด
+			return tx.Unscoped().Model(&order0006{}).Where("discount IS NULL").Update("discount", 0).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, column := range orderCouponColumns0006 {
+				if err := tx.Migrator().DropColumn(&order0006{}, column); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().DropTable(&couponRedemption0006{}, &coupon0006{})
+		},
+	})
+}
@@ -0,0 +1,78 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// product0007 และ item0007 เพิ่มน้ำหนักต่อชิ้นสำหรับคิดค่าส่งตามน้ำหนัก
+type product0007 struct {
+	gorm.Model
+	Weight int
+}
+
+func (product0007) TableName() string { return "products" }
+
+type item0007 struct {
+	gorm.Model
+	Weight int
+}
+
+func (item0007) TableName() string { return "items" }
+
+// order0007 แยกยอดสินค้า ค่าส่ง และภาษีออกจากยอดสุทธิ
+type order0007 struct {
+	gorm.Model
+	Subtotal int
+	Shipping int
+	Tax      int
+}
+
+func (order0007) TableName() string { return "orders" }
+
+var orderTotalColumns0007 = []string{"Subtotal", "Shipping", "Tax"}
+
+func init() {
+	register(Migration{
+		Version: 7,
+		Name:    "order_totals",
+		Up: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			if err := migrator.AddColumn(&product0007{}, "Weight"); err != nil {
+				return err
+			}
+			if err := migrator.AddColumn(&item0007{}, "Weight"); err != nil {
+				return err
+			}
+			for _, column := range orderTotalColumns0007 {
+				if err := migrator.AddColumn(&order0007{}, column); err != nil {
+					return err
+				}
+			}
+
+			if err := tx.Unscoped().Model(&product0007{}).Where("weight IS NULL").Update("weight", 0).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Model(&item0007{}).Where("weight IS NULL").Update("weight", 0).Error; err != nil {
+				return err
+			}
+			// order เดิมไม่มีค่าส่งและภาษี ยอดสินค้าจึงเท่ากับยอดสุทธิบวกส่วนลดกลับ
+			return tx.Unscoped().Model(&order0007{}).Where("subtotal IS NULL").Updates(map[string]any{
+				"subtotal": gorm.Expr("total_price + discount"),
+				"shipping": 0,
+				"tax":      0,
+			}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			for _, column := range orderTotalColumns0007 {
+				if err := migrator.DropColumn(&order0007{}, column); err != nil {
+					return err
+				}
+			}
+			if err := migrator.DropColumn(&item0007{}, "Weight"); err != nil {
+				return err
+			}
+			return migrator.DropColumn(&product0007{}, "Weight")
+		},
+	})
+}
@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type invoice0008 struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	OrderID   uint `gorm:"uniqueIndex"`
+}
+
+func (invoice0008) TableName() string { return "invoices" }
+
+func init() {
+	register(Migration{
+		Version: 8,
+		Name:    "invoices",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AutoMigrate(&invoice0008{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&invoice0008{})
+		},
+	})
+}
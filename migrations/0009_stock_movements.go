@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type stockMovement0009 struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	ProductID uint `gorm:"index"`
+	Delta     int
+	Balance   int
+	Reason    string `gorm:"size:30"`
+	ActorID   *uint
+	Reference string `gorm:"size:100;index"`
+	Note      string `gorm:"size:255"`
+}
+
+func (stockMovement0009) TableName() string { return "stock_movements" }
+
+type product0009 struct {
+	gorm.Model
+	Amount int
+}
+
+func (product0009) TableName() string { return "products" }
+
+func init() {
+	register(Migration{
+		Version: 9,
+		Name:    "stock_movements",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AutoMigrate(&stockMovement0009{}); err != nil {
+				return err
+			}
+
+			// สินค้าที่มีอยู่ก่อน ledger ได้ยอดยกมาหนึ่งแถว ผลรวมใน ledger จึงเท่ากับ amount ตั้งแต่เริ่ม
+			var products []product0009
+			if err := tx.Unscoped().Where("amount <> 0").Find(&products).Error; err != nil {
+				return err
+			}
+			now := time.Now()
+			for _, product := range products {
+				opening := stockMovement0009{
+					CreatedAt: now,
+					ProductID: product.ID,
+					Delta:     product.Amount,
+					Balance:   product.Amount,
+					Reason:    "initial",
+					Note:      "Opening balance",
+				}
+				if err := tx.Create(&opening).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&stockMovement0009{})
+		},
+	})
+}
@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// product0010 แยกจำนวนที่ถูกกันไว้ให้ order ที่ยังไม่ยืนยันออกจากจำนวนในคลัง
+type product0010 struct {
+	gorm.Model
+	Reserved int
+}
+
+func (product0010) TableName() string { return "products" }
+
+// order0010 เก็บเวลาที่การกันสินค้าของ order หมดอายุ order เดิมถูกตัดสต็อกไปแล้วจึงเป็น NULL
+type order0010 struct {
+	gorm.Model
+	ReservedUntil *time.Time `gorm:"index"`
+}
+
+func (order0010) TableName() string { return "orders" }
+
+func init() {
+	register(Migration{
+		Version: 10,
+		Name:    "stock_reservations",
+		Up: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			if err := migrator.AddColumn(&product0010{}, "Reserved"); err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Model(&product0010{}).Where("reserved IS NULL").Update("reserved", 0).Error; err != nil {
+				return err
+			}
+			if err := migrator.AddColumn(&order0010{}, "ReservedUntil"); err != nil {
+				return err
+			}
+			return migrator.CreateIndex(&order0010{}, "ReservedUntil")
+		},
+		Down: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			if err := migrator.DropIndex(&order0010{}, "ReservedUntil"); err != nil {
+				return err
+			}
+			if err := migrator.DropColumn(&order0010{}, "ReservedUntil"); err != nil {
+				return err
+			}
+			return migrator.DropColumn(&product0010{}, "Reserved")
+		},
+	})
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type idempotencyKey0011 struct {
+	ID          uint `gorm:"primaryKey"`
+	CreatedAt   time.Time
+	UserID      uint   `gorm:"uniqueIndex:idx_idempotency_user_key"`
+	Key         string `gorm:"column:idempotency_key;size:255;uniqueIndex:idx_idempotency_user_key"`
+	Fingerprint string `gorm:"size:64"`
+	Completed   bool
+	Status      int
+	ContentType string `gorm:"size:100"`
+	Body        []byte
+	ExpiresAt   time.Time `gorm:"index"`
+}
+
+func (idempotencyKey0011) TableName() string { return "idempotency_keys" }
+
+func init() {
+	register(Migration{
+		Version: 11,
+		Name:    "idempotency_keys",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AutoMigrate(&idempotencyKey0011{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&idempotencyKey0011{})
+		},
+	})
+}
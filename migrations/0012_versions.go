@@ -0,0 +1,61 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// product0012, order0012 และ user0012 เพิ่ม version ที่ใช้ตรวจการแก้ไขซ้อนกัน (ETag/If-Match)
+// แถวเดิมได้ version 1 จาก default ของ column
+type product0012 struct {
+	gorm.Model
+	Version int `gorm:"not null;default:1"`
+}
+
+func (product0012) TableName() string { return "products" }
+
+// ReservedUntil มีไว้สร้าง index ของ 0010 คืนใน Down เท่านั้น
+type order0012 struct {
+	gorm.Model
+	Version       int        `gorm:"not null;default:1"`
+	ReservedUntil *time.Time `gorm:"index"`
+}
+
+func (order0012) TableName() string { return "orders" }
+
+type user0012 struct {
+	gorm.Model
+	Version int `gorm:"not null;default:1"`
+}
+
+func (user0012) TableName() string { return "users" }
+
+func init() {
+	register(Migration{
+		Version: 12,
+		Name:    "versions",
+		Up: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			for _, model := range []any{&product0012{}, &order0012{}, &user0012{}} {
+				if err := migrator.AddColumn(model, "Version"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			for _, model := range []any{&user0012{}, &order0012{}, &product0012{}} {
+				if err := migrator.DropColumn(model, "Version"); err != nil {
+					return err
+				}
+			}
+			// sqlite drop column ด้วยการสร้างตารางใหม่ซึ่งไม่มี index เดิม จึงสร้าง index ที่ Down ของ 0010 ต้องใช้คืนให้
+			if !migrator.HasIndex(&order0012{}, "ReservedUntil") {
+				return migrator.CreateIndex(&order0012{}, "ReservedUntil")
+			}
+			return nil
+		},
+	})
+}
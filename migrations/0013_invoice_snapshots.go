@@ -0,0 +1,96 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// invoice0013 เก็บยอดเงินของ order ไว้กับใบแจ้งหนี้ตอนออก เอกสารจึงไม่เปลี่ยนตาม order ที่ถูกแก้ภายหลัง
+type invoice0013 struct {
+	ID          uint `gorm:"primaryKey"`
+	OrderID     uint `gorm:"uniqueIndex"`
+	Buyer       string
+	OrderedAt   time.Time
+	PaidAt      *time.Time
+	Subtotal    int
+	CouponCode  string `gorm:"size:64"`
+	Discount    int
+	Shipping    int
+	Tax         int
+	Total_Price int
+}
+
+func (invoice0013) TableName() string { return "invoices" }
+
+// invoiceLine0013 คือรายการสินค้าที่คัดลอกจาก order ตอนออกใบแจ้งหนี้
+type invoiceLine0013 struct {
+	ID         uint `gorm:"primaryKey"`
+	InvoiceID  uint `gorm:"index"`
+	Product    string
+	Unit_Price int
+	Amount     int
+	Line_Total int
+}
+
+func (invoiceLine0013) TableName() string { return "invoice_lines" }
+
+var invoiceColumns0013 = []string{"Buyer", "OrderedAt", "PaidAt", "Subtotal", "CouponCode", "Discount", "Shipping", "Tax", "Total_Price"}
+
+func init() {
+	register(Migration{
+		Version: 13,
+		Name:    "invoice_snapshots",
+		Up: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			for _, column := range invoiceColumns0013 {
+				if err := migrator.AddColumn(&invoice0013{}, column); err != nil {
+					return err
+				}
+			}
+			if err := migrator.CreateTable(&invoiceLine0013{}); err != nil {
+				return err
+			}
+
+			// ใบแจ้งหนี้ที่ออกไปแล้วไม่มีสำเนา จึงคัดลอกจาก order ตอนนี้ซึ่งใกล้เคียงที่สุดที่มี
+			fromOrder := func(column string) any {
+				return gorm.Expr("(SELECT " + column + " FROM orders WHERE orders.id = invoices.order_id)")
+			}
+			err := tx.Model(&invoice0013{}).Where("subtotal IS NULL").Updates(map[string]any{
+				"buyer":       fromOrder("buyer"),
+				"ordered_at":  fromOrder("created_at"),
+				"paid_at":     fromOrder("paid_at"),
+				"subtotal":    fromOrder("subtotal"),
+				"coupon_code": fromOrder("coupon_code"),
+				"discount":    fromOrder("discount"),
+				"shipping":    fromOrder("shipping"),
+				"tax":         fromOrder("tax"),
+				"total_price": fromOrder("total_price"),
+			}).Error
+			if err != nil {
+				return err
+			}
+			return tx.Exec(`INSERT INTO invoice_lines (invoice_id, product, unit_price, amount, line_total)
+				SELECT invoices.id, items.product, items.unit_price, items.amount, items.line_total
+				FROM invoices JOIN items ON items.order_id = invoices.order_id
+				WHERE items.deleted_at IS NULL
+				ORDER BY invoices.id, items.id`).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			if err := migrator.DropTable(&invoiceLine0013{}); err != nil {
+				return err
+			}
+			for _, column := range invoiceColumns0013 {
+				if err := migrator.DropColumn(&invoice0013{}, column); err != nil {
+					return err
+				}
+			}
+			// sqlite drop column ด้วยการสร้างตารางใหม่ซึ่งไม่มี index เดิม จึงสร้าง unique index ของ 0008 คืนให้
+			if !migrator.HasIndex(&invoice0013{}, "OrderID") {
+				return migrator.CreateIndex(&invoice0013{}, "OrderID")
+			}
+			return nil
+		},
+	})
+}
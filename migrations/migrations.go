@@ -0,0 +1,161 @@
+package migrations
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration คือการเปลี่ยนแปลง schema หนึ่งครั้ง แต่ละไฟล์ในแพ็กเกจนี้ลงทะเบียนไว้ผ่าน register
+// โดยตั้งชื่อไฟล์ตาม Version เช่น 0001_initial_schema.go
+type Migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration คือแถวในตาราง schema_migrations ที่ใช้ติดตามว่า migration ไหนถูก apply แล้ว
+type SchemaMigration struct {
+	Version   uint `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// Status คือสถานะของ migration แต่ละตัวสำหรับคำสั่ง migrate status
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+var registry []Migration
+
+// register ถูกเรียกจาก init() ของไฟล์ migration แต่ละไฟล์
+func register(m Migration) {
+	for _, existing := range registry {
+		if existing.Version == m.Version {
+			panic(fmt.Sprintf("migrations: duplicate version %04d (%s and %s)", m.Version, existing.Name, m.Name))
+		}
+	}
+	registry = append(registry, m)
+	sort.Slice(registry, func(i, j int) bool { return registry[i].Version < registry[j].Version })
+}
+
+// All คืน migration ทั้งหมดเรียงตาม Version
+func All() []Migration {
+	return append([]Migration(nil), registry...)
+}
+
+func ensureTable(db *gorm.DB) error {
+	return db.AutoMigrate(&SchemaMigration{})
+}
+
+func applied(db *gorm.DB) (map[uint]SchemaMigration, error) {
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+
+	var rows []SchemaMigration
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint]SchemaMigration, len(rows))
+	for _, row := range rows {
+		result[row.Version] = row
+	}
+	return result, nil
+}
+
+// Pending คืน migration ที่ยังไม่ถูก apply
+func Pending(db *gorm.DB) ([]Migration, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, m := range registry {
+		if _, ok := done[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Up apply migration ที่ค้างอยู่ทั้งหมดตามลำดับ แต่ละตัวอยู่ใน transaction ของตัวเอง
+func Up(db *gorm.DB) ([]Migration, error) {
+	pending, err := Pending(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range pending {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Version:   m.Version,
+				Name:      m.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migrations: %04d_%s up: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Down ย้อน migration ล่าสุดที่ถูก apply ไปแล้วตามจำนวน steps
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(registry) - 1; i >= 0 && len(reverted) < steps; i-- {
+		m := registry[i]
+		if _, ok := done[m.Version]; !ok {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("migrations: %04d_%s down: %w", m.Version, m.Name, err)
+		}
+		reverted = append(reverted, m)
+	}
+	return reverted, nil
+}
+
+// StatusOf คืนสถานะของ migration ทุกตัว
+func StatusOf(db *gorm.DB) ([]Status, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(registry))
+	for _, m := range registry {
+		row, ok := done[m.Version]
+		statuses = append(statuses, Status{
+			Migration: m,
+			Applied:   ok,
+			AppliedAt: row.AppliedAt,
+		})
+	}
+	return statuses, nil
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ชนิดของส่วนลด
+const (
+	// CouponPercent ลดเป็นเปอร์เซ็นต์ของยอดสินค้าที่อยู่ในขอบเขตของ coupon
+	CouponPercent = "percent"
+	// CouponFixed ลดเป็นจำนวนเงินคงที่ แต่ไม่เกินยอดสินค้าที่อยู่ในขอบเขตของ coupon
+	CouponFixed = "fixed"
+)
+
+var (
+	ErrCouponNotStarted = errors.New("models: coupon is not active yet")
+	ErrCouponExpired    = errors.New("models: coupon has expired")
+)
+
+// Coupon คือโค้ดส่วนลด ถ้า ProductID เป็น nil จะลดจากทุกรายการใน order
+// UsageLimit และ PerUserLimit เป็น 0 คือไม่จำกัด ส่วน Used คือจำนวนครั้งที่ถูกใช้ไปแล้วทั้งหมด
+type Coupon struct {
+	gorm.Model
+	Code         string     `gorm:"size:64;uniqueIndex" json:"Code"`
+	Type         string     `gorm:"size:10" json:"Type"`
+	Value        int        `json:"Value"`
+	MinOrder     int        `json:"MinOrder"`
+	ProductID    *uint      `gorm:"index" json:"ProductID"`
+	StartsAt     *time.Time `json:"StartsAt"`
+	EndsAt       *time.Time `json:"EndsAt"`
+	UsageLimit   int        `json:"UsageLimit"`
+	PerUserLimit int        `json:"PerUserLimit"`
+	Used         int        `json:"Used"`
+}
+
+// CouponRedemption คือการใช้ coupon หนึ่งครั้งกับ order หนึ่ง ใช้นับจำนวนครั้งที่ user แต่ละคนใช้
+type CouponRedemption struct {
+	ID        uint      `gorm:"primaryKey" json:"ID"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	CouponID  uint      `gorm:"index" json:"CouponID"`
+	UserID    uint      `gorm:"index" json:"UserID"`
+	OrderID   uint      `gorm:"uniqueIndex" json:"OrderID"`
+}
+
+// ActiveAt ตรวจว่า coupon อยู่ในช่วงวันที่ใช้งานได้ ณ เวลา now (StartsAt รวมขอบเขต EndsAt ไม่รวม)
+func (c *Coupon) ActiveAt(now time.Time) error {
+	if c.StartsAt != nil && now.Before(*c.StartsAt) {
+		return ErrCouponNotStarted
+	}
+	if c.EndsAt != nil && !now.Before(*c.EndsAt) {
+		return ErrCouponExpired
+	}
+	return nil
+}
+
+// DiscountFor คำนวณส่วนลดจากรายการที่อยู่ในขอบเขตของ coupon คืน 0 ถ้าไม่มีรายการใดอยู่ในขอบเขต
+func (c *Coupon) DiscountFor(items []Item) int {
+	eligible := 0
+	for _, item := range items {
+		if c.ProductID == nil || *c.ProductID == item.ProductID {
+			eligible += item.Line_Total
+		}
+	}
+
+	switch c.Type {
+	case CouponPercent:
+		return eligible * c.Value / 100
+	case CouponFixed:
+		return min(c.Value, eligible)
+	}
+	return 0
+}
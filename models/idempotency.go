@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// IdempotencyKey คือ response แรกของ request ที่ส่ง Idempotency-Key มา ถูกเก็บไว้ตอบซ้ำเมื่อ client ส่ง request เดิมซ้ำ
+// คีย์ไม่ซ้ำกันภายใน user คนเดียว
+type IdempotencyKey struct {
+	ID        uint      `gorm:"primaryKey" json:"ID"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	UserID    uint      `gorm:"uniqueIndex:idx_idempotency_user_key" json:"UserID"`
+	Key       string    `gorm:"column:idempotency_key;size:255;uniqueIndex:idx_idempotency_user_key" json:"Key"`
+	// Fingerprint คือ hash ของ method, path และ body ใช้ตรวจว่า request ที่ใช้คีย์เดิมเป็น request เดียวกัน
+	Fingerprint string `gorm:"size:64" json:"Fingerprint"`
+	// Completed เป็น false ระหว่างที่ request แรกยังทำงานอยู่
+	Completed   bool      `json:"Completed"`
+	Status      int       `json:"Status"`
+	ContentType string    `gorm:"size:100" json:"ContentType"`
+	Body        []byte    `json:"Body"`
+	ExpiresAt   time.Time `gorm:"index" json:"ExpiresAt"`
+}
+
+// Expired บอกว่า record นี้หมดอายุแล้ว ณ เวลา now และใช้คีย์เดิมกับ request ใหม่ได้
+func (k *IdempotencyKey) Expired(now time.Time) bool {
+	return !now.Before(k.ExpiresAt)
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Invoice คือใบแจ้งหนี้ของ order ที่ชำระเงินแล้ว ซึ่งออกให้ครั้งเดียวตอนที่ถูกขอครั้งแรก
+// เลขที่ใบแจ้งหนี้เรียงตาม ID ที่ฐานข้อมูลสร้างให้ จึงเรียงตามลำดับที่ออกเสมอ
+// ยอดเงินและรายการสินค้าถูกคัดลอกจาก order ตอนออก เอกสารจึงไม่เปลี่ยนตาม order ที่ถูกแก้หรือเปลี่ยนสถานะภายหลัง
+type Invoice struct {
+	ID          uint          `gorm:"primaryKey" json:"ID"`
+	CreatedAt   time.Time     `json:"CreatedAt"`
+	OrderID     uint          `gorm:"uniqueIndex" json:"OrderID"`
+	Buyer       string        `json:"Buyer"`
+	OrderedAt   time.Time     `json:"OrderedAt"`
+	PaidAt      *time.Time    `json:"PaidAt"`
+	Subtotal    int           `json:"Subtotal"`
+	CouponCode  string        `gorm:"size:64" json:"CouponCode"`
+	Discount    int           `json:"Discount"`
+	Shipping    int           `json:"Shipping"`
+	Tax         int           `json:"Tax"`
+	Total_Price int           `json:"Total Price"`
+	Lines       []InvoiceLine `gorm:"foreignKey:InvoiceID" json:"Lines"`
+}
+
+// InvoiceLine คือรายการสินค้าหนึ่งบรรทัดในใบแจ้งหนี้ตามที่อยู่ใน order ตอนออก
+type InvoiceLine struct {
+	ID         uint   `gorm:"primaryKey" json:"ID"`
+	InvoiceID  uint   `gorm:"index" json:"InvoiceID"`
+	Product    string `json:"Product"`
+	Unit_Price int    `json:"Unit Price"`
+	Amount     int    `json:"Amount"`
+	Line_Total int    `json:"Line Total"`
+}
+
+// NewInvoice คัดลอกยอดเงินและรายการสินค้าปัจจุบันของ order เป็นใบแจ้งหนี้ที่ยังไม่มีเลขที่
+func NewInvoice(order *Order) *Invoice {
+	invoice := &Invoice{
+		OrderID:     order.ID,
+		Buyer:       order.Buyer,
+		OrderedAt:   order.CreatedAt,
+		PaidAt:      order.PaidAt,
+		Subtotal:    order.Subtotal,
+		CouponCode:  order.CouponCode,
+		Discount:    order.Discount,
+		Shipping:    order.Shipping,
+		Tax:         order.Tax,
+		Total_Price: order.Total_Price,
+	}
+	for _, item := range order.Items {
+		invoice.Lines = append(invoice.Lines, InvoiceLine{
+			Product:    item.Product,
+			Unit_Price: item.Unit_Price,
+			Amount:     item.Amount,
+			Line_Total: item.Line_Total,
+		})
+	}
+	return invoice
+}
+
+// Number คือเลขที่ใบแจ้งหนี้ที่แสดงในเอกสาร
+func (i *Invoice) Number() string {
+	return fmt.Sprintf("INV-%06d", i.ID)
+}
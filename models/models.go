@@ -14,32 +14,135 @@ type ProductImage struct {
 
 type Product struct {
 	gorm.Model
-	Product_Name string         `json:"Product_Name"`
-	Price        int            `json:"Price"`
-	Amount       int            `json:"Amount"`
-	Images       []ProductImage `gorm:"foreignKey:ProductID" json:"Images"`
+	// Version เพิ่มขึ้นทุกครั้งที่บันทึกผ่าน store.Save ใช้ตรวจว่ามีคนแก้ไปก่อนหรือไม่ (optimistic concurrency)
+	Version      int    `gorm:"not null;default:1" json:"Version"`
+	Product_Name string `json:"Product_Name"`
+	Price        int    `json:"Price"`
+	// Amount คือจำนวนสินค้าที่มีอยู่จริงในคลัง (on-hand)
+	Amount int `json:"Amount"`
+	// Reserved คือจำนวนที่ถูกกันไว้ให้ order ที่ยังไม่ยืนยัน ซึ่งยังอยู่ในคลังแต่ขายให้คนอื่นไม่ได้
+	Reserved int `json:"Reserved"`
+	// Weight คือน้ำหนักต่อชิ้นเป็นกรัม ใช้คิดค่าส่งแบบตามน้ำหนัก
+	Weight int            `json:"Weight"`
+	Images []ProductImage `gorm:"foreignKey:ProductID" json:"Images"`
 }
 
+// Available คือจำนวนที่ยังขายได้ (available-to-sell)
+func (p *Product) Available() int {
+	return p.Amount - p.Reserved
+}
+
+// Item คือสินค้าหนึ่งรายการใน order โดยเก็บชื่อและราคา ณ เวลาที่สั่งซื้อไว้ด้วย
+// เพื่อให้ order ไม่เปลี่ยนตามเมื่อสินค้าถูกแก้ชื่อหรือราคาในภายหลัง
 type Item struct {
 	gorm.Model
-	Product string `json:"Product"`
-	Amount  int    `json:"Amount"`
-	OrderID uint   // Foreign Key
+	ProductID  uint   `gorm:"index" json:"ProductID"`
+	Product    string `json:"Product"`
+	Unit_Price int    `json:"Unit Price"`
+	Amount     int    `json:"Amount"`
+	Line_Total int    `json:"Line Total"`
+	Weight     int    `json:"Weight"`
+	OrderID    uint   // Foreign Key
+}
+
+// SetAmount เปลี่ยนจำนวนสินค้าและคำนวณ Line_Total ใหม่จากราคาที่บันทึกไว้
+func (i *Item) SetAmount(amount int) {
+	i.Amount = amount
+	i.Line_Total = i.Unit_Price * amount
 }
 
 type Order struct {
 	gorm.Model
-	Buyer       string `json:"Buyer"`
-	Items       []Item `gorm:"foreignKey:OrderID"`
-	Total_Price int    `json:"Total Price"`
+	Version      int        `gorm:"not null;default:1" json:"Version"`
+	Buyer        string     `json:"Buyer"`
+	Items        []Item     `gorm:"foreignKey:OrderID"`
+	Subtotal     int        `json:"Subtotal"`
+	Shipping     int        `json:"Shipping"`
+	Tax          int        `json:"Tax"`
+	Total_Price  int        `json:"Total Price"`
+	CouponID     *uint      `json:"CouponID"`
+	CouponCode   string     `gorm:"size:64" json:"CouponCode"`
+	Discount     int        `json:"Discount"`
+	Status       string     `gorm:"size:20;index" json:"Status"`
+	PaidAt       *time.Time `json:"PaidAt"`
+	FailedAt     *time.Time `json:"FailedAt"`
+	ProcessingAt *time.Time `json:"ProcessingAt"`
+	ShippedAt    *time.Time `json:"ShippedAt"`
+	DeliveredAt  *time.Time `json:"DeliveredAt"`
+	CancelledAt  *time.Time `json:"CancelledAt"`
+	RefundedAt   *time.Time `json:"RefundedAt"`
+	// ReservedUntil คือเวลาที่สินค้าของ order ถูกกันไว้ให้ถึง nil คือสินค้าถูกตัดสต็อกจริงแล้วหรือไม่ได้กันไว้แล้ว
+	ReservedUntil *time.Time `gorm:"index" json:"ReservedUntil"`
+}
+
+// order ใหม่เริ่มที่สถานะ pending เสมอ
+func (o *Order) BeforeCreate(tx *gorm.DB) (err error) {
+	if o.Status == "" {
+		o.Status = OrderPending
+	}
+	return
+}
+
+// ItemsTotal คือผลรวม Line_Total ของทุกรายการก่อนหักส่วนลด
+func (o *Order) ItemsTotal() int {
+	total := 0
+	for _, item := range o.Items {
+		total += item.Line_Total
+	}
+	return total
+}
+
+// TotalWeight คือน้ำหนักรวมของทุกรายการเป็นกรัม
+func (o *Order) TotalWeight() int {
+	weight := 0
+	for _, item := range o.Items {
+		weight += item.Weight * item.Amount
+	}
+	return weight
+}
+
+// CartItem คือสินค้าหนึ่งรายการในตะกร้าของ user ซึ่งยังไม่ได้ตัดสต็อกและไม่ได้เก็บราคาไว้
+// ราคาและจำนวนคงเหลือจะอ่านจากสินค้าใหม่ทุกครั้งจนกว่าจะ checkout
+type CartItem struct {
+	ID        uint      `gorm:"primaryKey" json:"ID"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+	UserID    uint      `gorm:"uniqueIndex:idx_cart_items_user_product" json:"UserID"`
+	ProductID uint      `gorm:"uniqueIndex:idx_cart_items_user_product" json:"ProductID"`
+	Amount    int       `json:"Amount"`
+}
+
+// สถานะของ payment
+const (
+	PaymentPending = "pending"
+	// PaymentCapturing คือบันทึกแล้วว่าจะตัดเงินแต่ยังไม่ได้ผลจาก provider
+	PaymentCapturing = "capturing"
+	PaymentSucceeded = "succeeded"
+	PaymentFailed    = "failed"
+	// PaymentRefunding คือบันทึกแล้วว่าต้องคืนเงินแต่ provider ยังไม่ได้คืน
+	PaymentRefunding = "refunding"
+	PaymentRefunded  = "refunded"
+)
+
+// Payment คือการชำระเงินหนึ่งครั้งของ order ผ่าน payment provider
+// ProviderRef คือ ID ของ payment intent ฝั่ง provider ซึ่งใช้จับคู่กับ webhook
+type Payment struct {
+	gorm.Model
+	OrderID     uint   `gorm:"index" json:"OrderID"`
+	Provider    string `gorm:"size:50" json:"Provider"`
+	ProviderRef string `gorm:"size:255;uniqueIndex" json:"ProviderRef"`
+	Amount      int    `json:"Amount"`
+	Currency    string `gorm:"size:3" json:"Currency"`
+	Status      string `gorm:"size:20" json:"Status"`
 }
 
 type User struct {
 	gorm.Model
-	Username  string `json:"Username" validate:"required"`
-	Password  string `json:"Password" validate:"required, min=6, max=20"`
-	FirstName string `json:"FirstName" validate:"required"`
-	LastName  string `json:"LastName" validate:"required"`
+	Version   int    `gorm:"not null;default:1" json:"Version"`
+	Username  string `json:"Username"`
+	Password  string `json:"-"`
+	FirstName string `json:"FirstName"`
+	LastName  string `json:"LastName"`
 	Role      string `json:"Role"`
 	Approve   bool   `json:"Approve"`
 }
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// สถานะของ order
+const (
+	OrderPending    = "pending"
+	OrderPaid       = "paid"
+	OrderFailed     = "failed"
+	OrderProcessing = "processing"
+	OrderShipped    = "shipped"
+	OrderDelivered  = "delivered"
+	OrderCancelled  = "cancelled"
+	OrderRefunded   = "refunded"
+)
+
+// SalesStatuses คือสถานะของ order ที่ชำระเงินแล้วและยังไม่ถูกยกเลิกหรือคืนเงิน ซึ่งนับเป็นยอดขายในรายงาน
+var SalesStatuses = []string{OrderPaid, OrderProcessing, OrderShipped, OrderDelivered}
+
+// ErrInvalidTransition ถูกส่งกลับเมื่อเปลี่ยนไปยังสถานะที่ไม่อนุญาตจากสถานะปัจจุบัน
+var ErrInvalidTransition = errors.New("models: invalid order status transition")
+
+// orderTransitions คือสถานะถัดไปที่อนุญาตจากแต่ละสถานะ cancelled และ refunded เป็นสถานะสุดท้าย
+// failed คือการชำระเงินไม่สำเร็จ ซึ่งผู้ซื้อยังจ่ายใหม่ได้
+var orderTransitions = map[string][]string{
+	OrderPending:    {OrderPaid, OrderFailed, OrderCancelled},
+	OrderFailed:     {OrderPaid, OrderCancelled},
+	OrderPaid:       {OrderProcessing, OrderCancelled, OrderRefunded},
+	OrderProcessing: {OrderShipped, OrderCancelled, OrderRefunded},
+	OrderShipped:    {OrderDelivered, OrderRefunded},
+	OrderDelivered:  {OrderRefunded},
+}
+
+// CanTransitionTo บอกว่าเปลี่ยนจากสถานะปัจจุบันไปเป็น status ได้หรือไม่
+func (o *Order) CanTransitionTo(status string) bool {
+	return slices.Contains(orderTransitions[o.Status], status)
+}
+
+// TransitionTo เปลี่ยนสถานะและบันทึกเวลาที่เปลี่ยนไว้ใน field ของสถานะนั้น
+func (o *Order) TransitionTo(status string, at time.Time) error {
+	if !o.CanTransitionTo(status) {
+		return ErrInvalidTransition
+	}
+
+	switch status {
+	case OrderPaid:
+		o.PaidAt = &at
+	case OrderFailed:
+		o.FailedAt = &at
+	case OrderProcessing:
+		o.ProcessingAt = &at
+	case OrderShipped:
+		o.ShippedAt = &at
+	case OrderDelivered:
+		o.DeliveredAt = &at
+	case OrderCancelled:
+		o.CancelledAt = &at
+	case OrderRefunded:
+		o.RefundedAt = &at
+	}
+	o.Status = status
+	return nil
+}
+
+// HoldsStock บอกว่าสินค้าใน order ถูกตัดสต็อก (หรือกันไว้) แล้วแต่ยังไม่ได้ส่งออกจากคลัง
+// ถ้า order ถูกยกเลิกหรือคืนเงินในสถานะนี้ต้องคืนสินค้าเข้าคลังหรือปล่อยการกันสินค้า
+func (o *Order) HoldsStock() bool {
+	switch o.Status {
+	case OrderPending, OrderFailed, OrderPaid, OrderProcessing:
+		return true
+	}
+	return false
+}
+
+// HasReservation บอกว่าสินค้าของ order ยังถูกกันไว้โดยยังไม่ได้ตัดสต็อกจริง
+func (o *Order) HasReservation() bool {
+	return o.ReservedUntil != nil
+}
+
+// ReservationExpired บอกว่าการกันสินค้าของ order หมดเวลาแล้ว ณ เวลา now
+func (o *Order) ReservationExpired(now time.Time) bool {
+	return o.ReservedUntil != nil && !now.Before(*o.ReservedUntil)
+}
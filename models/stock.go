@@ -0,0 +1,42 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// เหตุผลของการเปลี่ยนแปลงสต็อก
+const (
+	// StockInitial คือจำนวนตอนสร้างสินค้า หรือยอดยกมาของสินค้าที่มีอยู่ก่อนจะมี ledger
+	StockInitial        = "initial"
+	StockOrderPlaced    = "order_placed"
+	StockOrderEdited    = "order_edited"
+	StockOrderCancelled = "order_cancelled"
+	StockOrderRefunded  = "order_refunded"
+	// StockAdjustment คือการแก้จำนวนโดย admin เช่นนับสต็อกแล้วไม่ตรง
+	StockAdjustment = "adjustment"
+	// StockRestock คือการรับสินค้าเข้าคลัง
+	StockRestock = "restock"
+)
+
+// StockMovement คือการเปลี่ยนแปลงจำนวนสินค้าหนึ่งครั้ง ซึ่งบันทึกต่อท้ายเสมอและไม่ถูกแก้ไขหรือลบ
+// ผลรวม Delta ของสินค้าหนึ่งตัวต้องเท่ากับ Product.Amount
+type StockMovement struct {
+	ID        uint      `gorm:"primaryKey" json:"ID"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	ProductID uint      `gorm:"index" json:"ProductID"`
+	Delta     int       `json:"Delta"`
+	// Balance คือจำนวนสินค้าหลังการเปลี่ยนแปลงนี้
+	Balance int    `json:"Balance"`
+	Reason  string `gorm:"size:30" json:"Reason"`
+	// ActorID คือ user ที่ทำให้เกิดการเปลี่ยนแปลง nil คือระบบเป็นผู้ทำ
+	ActorID *uint `json:"ActorID"`
+	// Reference คือสิ่งที่ทำให้เกิดการเปลี่ยนแปลง เช่น order:12
+	Reference string `gorm:"size:100;index" json:"Reference"`
+	Note      string `gorm:"size:255" json:"Note"`
+}
+
+// OrderReference คือ Reference ของการเปลี่ยนแปลงที่เกิดจาก order
+func OrderReference(orderID uint) string {
+	return "order:" + strconv.FormatUint(uint64(orderID), 10)
+}
@@ -0,0 +1,123 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"go-fiber-test/config"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Fake คือ provider ที่ทำงานใน memory สำหรับ local/dev/test ไม่มีการตัดเงินจริง
+// ใช้ Sign สร้างลายเซ็นเพื่อจำลอง webhook ที่ provider จริงจะส่งมา
+type Fake struct {
+	secret  string
+	mu      sync.Mutex
+	intents map[string]*Intent
+	refunds map[string]*Refund
+}
+
+func NewFake(secret string) *Fake {
+	return &Fake{secret: secret, intents: map[string]*Intent{}, refunds: map[string]*Refund{}}
+}
+
+func (f *Fake) Name() string { return config.PaymentFake }
+
+func (f *Fake) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent := &Intent{
+		ID:           "fake_pi_" + uuid.NewString(),
+		Amount:       req.Amount,
+		Currency:     req.Currency,
+		Status:       StatusRequiresPayment,
+		ClientSecret: "fake_secret_" + uuid.NewString(),
+	}
+	f.intents[intent.ID] = intent
+	copied := *intent
+	return &copied, nil
+}
+
+func (f *Fake) Capture(ctx context.Context, intentID string) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, ok := f.intents[intentID]
+	if !ok {
+		return nil, ErrUnknownIntent
+	}
+	// fake ยอมให้ capture intent ที่ยังไม่ได้อนุมัติด้วย เพื่อให้จำลอง event ได้โดยไม่ต้องผ่านหน้าจ่ายเงิน
+	switch intent.Status {
+	case StatusRequiresPayment, StatusAuthorized, StatusSucceeded:
+		intent.Status = StatusSucceeded
+	default:
+		return nil, ErrInvalidState
+	}
+	copied := *intent
+	return &copied, nil
+}
+
+func (f *Fake) Refund(ctx context.Context, intentID string) (*Refund, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, ok := f.intents[intentID]
+	if !ok {
+		return nil, ErrUnknownIntent
+	}
+	switch intent.Status {
+	case StatusSucceeded:
+		intent.Status = StatusRefunded
+		f.refunds[intentID] = &Refund{ID: "fake_re_" + uuid.NewString(), IntentID: intentID, Amount: intent.Amount}
+	case StatusRefunded:
+		// คืนเงินไปแล้ว ส่งผลเดิมกลับไปเหมือน provider จริงที่รับคำสั่งซ้ำได้
+	default:
+		return nil, ErrInvalidState
+	}
+	copied := *f.refunds[intentID]
+	return &copied, nil
+}
+
+func (f *Fake) VerifyWebhook(payload []byte, signature string) (*Event, error) {
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, sign(f.secret, payload)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+
+	// อัปเดตสถานะใน memory ให้ตรงกับ event ที่จำลองมา
+	f.mu.Lock()
+	if intent, ok := f.intents[event.IntentID]; ok {
+		switch event.Type {
+		case EventAuthorized:
+			intent.Status = StatusAuthorized
+		case EventSucceeded:
+			intent.Status = StatusSucceeded
+		case EventFailed:
+			intent.Status = StatusFailed
+		}
+	}
+	f.mu.Unlock()
+
+	return &event, nil
+}
+
+// Sign คืนลายเซ็น (hex ของ HMAC-SHA256) ของ payload สำหรับใส่ใน SignatureHeader ของ webhook จำลอง
+func (f *Fake) Sign(payload []byte) string {
+	return hex.EncodeToString(sign(f.secret, payload))
+}
+
+func sign(secret string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
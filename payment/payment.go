@@ -0,0 +1,89 @@
+// Package payment คือ interface กลางของ payment gateway ที่ controller ใช้รับชำระเงินและคืนเงิน
+// โดยไม่ผูกกับ provider ใดโดยตรง provider ถูกเลือกจาก config.PaymentConfig
+package payment
+
+import (
+	"context"
+	"errors"
+	"go-fiber-test/config"
+)
+
+// SignatureHeader คือ header ที่ provider ใส่ลายเซ็นของ webhook มา
+const SignatureHeader = "X-Payment-Signature"
+
+// สถานะของ payment intent
+const (
+	StatusRequiresPayment = "requires_payment"
+	StatusAuthorized      = "authorized"
+	StatusSucceeded       = "succeeded"
+	StatusFailed          = "failed"
+	StatusRefunded        = "refunded"
+)
+
+// ชนิดของ webhook event ที่แอปสนใจ
+const (
+	// EventAuthorized คือผู้ซื้ออนุมัติการจ่ายแล้วแต่ต้อง Capture ก่อนเงินจะถูกตัดจริง
+	EventAuthorized = "payment.authorized"
+	EventSucceeded  = "payment.succeeded"
+	EventFailed     = "payment.failed"
+)
+
+var (
+	// ErrInvalidSignature ถูกส่งกลับเมื่อลายเซ็นของ webhook ไม่ตรงกับ payload
+	ErrInvalidSignature = errors.New("payment: invalid webhook signature")
+	// ErrUnknownIntent ถูกส่งกลับเมื่อ provider ไม่รู้จัก intent ที่อ้างถึง
+	ErrUnknownIntent = errors.New("payment: unknown payment intent")
+	// ErrInvalidState ถูกส่งกลับเมื่อ intent อยู่ในสถานะที่ทำคำสั่งนั้นไม่ได้ เช่นคืนเงินก่อนถูกตัดเงิน
+	ErrInvalidState = errors.New("payment: payment intent is in the wrong state")
+)
+
+// IntentRequest คือข้อมูลสำหรับสร้าง payment intent ของ order หนึ่ง
+// Amount เป็นหน่วยเดียวกับราคาสินค้าในระบบ
+type IntentRequest struct {
+	OrderID  uint
+	Amount   int
+	Currency string
+}
+
+// Intent คือการชำระเงินหนึ่งครั้งฝั่ง provider ClientSecret ส่งให้ frontend ใช้ยืนยันการจ่ายกับ provider
+type Intent struct {
+	ID           string
+	Amount       int
+	Currency     string
+	Status       string
+	ClientSecret string
+}
+
+// Refund คือผลการคืนเงินของ intent
+type Refund struct {
+	ID       string
+	IntentID string
+	Amount   int
+}
+
+// Event คือ webhook event ที่ผ่านการตรวจลายเซ็นแล้ว
+type Event struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	IntentID string `json:"intent_id"`
+}
+
+// Provider คือ payment gateway หนึ่งเจ้า
+type Provider interface {
+	// Name คือชื่อ provider ที่บันทึกไว้กับ payment
+	Name() string
+	CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error)
+	// Capture ตัดเงินของ intent ที่ได้รับอนุมัติแล้ว ถ้าตัดไปแล้วจะคืนผลเดิมโดยไม่ตัดซ้ำ
+	Capture(ctx context.Context, intentID string) (*Intent, error)
+	// Refund คืนเงินเต็มจำนวนของ intent ที่ตัดเงินไปแล้ว ถ้าคืนไปแล้วจะคืนผลเดิมโดยไม่คืนซ้ำ
+	// ทั้งสองคำสั่งจึงเรียกซ้ำได้เมื่อบันทึกผลลงฐานข้อมูลไม่สำเร็จ
+	Refund(ctx context.Context, intentID string) (*Refund, error)
+	// VerifyWebhook ตรวจลายเซ็นของ webhook แล้ว parse เป็น Event
+	VerifyWebhook(payload []byte, signature string) (*Event, error)
+}
+
+// New สร้าง Provider ตาม config ซึ่ง config.Validate ตรวจแล้วว่าเป็น provider ที่รองรับ
+// ตอนนี้มีเฉพาะ fake เมื่อเพิ่ม provider จริงให้เลือกตาม cfg.Provider ที่นี่
+func New(cfg config.PaymentConfig) Provider {
+	return NewFake(cfg.WebhookSecret)
+}
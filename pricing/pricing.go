@@ -0,0 +1,106 @@
+// Package pricing คำนวณยอดของ order จากรายการสินค้า ได้แก่ยอดสินค้า ค่าส่ง ภาษี และยอดสุทธิ
+// วิธีคิดภาษีและค่าส่งเปลี่ยนได้ตาม config.PricingConfig ของร้าน
+package pricing
+
+import (
+	"go-fiber-test/config"
+	m "go-fiber-test/models"
+	"math"
+)
+
+// ShippingCalculator คิดค่าส่งของ order จากรายการสินค้าและส่วนลดที่ได้แล้ว
+type ShippingCalculator interface {
+	Shipping(order *m.Order) int
+}
+
+// TaxCalculator คิดภาษีของ order หลังจากคิดส่วนลดและค่าส่งแล้ว
+type TaxCalculator interface {
+	Tax(order *m.Order) int
+}
+
+// NoShipping ไม่คิดค่าส่ง
+type NoShipping struct{}
+
+func (NoShipping) Shipping(order *m.Order) int { return 0 }
+
+// FlatRate คิดค่าส่งเท่ากันทุก order
+type FlatRate struct {
+	Fee int
+}
+
+func (f FlatRate) Shipping(order *m.Order) int { return f.Fee }
+
+// WeightBased คิดค่าส่งเป็น Base บวก PerKg ต่อทุกกิโลกรัมที่เริ่ม (เศษของกิโลกรัมนับเป็นหนึ่งกิโลกรัม)
+type WeightBased struct {
+	Base  int
+	PerKg int
+}
+
+func (w WeightBased) Shipping(order *m.Order) int {
+	kg := (order.TotalWeight() + 999) / 1000
+	return w.Base + kg*w.PerKg
+}
+
+// FreeOver ไม่คิดค่าส่งเมื่อยอดหลังหักส่วนลดถึง Threshold มิฉะนั้นใช้ค่าส่งจาก Next
+type FreeOver struct {
+	Threshold int
+	Next      ShippingCalculator
+}
+
+func (f FreeOver) Shipping(order *m.Order) int {
+	if order.ItemsTotal()-order.Discount >= f.Threshold {
+		return 0
+	}
+	return f.Next.Shipping(order)
+}
+
+// NoTax ไม่คิดภาษี
+type NoTax struct{}
+
+func (NoTax) Tax(order *m.Order) int { return 0 }
+
+// VAT คิดภาษีมูลค่าเพิ่ม Rate เปอร์เซ็นต์ของยอดหลังหักส่วนลดรวมค่าส่ง ปัดเศษเป็นจำนวนเต็มที่ใกล้ที่สุด
+type VAT struct {
+	Rate float64
+}
+
+func (v VAT) Tax(order *m.Order) int {
+	taxable := order.ItemsTotal() - order.Discount + order.Shipping
+	return int(math.Round(float64(taxable) * v.Rate / 100))
+}
+
+// Calculator รวมวิธีคิดค่าส่งและภาษีของร้านไว้ด้วยกัน
+type Calculator struct {
+	Shipping ShippingCalculator
+	Tax      TaxCalculator
+}
+
+// New สร้าง Calculator ตาม config ซึ่ง config.Validate ตรวจแล้วว่าเป็นวิธีที่รองรับ
+func New(cfg config.PricingConfig) *Calculator {
+	var shipping ShippingCalculator = NoShipping{}
+	switch cfg.Shipping {
+	case config.ShippingFlat:
+		shipping = FlatRate{Fee: cfg.ShippingFlat}
+	case config.ShippingWeight:
+		shipping = WeightBased{Base: cfg.ShippingBase, PerKg: cfg.ShippingPerKg}
+	}
+	if cfg.FreeShippingOver > 0 {
+		shipping = FreeOver{Threshold: cfg.FreeShippingOver, Next: shipping}
+	}
+
+	var tax TaxCalculator = NoTax{}
+	if cfg.Tax == config.TaxVAT {
+		tax = VAT{Rate: cfg.VATRate}
+	}
+
+	return &Calculator{Shipping: shipping, Tax: tax}
+}
+
+// Apply คำนวณ Subtotal, Shipping, Tax และ Total_Price ของ order ใหม่จากรายการและส่วนลดปัจจุบัน
+// ต้องเรียกทุกครั้งหลังรายการหรือส่วนลดเปลี่ยน
+func (c *Calculator) Apply(order *m.Order) {
+	order.Subtotal = order.ItemsTotal()
+	order.Shipping = c.Shipping.Shipping(order)
+	order.Tax = c.Tax.Tax(order)
+	order.Total_Price = order.Subtotal - order.Discount + order.Shipping + order.Tax
+}
@@ -0,0 +1,138 @@
+package routes_test
+
+import (
+	"fmt"
+	"go-fiber-test/apperr"
+	"go-fiber-test/dto"
+	m "go-fiber-test/models"
+	"go-fiber-test/testutil"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func getCart(t *testing.T, h *testutil.Harness, token string) dto.CartResponse {
+	t.Helper()
+	var out struct {
+		Data dto.CartResponse `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodGet, "/cart", token, nil), fiber.StatusOK).JSON(t, &out)
+	return out.Data
+}
+
+func TestCartAddUpdateRemove(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	book := h.CreateProduct(admin.AccessToken, "Book", 7, 3)
+
+	h.Expect(h.Request(http.MethodPost, "/cart/items", user.AccessToken, testutil.ItemRequest{ProductID: pen.ID, Amount: 2}), fiber.StatusCreated)
+	h.Expect(h.Request(http.MethodPost, "/cart/items", user.AccessToken, testutil.ItemRequest{Product: "Pen", Amount: 1}), fiber.StatusCreated)
+	h.Expect(h.Request(http.MethodPost, "/cart/items", user.AccessToken, testutil.ItemRequest{ProductID: book.ID, Amount: 1}), fiber.StatusCreated)
+
+	cart := getCart(t, h, user.AccessToken)
+	if len(cart.Items) != 2 || cart.Items[0].Amount != 3 || cart.Total_Price != 3*5+7 || !cart.Available {
+		t.Fatalf("unexpected cart: %+v", cart)
+	}
+
+	// ใส่เกินสต็อกที่มีตอนนี้ไม่ได้
+	h.ExpectProblem(h.Request(http.MethodPut, fmt.Sprintf("/cart/items/%d", book.ID), user.AccessToken, map[string]int{"Amount": 4}),
+		fiber.StatusConflict, apperr.CodeInsufficientStock)
+	problem := h.ExpectProblem(h.Request(http.MethodPut, fmt.Sprintf("/cart/items/%d", book.ID), user.AccessToken, map[string]int{"Amount": 0}),
+		fiber.StatusBadRequest, apperr.CodeValidation)
+	expectFieldErrors(t, problem, "Amount:gt")
+
+	h.Expect(h.Request(http.MethodPut, fmt.Sprintf("/cart/items/%d", book.ID), user.AccessToken, map[string]int{"Amount": 3}), fiber.StatusOK)
+	h.Expect(h.Request(http.MethodDelete, fmt.Sprintf("/cart/items/%d", pen.ID), user.AccessToken, nil), fiber.StatusOK)
+	h.Expect(h.Request(http.MethodDelete, fmt.Sprintf("/cart/items/%d", pen.ID), user.AccessToken, nil), fiber.StatusNotFound)
+
+	cart = getCart(t, h, user.AccessToken)
+	if len(cart.Items) != 1 || cart.Items[0].ProductID != book.ID || cart.Total_Price != 21 {
+		t.Fatalf("unexpected cart: %+v", cart)
+	}
+
+	// ตะกร้าไม่ตัดสต็อก
+	expectStock(t, h, pen, 10)
+	expectStock(t, h, book, 3)
+
+	h.Expect(h.Request(http.MethodDelete, "/cart", user.AccessToken, nil), fiber.StatusOK)
+	if cart := getCart(t, h, user.AccessToken); len(cart.Items) != 0 || cart.Total_Price != 0 {
+		t.Fatalf("expected empty cart, got %+v", cart)
+	}
+}
+
+func TestCartShowsLivePricesAndAvailability(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	bob := h.LoginUser(admin.AccessToken, "bob")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 4)
+
+	h.Expect(h.Request(http.MethodPost, "/cart/items", alice.AccessToken, testutil.ItemRequest{ProductID: pen.ID, Amount: 3}), fiber.StatusCreated)
+
+	h.Expect(h.Form(http.MethodPut, fmt.Sprintf("/product/%d", pen.ID), admin.AccessToken, map[string]string{
+		"Price": "6",
+	}), fiber.StatusOK)
+	h.PlaceOrder(bob, testutil.ItemRequest{ProductID: pen.ID, Amount: 2})
+
+	cart := getCart(t, h, alice.AccessToken)
+	if item := cart.Items[0]; item.Unit_Price != 6 || item.Line_Total != 18 || item.Stock != 2 || item.Available || cart.Available {
+		t.Fatalf("expected live price and unavailable stock, got %+v", cart)
+	}
+
+	// ตะกร้าเป็นของแต่ละคน
+	if cart := getCart(t, h, bob.AccessToken); len(cart.Items) != 0 {
+		t.Fatalf("expected bob's cart to be empty, got %+v", cart)
+	}
+	h.Expect(h.Request(http.MethodGet, "/cart", admin.AccessToken, nil), fiber.StatusForbidden)
+}
+
+func TestCheckoutCreatesOrderAndClearsCart(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	book := h.CreateProduct(admin.AccessToken, "Book", 7, 10)
+
+	h.ExpectProblem(h.Request(http.MethodPost, "/cart/checkout", user.AccessToken, nil), fiber.StatusBadRequest, apperr.CodeBadRequest)
+
+	h.Expect(h.Request(http.MethodPost, "/cart/items", user.AccessToken, testutil.ItemRequest{ProductID: pen.ID, Amount: 2}), fiber.StatusCreated)
+	h.Expect(h.Request(http.MethodPost, "/cart/items", user.AccessToken, testutil.ItemRequest{ProductID: book.ID, Amount: 1}), fiber.StatusCreated)
+
+	var out struct {
+		Data m.Order `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodPost, "/cart/checkout", user.AccessToken, nil), fiber.StatusCreated).JSON(t, &out)
+	if out.Data.Buyer != fmt.Sprint(user.UserID) || len(out.Data.Items) != 2 || out.Data.Total_Price != 2*5+7 || out.Data.Status != m.OrderPending {
+		t.Fatalf("unexpected order: %+v", out.Data)
+	}
+	expectStock(t, h, pen, 8)
+	expectStock(t, h, book, 9)
+
+	if cart := getCart(t, h, user.AccessToken); len(cart.Items) != 0 {
+		t.Fatalf("expected cart to be cleared, got %+v", cart)
+	}
+}
+
+func TestCheckoutKeepsCartWhenStockRunsOut(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	bob := h.LoginUser(admin.AccessToken, "bob")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	book := h.CreateProduct(admin.AccessToken, "Book", 7, 3)
+
+	h.Expect(h.Request(http.MethodPost, "/cart/items", alice.AccessToken, testutil.ItemRequest{ProductID: pen.ID, Amount: 2}), fiber.StatusCreated)
+	h.Expect(h.Request(http.MethodPost, "/cart/items", alice.AccessToken, testutil.ItemRequest{ProductID: book.ID, Amount: 3}), fiber.StatusCreated)
+	h.PlaceOrder(bob, testutil.ItemRequest{ProductID: book.ID, Amount: 1})
+
+	h.ExpectProblem(h.Request(http.MethodPost, "/cart/checkout", alice.AccessToken, nil), fiber.StatusConflict, apperr.CodeInsufficientStock)
+	expectStock(t, h, pen, 10)
+	expectStock(t, h, book, 2)
+
+	if cart := getCart(t, h, alice.AccessToken); len(cart.Items) != 2 || cart.Available {
+		t.Fatalf("expected cart to be kept, got %+v", cart)
+	}
+}
@@ -0,0 +1,174 @@
+package routes_test
+
+import (
+	"fmt"
+	"go-fiber-test/apperr"
+	"go-fiber-test/config"
+	m "go-fiber-test/models"
+	"go-fiber-test/payment"
+	"go-fiber-test/testutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// conditionalGet ส่ง GET พร้อม header ของ conditional request
+func conditionalGet(h *testutil.Harness, path, header, value string) *testutil.Response {
+	req := h.NewRequest(http.MethodGet, path, "", nil)
+	req.Header.Set(header, value)
+	return h.Do(req)
+}
+
+// productPrice อ่านราคาของสินค้าจาก GET /product/:productId
+func productPrice(t *testing.T, h *testutil.Harness, id uint) int {
+	t.Helper()
+	var out struct {
+		Data m.Product `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodGet, fmt.Sprintf("/product/%d", id), "", nil), fiber.StatusOK).JSON(t, &out)
+	return out.Data.Price
+}
+
+func TestCatalogConditionalGet(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	ink := h.CreateProduct(admin.AccessToken, "Ink", 8, 10)
+	path := fmt.Sprintf("/product/%d", pen.ID)
+
+	resp := h.Expect(h.Request(http.MethodGet, path, "", nil), fiber.StatusOK)
+	etag := resp.Header.Get(fiber.HeaderETag)
+	lastModified := resp.Header.Get(fiber.HeaderLastModified)
+	if etag == "" || lastModified == "" {
+		t.Fatalf("expected ETag and Last-Modified, got %v", resp.Header)
+	}
+	if cc := resp.Header.Get(fiber.HeaderCacheControl); cc != "no-cache" {
+		t.Fatalf("expected the default Cache-Control, got %q", cc)
+	}
+
+	notModified := h.Expect(conditionalGet(h, path, fiber.HeaderIfNoneMatch, etag), fiber.StatusNotModified)
+	if len(notModified.Body) != 0 || notModified.Header.Get(fiber.HeaderETag) != etag {
+		t.Fatalf("expected an empty 304 with the same ETag, got %q and %v", notModified.Body, notModified.Header)
+	}
+	h.Expect(conditionalGet(h, path, fiber.HeaderIfModifiedSince, lastModified), fiber.StatusNotModified)
+	// If-None-Match มาก่อน If-Modified-Since
+	req := h.NewRequest(http.MethodGet, path, "", nil)
+	req.Header.Set(fiber.HeaderIfNoneMatch, `"stale"`)
+	req.Header.Set(fiber.HeaderIfModifiedSince, lastModified)
+	h.Expect(h.Do(req), fiber.StatusOK)
+
+	// จำนวนที่ขายได้เปลี่ยนเมื่อมี order ETag จึงเปลี่ยนตาม
+	h.PlaceOrder(alice, testutil.ItemRequest{Product: "Pen", Amount: 2})
+	changed := h.Expect(conditionalGet(h, path, fiber.HeaderIfNoneMatch, etag), fiber.StatusOK)
+	if changed.Header.Get(fiber.HeaderETag) == etag {
+		t.Fatal("expected a new ETag after the stock changed")
+	}
+
+	// หน้ารายการใช้ weak ETag และเปลี่ยนเมื่อสินค้าถูกลบออกจากหน้า
+	list := h.Expect(h.Request(http.MethodGet, "/product", "", nil), fiber.StatusOK)
+	listETag := list.Header.Get(fiber.HeaderETag)
+	if !strings.HasPrefix(listETag, `W/"`) {
+		t.Fatalf("expected a weak ETag on the list, got %q", listETag)
+	}
+	h.Expect(conditionalGet(h, "/product", fiber.HeaderIfNoneMatch, listETag), fiber.StatusNotModified)
+	h.Expect(h.Request(http.MethodDelete, fmt.Sprintf("/product/%d", ink.ID), admin.AccessToken, nil), fiber.StatusCreated)
+	h.Expect(conditionalGet(h, "/product", fiber.HeaderIfNoneMatch, listETag), fiber.StatusOK)
+
+	// error ไม่ถูกบอกให้ cache
+	missing := h.Expect(h.Request(http.MethodGet, "/product/999", "", nil), fiber.StatusNotFound)
+	if cc := missing.Header.Get(fiber.HeaderCacheControl); cc != "" {
+		t.Fatalf("expected no Cache-Control on errors, got %q", cc)
+	}
+}
+
+func TestCatalogCacheIsInvalidatedByProductChanges(t *testing.T) {
+	cfg := testutil.Config(t)
+	cfg.Catalog.MaxAge = 30 * time.Second
+	cfg.Catalog.CacheTTL = time.Minute
+	h := testutil.NewWithConfig(t, cfg)
+	admin := h.LoginAdmin("admin")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	path := fmt.Sprintf("/product/%d", pen.ID)
+
+	etag := h.Expect(h.Request(http.MethodGet, path, "", nil), fiber.StatusOK).Header.Get(fiber.HeaderETag)
+
+	// แก้ในฐานข้อมูลโดยตรงไม่ผ่าน endpoint จึงยังได้ response เดิมจาก memory
+	if err := h.DB.Model(&m.Product{}).Where("id = ?", pen.ID).Update("price", 9).Error; err != nil {
+		t.Fatal(err)
+	}
+	if price := productPrice(t, h, pen.ID); price != 5 {
+		t.Fatalf("expected the cached price 5, got %d", price)
+	}
+	cached := h.Expect(conditionalGet(h, path, fiber.HeaderIfNoneMatch, etag), fiber.StatusNotModified)
+	if cc := cached.Header.Get(fiber.HeaderCacheControl); cc != "public, max-age=30" {
+		t.Fatalf("expected Cache-Control public, max-age=30, got %q", cc)
+	}
+
+	// การแก้ผ่าน endpoint ของ admin ล้าง cache
+	h.Expect(h.Form(http.MethodPut, path, admin.AccessToken, map[string]string{"Price": "7"}), fiber.StatusOK)
+	if price := productPrice(t, h, pen.ID); price != 7 {
+		t.Fatalf("expected the updated price 7, got %d", price)
+	}
+	h.Expect(conditionalGet(h, path, fiber.HeaderIfNoneMatch, etag), fiber.StatusOK)
+
+	h.Expect(h.Request(http.MethodDelete, path, admin.AccessToken, nil), fiber.StatusCreated)
+	h.Expect(h.Request(http.MethodGet, path, "", nil), fiber.StatusNotFound)
+}
+
+func TestCatalogCacheIsInvalidatedByStockChanges(t *testing.T) {
+	cfg := testutil.Config(t)
+	cfg.Catalog.CacheTTL = time.Minute
+	h := testutil.NewWithConfig(t, cfg)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	path := fmt.Sprintf("/product/%d", pen.ID)
+
+	// order กันสินค้า ยกเลิก และชำระเงินผ่าน route ของ order และ webhook ทุกครั้งต้องเห็นจำนวนใหม่ทันทีแม้ cache เปิดอยู่
+	expectOnHand(t, h, pen.ID, 10, 0)
+	order := h.PlaceOrder(alice, testutil.ItemRequest{Product: "Pen", Amount: 2})
+	expectOnHand(t, h, pen.ID, 10, 2)
+
+	h.Expect(h.Request(http.MethodDelete, fmt.Sprintf("/order/%d", order.ID), alice.AccessToken, nil), fiber.StatusOK)
+	expectOnHand(t, h, pen.ID, 10, 0)
+
+	paid := h.PlaceOrder(alice, testutil.ItemRequest{Product: "Pen", Amount: 3})
+	expectOnHand(t, h, pen.ID, 10, 3)
+	pay := h.Pay(alice, paid.ID)
+	h.Expect(h.Webhook(payment.Event{ID: "evt_1", Type: payment.EventSucceeded, IntentID: pay.ProviderRef}), fiber.StatusOK)
+	expectOnHand(t, h, pen.ID, 7, 0)
+
+	h.Expect(h.Request(http.MethodPost, "/cart/items", alice.AccessToken, testutil.ItemRequest{ProductID: pen.ID, Amount: 1}), fiber.StatusCreated)
+	h.Expect(h.Request(http.MethodPost, "/cart/checkout", alice.AccessToken, nil), fiber.StatusCreated)
+	expectOnHand(t, h, pen.ID, 7, 1)
+
+	// หน้ารายการถูกล้างพร้อมกัน
+	var list struct {
+		Data []m.Product `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodGet, "/product", "", nil), fiber.StatusOK).JSON(t, &list)
+	if len(list.Data) != 1 || list.Data[0].Reserved != 1 {
+		t.Fatalf("expected the list to show 1 reserved, got %+v", list.Data)
+	}
+	h.Expect(h.Request(http.MethodGet, path, "", nil), fiber.StatusOK)
+}
+
+func TestCatalogHasItsOwnRateLimit(t *testing.T) {
+	cfg := testutil.Config(t)
+	cfg.RateLimit = config.RateLimitConfig{Max: 1, Expiration: time.Minute}
+	cfg.Catalog.RateLimit = config.RateLimitConfig{Max: 3, Expiration: time.Minute}
+	h := testutil.NewWithConfig(t, cfg)
+
+	// GET ของรายการสินค้าไม่นับใน limiter หลัก แต่ถูกจำกัดด้วย limiter ของ catalog
+	h.Expect(h.Request(http.MethodGet, "/product", "", nil), fiber.StatusOK)
+	h.Expect(h.Request(http.MethodGet, "/product/999", "", nil), fiber.StatusNotFound)
+	h.Expect(h.Request(http.MethodGet, "/product/", "", nil), fiber.StatusOK)
+	h.ExpectProblem(h.Request(http.MethodGet, "/product", "", nil), fiber.StatusTooManyRequests, apperr.CodeTooManyRequests)
+
+	h.Expect(h.Request(http.MethodGet, "/no-such-route", "", nil), fiber.StatusNotFound)
+	h.ExpectProblem(h.Request(http.MethodGet, "/no-such-route", "", nil), fiber.StatusTooManyRequests, apperr.CodeTooManyRequests)
+}
@@ -0,0 +1,213 @@
+package routes_test
+
+import (
+	"errors"
+	"fmt"
+	"go-fiber-test/apperr"
+	m "go-fiber-test/models"
+	"go-fiber-test/store"
+	"go-fiber-test/testutil"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ifMatch ส่ง request พร้อม If-Match
+func ifMatch(h *testutil.Harness, req *http.Request, etag string) *testutil.Response {
+	req.Header.Set(fiber.HeaderIfMatch, etag)
+	return h.Do(req)
+}
+
+func expectETag(t *testing.T, resp *testutil.Response, etag string) {
+	t.Helper()
+	if got := resp.Header.Get(fiber.HeaderETag); got != etag {
+		t.Fatalf("expected ETag %s, got %q", etag, got)
+	}
+}
+
+func TestProductUpdatesRequireMatchingETag(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	path := fmt.Sprintf("/product/%d", pen.ID)
+
+	read := h.Expect(h.Request(http.MethodGet, path, "", nil), fiber.StatusOK).Header.Get(fiber.HeaderETag)
+	if read == "" {
+		t.Fatal("expected an ETag on GET /product/:productId")
+	}
+
+	// admin สองคนอ่าน version เดียวกัน คนแรกบันทึกได้ คนที่สองได้ 412 และไม่มีอะไรถูกเขียนทับ
+	first := h.Expect(ifMatch(h, h.NewForm(http.MethodPut, path, admin.AccessToken, map[string]string{
+		"Product_Name": "Blue Pen",
+	}), read), fiber.StatusOK)
+	current := first.Header.Get(fiber.HeaderETag)
+	if current == "" || current == read {
+		t.Fatalf("expected a new ETag after the update, got %q", current)
+	}
+	h.ExpectProblem(ifMatch(h, h.NewForm(http.MethodPut, path, admin.AccessToken, map[string]string{
+		"Product_Name": "Red Pen",
+		"Amount":       "3",
+	}), read), fiber.StatusPreconditionFailed, apperr.CodePreconditionFailed)
+	if got := h.Product(pen.ID); got.Product_Name != "Blue Pen" || got.Amount != 10 || got.Version != 2 {
+		t.Fatalf("expected the first update to win, got %+v", got)
+	}
+
+	// weak ETag ไม่ตรงกับ If-Match ส่วน * ตรงกับทุก version
+	h.ExpectProblem(ifMatch(h, h.NewRequest(http.MethodDelete, path, admin.AccessToken, nil), "W/"+current),
+		fiber.StatusPreconditionFailed, apperr.CodePreconditionFailed)
+	latest := h.Expect(ifMatch(h, h.NewForm(http.MethodPut, path, admin.AccessToken, map[string]string{
+		"Price": "7",
+	}), "*"), fiber.StatusOK).Header.Get(fiber.HeaderETag)
+
+	h.ExpectProblem(ifMatch(h, h.NewRequest(http.MethodDelete, path, admin.AccessToken, nil), current),
+		fiber.StatusPreconditionFailed, apperr.CodePreconditionFailed)
+	h.Expect(ifMatch(h, h.NewRequest(http.MethodDelete, path, admin.AccessToken, nil), read+", "+latest), fiber.StatusCreated)
+	h.Expect(h.Request(http.MethodGet, path, "", nil), fiber.StatusNotFound)
+}
+
+func TestProductETagFollowsVersion(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	path := fmt.Sprintf("/product/%d", pen.ID)
+
+	expectETag(t, h.Expect(h.Request(http.MethodGet, path, "", nil), fiber.StatusOK), `"1"`)
+
+	// จำนวนที่ขายได้อยู่ใน response ของ product การกันสินค้าจึงเพิ่ม version
+	// admin ที่ยังเห็นจำนวนเดิมจะได้ 412 แทนการบันทึกทับ
+	h.PlaceOrder(alice, testutil.ItemRequest{Product: "Pen", Amount: 2})
+	expectETag(t, h.Expect(h.Request(http.MethodGet, path, "", nil), fiber.StatusOK), `"2"`)
+	h.ExpectProblem(ifMatch(h, h.NewForm(http.MethodPut, path, admin.AccessToken, map[string]string{
+		"Amount": "20",
+	}), `"1"`), fiber.StatusPreconditionFailed, apperr.CodePreconditionFailed)
+	if got := h.Product(pen.ID); got.Amount != 10 || got.Version != 2 {
+		t.Fatalf("expected the stale update to be rejected, got %+v", got)
+	}
+}
+
+func TestDeleteAndRestoreChangeETag(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	path := fmt.Sprintf("/product/%d", pen.ID)
+
+	// ETag ที่อ่านก่อนลบใช้แก้ product ที่ถูกกู้คืนแล้วไม่ได้
+	h.Expect(ifMatch(h, h.NewRequest(http.MethodDelete, path, admin.AccessToken, nil), `"1"`), fiber.StatusCreated)
+	restorePath := fmt.Sprintf("/product/restore/%d", pen.ID)
+	h.ExpectProblem(ifMatch(h, h.NewRequest(http.MethodPut, restorePath, admin.AccessToken, nil), `"1"`),
+		fiber.StatusPreconditionFailed, apperr.CodePreconditionFailed)
+	expectETag(t, h.Expect(ifMatch(h, h.NewRequest(http.MethodPut, restorePath, admin.AccessToken, nil), `"2"`), fiber.StatusCreated), `"3"`)
+	h.ExpectProblem(ifMatch(h, h.NewForm(http.MethodPut, path, admin.AccessToken, map[string]string{
+		"Price": "7",
+	}), `"1"`), fiber.StatusPreconditionFailed, apperr.CodePreconditionFailed)
+
+	// user ก็เช่นกัน การ approve ตอนสมัครทำให้ user อยู่ที่ version 2
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	userPath := fmt.Sprintf("/user/%d", alice.UserID)
+	h.Expect(ifMatch(h, h.NewRequest(http.MethodDelete, userPath, admin.AccessToken, nil), `"2"`), fiber.StatusOK)
+	expectETag(t, h.Expect(h.Request(http.MethodPut, fmt.Sprintf("/user/restore/%d", alice.UserID), admin.AccessToken, nil), fiber.StatusOK), `"4"`)
+	h.ExpectProblem(ifMatch(h, h.NewRequest(http.MethodDelete, userPath, admin.AccessToken, nil), `"2"`),
+		fiber.StatusPreconditionFailed, apperr.CodePreconditionFailed)
+}
+
+func TestOrderUpdatesRequireMatchingETag(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	order := h.PlaceOrder(alice, testutil.ItemRequest{Product: "Pen", Amount: 2})
+	if order.Version != 1 {
+		t.Fatalf("expected a new order to start at version 1, got %d", order.Version)
+	}
+	path := fmt.Sprintf("/order/%d", order.ID)
+
+	updated := h.Expect(ifMatch(h, h.NewRequest(http.MethodPut, path, alice.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 3}},
+	}), `"1"`), fiber.StatusOK)
+	expectETag(t, updated, `"2"`)
+
+	// request ที่อ่าน order ก่อนถูกแก้จะไม่เปลี่ยนสถานะหรือยกเลิก order
+	h.ExpectProblem(ifMatch(h, h.NewRequest(http.MethodPut, path+"/status", admin.AccessToken, map[string]string{
+		"Status": m.OrderPaid,
+	}), `"1"`), fiber.StatusPreconditionFailed, apperr.CodePreconditionFailed)
+	h.ExpectProblem(ifMatch(h, h.NewRequest(http.MethodDelete, path, alice.AccessToken, nil), `"1"`),
+		fiber.StatusPreconditionFailed, apperr.CodePreconditionFailed)
+	expectStock(t, h, pen, 7)
+
+	cancelled := h.Expect(ifMatch(h, h.NewRequest(http.MethodDelete, path, alice.AccessToken, nil), `"2"`), fiber.StatusOK)
+	expectETag(t, cancelled, `"3"`)
+	expectStock(t, h, pen, 10)
+}
+
+func TestUserUpdatesRequireMatchingETag(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	path := fmt.Sprintf("/user/%d", alice.UserID)
+
+	// การ approve ตอนสมัครทำให้ user อยู่ที่ version 2 แล้ว
+	h.ExpectProblem(ifMatch(h, h.NewForm(http.MethodPut, path, alice.AccessToken, map[string]string{
+		"FirstName": "Alice",
+	}), `"1"`), fiber.StatusPreconditionFailed, apperr.CodePreconditionFailed)
+	resp := h.Expect(ifMatch(h, h.NewForm(http.MethodPut, path, alice.AccessToken, map[string]string{
+		"FirstName": "Alice",
+	}), `"2"`), fiber.StatusCreated)
+	expectETag(t, resp, `"3"`)
+
+	h.ExpectProblem(ifMatch(h, h.NewRequest(http.MethodDelete, path, admin.AccessToken, nil), `"2"`),
+		fiber.StatusPreconditionFailed, apperr.CodePreconditionFailed)
+	h.Expect(ifMatch(h, h.NewRequest(http.MethodDelete, path, admin.AccessToken, nil), `"3"`), fiber.StatusOK)
+}
+
+func TestStaleSaveIsRejected(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	products := h.Store.Products()
+
+	first, err := products.Get(pen.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := products.Get(pen.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first.Price = 6
+	if err := products.Save(first); err != nil {
+		t.Fatal(err)
+	}
+	second.Price = 8
+	if err := products.Save(second); !errors.Is(err, store.ErrVersionConflict) {
+		t.Fatalf("expected a version conflict, got %v", err)
+	}
+	if second.Version != 1 {
+		t.Fatalf("expected the stale copy to keep its version, got %d", second.Version)
+	}
+	if err := products.SoftDelete(second); !errors.Is(err, store.ErrVersionConflict) {
+		t.Fatalf("expected a version conflict on delete, got %v", err)
+	}
+
+	// กู้คืนด้วย version ที่อ่านมาก่อนมีคนกู้คืนไปแล้วไม่ได้
+	if err := products.SoftDelete(first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := products.Restore(pen.ID, first.Version); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := products.Restore(pen.ID, first.Version); !errors.Is(err, store.ErrVersionConflict) {
+		t.Fatalf("expected a version conflict on restore, got %v", err)
+	}
+
+	// บันทึกที่ไม่ผ่านต้องไม่กลายเป็นการ insert แถวใหม่
+	var count int64
+	if err := h.DB.Model(&m.Product{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Product(pen.ID); count != 1 || got.Price != 6 || got.Version != 4 {
+		t.Fatalf("expected one product at price 6 and version 4, got %d products and %+v", count, got)
+	}
+}
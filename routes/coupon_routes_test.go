@@ -0,0 +1,235 @@
+package routes_test
+
+import (
+	"fmt"
+	"go-fiber-test/apperr"
+	"go-fiber-test/dto"
+	m "go-fiber-test/models"
+	"go-fiber-test/testutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func createCoupon(t *testing.T, h *testutil.Harness, token string, coupon map[string]any) dto.CouponResponse {
+	t.Helper()
+	var out struct {
+		Data dto.CouponResponse `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodPost, "/coupon", token, coupon), fiber.StatusCreated).JSON(t, &out)
+	return out.Data
+}
+
+func orderWithCoupon(h *testutil.Harness, tokens testutil.Tokens, coupon string, items ...testutil.ItemRequest) *testutil.Response {
+	return h.Request(http.MethodPost, fmt.Sprintf("/order/%d", tokens.UserID), tokens.AccessToken, map[string]any{
+		"Items":  items,
+		"Coupon": coupon,
+	})
+}
+
+func TestCouponCRUD(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+
+	coupon := createCoupon(t, h, admin.AccessToken, map[string]any{"Code": "save10", "Type": "percent", "Value": 10})
+	if coupon.Code != "SAVE10" {
+		t.Fatalf("expected code to be upper-cased, got %q", coupon.Code)
+	}
+
+	h.ExpectProblem(h.Request(http.MethodPost, "/coupon", admin.AccessToken, map[string]any{
+		"Code": "SAVE10", "Type": "fixed", "Value": 5,
+	}), fiber.StatusConflict, apperr.CodeConflict)
+
+	problem := h.ExpectProblem(h.Request(http.MethodPost, "/coupon", admin.AccessToken, map[string]any{
+		"Code": "bad code", "Type": "bogo", "Value": 0,
+	}), fiber.StatusBadRequest, apperr.CodeValidation)
+	expectFieldErrors(t, problem, "Code:alphanum", "Type:oneof", "Value:gt")
+
+	now := time.Now()
+	problem = h.ExpectProblem(h.Request(http.MethodPost, "/coupon", admin.AccessToken, map[string]any{
+		"Code": "HALF", "Type": "percent", "Value": 150, "StartsAt": now, "EndsAt": now.Add(-time.Hour),
+	}), fiber.StatusBadRequest, apperr.CodeValidation)
+	expectFieldErrors(t, problem, "Value:max", "EndsAt:gtfield")
+
+	path := fmt.Sprintf("/coupon/%d", coupon.ID)
+	var updated struct {
+		Data dto.CouponResponse `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodPut, path, admin.AccessToken, map[string]any{
+		"Code": "SAVE20", "Type": "percent", "Value": 20, "UsageLimit": 5,
+	}), fiber.StatusOK).JSON(t, &updated)
+	if updated.Data.Code != "SAVE20" || updated.Data.Value != 20 || updated.Data.UsageLimit != 5 {
+		t.Fatalf("unexpected coupon after update: %+v", updated.Data)
+	}
+
+	page := getList[dto.CouponResponse](t, h, "/coupon", admin.AccessToken)
+	if len(page.Data) != 1 || page.Meta.Total != 1 {
+		t.Fatalf("expected one coupon, got %+v", page.Data)
+	}
+
+	h.Expect(h.Request(http.MethodGet, "/coupon", user.AccessToken, nil), fiber.StatusForbidden)
+	h.Expect(h.Request(http.MethodPost, "/coupon", user.AccessToken, map[string]any{"Code": "X", "Type": "fixed", "Value": 1}), fiber.StatusForbidden)
+
+	h.Expect(h.Request(http.MethodDelete, path, admin.AccessToken, nil), fiber.StatusOK)
+	h.Expect(h.Request(http.MethodGet, path, admin.AccessToken, nil), fiber.StatusNotFound)
+
+	// code ของ coupon ที่ถูกลบไปแล้วใช้ซ้ำไม่ได้
+	h.ExpectProblem(h.Request(http.MethodPost, "/coupon", admin.AccessToken, map[string]any{
+		"Code": "SAVE20", "Type": "fixed", "Value": 5,
+	}), fiber.StatusConflict, apperr.CodeConflict)
+}
+
+func TestOrderWithPercentCoupon(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 15, 10)
+	createCoupon(t, h, admin.AccessToken, map[string]any{"Code": "SAVE10", "Type": "percent", "Value": 10, "MinOrder": 20})
+
+	// ยอดไม่ถึงขั้นต่ำ และต้องไม่ตัดสต็อก
+	h.ExpectProblem(orderWithCoupon(h, user, "SAVE10", testutil.ItemRequest{ProductID: pen.ID, Amount: 1}),
+		fiber.StatusBadRequest, apperr.CodeCouponInvalid)
+	h.ExpectProblem(orderWithCoupon(h, user, "NOPE", testutil.ItemRequest{ProductID: pen.ID, Amount: 2}),
+		fiber.StatusBadRequest, apperr.CodeCouponInvalid)
+	expectStock(t, h, pen, 10)
+
+	var out struct {
+		Data m.Order `json:"data"`
+	}
+	h.Expect(orderWithCoupon(h, user, "save10", testutil.ItemRequest{ProductID: pen.ID, Amount: 2}), fiber.StatusCreated).JSON(t, &out)
+	if out.Data.CouponCode != "SAVE10" || out.Data.Discount != 3 || out.Data.Total_Price != 27 {
+		t.Fatalf("unexpected discounted order: %+v", out.Data)
+	}
+
+	// แก้รายการแล้วส่วนลดถูกคำนวณใหม่ แต่ยอดต้องไม่ต่ำกว่าขั้นต่ำ
+	h.Expect(h.Request(http.MethodPut, fmt.Sprintf("/order/%d", out.Data.ID), user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{ProductID: pen.ID, Amount: 4}},
+	}), fiber.StatusOK).JSON(t, &out)
+	if out.Data.Discount != 6 || out.Data.Total_Price != 54 {
+		t.Fatalf("expected discount to follow the items, got %+v", out.Data)
+	}
+	h.ExpectProblem(h.Request(http.MethodPut, fmt.Sprintf("/order/%d", out.Data.ID), user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{ProductID: pen.ID, Amount: 1}},
+	}), fiber.StatusBadRequest, apperr.CodeCouponInvalid)
+	problem := h.ExpectProblem(h.Request(http.MethodPut, fmt.Sprintf("/order/%d", out.Data.ID), user.AccessToken, map[string]any{
+		"Items":  []testutil.ItemRequest{{ProductID: pen.ID, Amount: 4}},
+		"Coupon": "SAVE10",
+	}), fiber.StatusBadRequest, apperr.CodeValidation)
+	expectFieldErrors(t, problem, "Coupon:excluded")
+	expectStock(t, h, pen, 6)
+}
+
+func TestProductScopedFixedCoupon(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 15, 10)
+	book := h.CreateProduct(admin.AccessToken, "Book", 7, 10)
+	createCoupon(t, h, admin.AccessToken, map[string]any{"Code": "PEN100", "Type": "fixed", "Value": 100, "ProductID": pen.ID})
+
+	h.ExpectProblem(orderWithCoupon(h, user, "PEN100", testutil.ItemRequest{ProductID: book.ID, Amount: 1}),
+		fiber.StatusBadRequest, apperr.CodeCouponInvalid)
+
+	// ส่วนลดคงที่ไม่เกินยอดของสินค้าที่อยู่ในขอบเขต
+	var out struct {
+		Data m.Order `json:"data"`
+	}
+	h.Expect(orderWithCoupon(h, user, "PEN100",
+		testutil.ItemRequest{ProductID: pen.ID, Amount: 2},
+		testutil.ItemRequest{ProductID: book.ID, Amount: 1},
+	), fiber.StatusCreated).JSON(t, &out)
+	if out.Data.Discount != 30 || out.Data.Total_Price != 7 {
+		t.Fatalf("unexpected discounted order: %+v", out.Data)
+	}
+}
+
+func TestOrderEditMustKeepCouponDiscount(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 15, 10)
+	book := h.CreateProduct(admin.AccessToken, "Book", 7, 10)
+	createCoupon(t, h, admin.AccessToken, map[string]any{"Code": "PEN5", "Type": "percent", "Value": 5, "ProductID": pen.ID})
+
+	var out struct {
+		Data m.Order `json:"data"`
+	}
+	h.Expect(orderWithCoupon(h, user, "PEN5",
+		testutil.ItemRequest{ProductID: pen.ID, Amount: 2},
+		testutil.ItemRequest{ProductID: book.ID, Amount: 1},
+	), fiber.StatusCreated).JSON(t, &out)
+	if out.Data.Discount != 1 {
+		t.Fatalf("expected a discount of 1, got %+v", out.Data)
+	}
+
+	// แก้ order จนส่วนลดเหลือ 0 ไม่ได้ เพราะ coupon ถูกนับว่าใช้ไปแล้ว
+	h.ExpectProblem(h.Request(http.MethodPut, fmt.Sprintf("/order/%d", out.Data.ID), user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{ProductID: pen.ID, Amount: 1}},
+	}), fiber.StatusBadRequest, apperr.CodeCouponInvalid)
+	if order := loadOrder(t, h, out.Data.ID); order.Discount != 1 || order.Total_Price != 36 {
+		t.Fatalf("expected the order to keep its items and discount, got %+v", order)
+	}
+	expectStock(t, h, pen, 8)
+}
+
+func TestCouponDatesAndUsageLimits(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	bob := h.LoginUser(admin.AccessToken, "bob")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 10, 100)
+	item := testutil.ItemRequest{ProductID: pen.ID, Amount: 1}
+
+	now := time.Now()
+	createCoupon(t, h, admin.AccessToken, map[string]any{"Code": "LATER", "Type": "fixed", "Value": 1, "StartsAt": now.Add(time.Hour)})
+	createCoupon(t, h, admin.AccessToken, map[string]any{"Code": "OLD", "Type": "fixed", "Value": 1, "EndsAt": now.Add(-time.Hour)})
+	createCoupon(t, h, admin.AccessToken, map[string]any{"Code": "ONCE", "Type": "fixed", "Value": 1, "UsageLimit": 1})
+	each := createCoupon(t, h, admin.AccessToken, map[string]any{"Code": "EACH", "Type": "fixed", "Value": 1, "PerUserLimit": 1})
+
+	h.ExpectProblem(orderWithCoupon(h, alice, "LATER", item), fiber.StatusBadRequest, apperr.CodeCouponInvalid)
+	h.ExpectProblem(orderWithCoupon(h, alice, "OLD", item), fiber.StatusBadRequest, apperr.CodeCouponInvalid)
+
+	h.Expect(orderWithCoupon(h, alice, "ONCE", item), fiber.StatusCreated)
+	h.ExpectProblem(orderWithCoupon(h, bob, "ONCE", item), fiber.StatusBadRequest, apperr.CodeCouponInvalid)
+
+	var first struct {
+		Data m.Order `json:"data"`
+	}
+	h.Expect(orderWithCoupon(h, alice, "EACH", item), fiber.StatusCreated).JSON(t, &first)
+	h.ExpectProblem(orderWithCoupon(h, alice, "EACH", item), fiber.StatusBadRequest, apperr.CodeCouponInvalid)
+	h.Expect(orderWithCoupon(h, bob, "EACH", item), fiber.StatusCreated)
+
+	// ยกเลิก order แล้วได้สิทธิ์คืน
+	h.Expect(h.Request(http.MethodDelete, fmt.Sprintf("/order/%d", first.Data.ID), alice.AccessToken, nil), fiber.StatusOK)
+	h.Expect(orderWithCoupon(h, alice, "EACH", item), fiber.StatusCreated)
+
+	var got struct {
+		Data dto.CouponResponse `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodGet, fmt.Sprintf("/coupon/%d", each.ID), admin.AccessToken, nil), fiber.StatusOK).JSON(t, &got)
+	if got.Data.Used != 2 {
+		t.Fatalf("expected EACH to be used twice, got %d", got.Data.Used)
+	}
+	expectStock(t, h, pen, 97)
+}
+
+func TestCheckoutWithCoupon(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 20, 10)
+	createCoupon(t, h, admin.AccessToken, map[string]any{"Code": "CART5", "Type": "fixed", "Value": 5})
+
+	h.Expect(h.Request(http.MethodPost, "/cart/items", user.AccessToken, testutil.ItemRequest{ProductID: pen.ID, Amount: 1}), fiber.StatusCreated)
+
+	var out struct {
+		Data m.Order `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodPost, "/cart/checkout", user.AccessToken, map[string]string{"Coupon": "CART5"}), fiber.StatusCreated).JSON(t, &out)
+	if out.Data.Discount != 5 || out.Data.Total_Price != 15 {
+		t.Fatalf("unexpected discounted order: %+v", out.Data)
+	}
+}
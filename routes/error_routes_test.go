@@ -0,0 +1,95 @@
+package routes_test
+
+import (
+	"fmt"
+	"go-fiber-test/apperr"
+	"go-fiber-test/config"
+	"go-fiber-test/testutil"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// expectFieldErrors ตรวจสอบว่า details ของ problem มี error ราย field ครบตามที่คาดไว้ในรูปแบบ "field:rule"
+func expectFieldErrors(t *testing.T, problem apperr.Problem, want ...string) {
+	t.Helper()
+
+	details, _ := problem.Details.([]any)
+	got := make([]string, 0, len(details))
+	for _, detail := range details {
+		field, _ := detail.(map[string]any)
+		got = append(got, fmt.Sprintf("%v:%v", field["field"], field["rule"]))
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected field errors %v, got %v", want, got)
+	}
+}
+
+func TestProblemResponseShape(t *testing.T) {
+	h := testutil.New(t)
+
+	resp := h.Request(http.MethodGet, "/product/999", "", nil)
+	problem := h.ExpectProblem(resp, fiber.StatusNotFound, apperr.CodeNotFound)
+
+	if problem.Type != "about:blank" || problem.Title != "Not Found" || problem.Detail != "Product not found." {
+		t.Fatalf("unexpected problem: %+v", problem)
+	}
+	if problem.Instance != "/product/999" {
+		t.Fatalf("unexpected instance: %s", problem.Instance)
+	}
+	if problem.RequestID == "" || problem.RequestID != resp.Header.Get(fiber.HeaderXRequestID) {
+		t.Fatalf("expected request ID %q to match header %q", problem.RequestID, resp.Header.Get(fiber.HeaderXRequestID))
+	}
+}
+
+func TestMalformedBodyIsBadRequest(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+
+	for _, tc := range []struct {
+		path, token string
+	}{
+		{"/user/register", ""},
+		{"/user/login", ""},
+		{fmt.Sprintf("/order/%d", user.UserID), user.AccessToken},
+	} {
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader("{not json"))
+		req.Header.Set("Content-Type", fiber.MIMEApplicationJSON)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		h.ExpectProblem(h.Do(req), fiber.StatusBadRequest, apperr.CodeInvalidBody)
+	}
+}
+
+func TestMiddlewareErrorsUseProblem(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+
+	h.ExpectProblem(h.Request(http.MethodGet, "/user", "", nil), fiber.StatusUnauthorized, apperr.CodeUnauthorized)
+	h.ExpectProblem(h.Request(http.MethodGet, "/user", "not-a-token", nil), fiber.StatusUnauthorized, apperr.CodeInvalidToken)
+	// header ที่สั้นกว่า "Bearer " หรือใช้ scheme อื่นได้ 401 ไม่ใช่ 500
+	for _, header := range []string{"Bear", "Basic " + user.AccessToken} {
+		req := httptest.NewRequest(http.MethodGet, "/user", nil)
+		req.Header.Set("Authorization", header)
+		h.ExpectProblem(h.Do(req), fiber.StatusUnauthorized, apperr.CodeUnauthorized)
+	}
+	h.ExpectProblem(h.Request(http.MethodGet, "/user", user.AccessToken, nil), fiber.StatusForbidden, apperr.CodeForbidden)
+	h.ExpectProblem(h.Request(http.MethodGet, "/no-such-route", "", nil), fiber.StatusNotFound, apperr.CodeNotFound)
+}
+
+func TestRateLimitUsesProblem(t *testing.T) {
+	cfg := testutil.Config(t)
+	cfg.RateLimit = config.RateLimitConfig{Max: 1, Expiration: time.Minute}
+	h := testutil.NewWithConfig(t, cfg)
+
+	h.Expect(h.Request(http.MethodGet, "/no-such-route", "", nil), fiber.StatusNotFound)
+	h.ExpectProblem(h.Request(http.MethodGet, "/no-such-route", "", nil), fiber.StatusTooManyRequests, apperr.CodeTooManyRequests)
+}
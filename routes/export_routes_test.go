@@ -0,0 +1,148 @@
+package routes_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"go-fiber-test/apperr"
+	m "go-fiber-test/models"
+	"go-fiber-test/testutil"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func exportCSV(t *testing.T, h *testutil.Harness, path, token string) [][]string {
+	t.Helper()
+	resp := h.Expect(h.Request(http.MethodGet, path, token, nil), fiber.StatusOK)
+	if got := resp.Header.Get(fiber.HeaderContentDisposition); !strings.HasPrefix(got, "attachment;") || !strings.Contains(got, ".csv") {
+		t.Fatalf("expected a CSV attachment, got %q", got)
+	}
+	rows, err := csv.NewReader(bytes.NewReader(resp.Body)).ReadAll()
+	if err != nil {
+		t.Fatalf("parsing CSV %q: %v", resp.Body, err)
+	}
+	return rows
+}
+
+// xlsxRows อ่านข้อความของทุก cell ใน worksheet แรกของไฟล์ XLSX
+func xlsxRows(t *testing.T, body []byte) [][]string {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheet, err := archive.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sheet.Close()
+	data, err := io.ReadAll(sheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parsed struct {
+		Rows []struct {
+			Cells []struct {
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("parsing sheet %s: %v", data, err)
+	}
+	rows := make([][]string, len(parsed.Rows))
+	for i, row := range parsed.Rows {
+		for _, cell := range row.Cells {
+			rows[i] = append(rows[i], cell.Value+cell.Inline)
+		}
+	}
+	return rows
+}
+
+func TestExportOrdersFlattensItems(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	bob := h.LoginUser(admin.AccessToken, "bob")
+	h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	h.CreateProduct(admin.AccessToken, "Book", 7, 10)
+	first := h.PlaceOrder(alice, testutil.ItemRequest{Product: "Pen", Amount: 2}, testutil.ItemRequest{Product: "Book", Amount: 1})
+	cancelled := h.PlaceOrder(bob, testutil.ItemRequest{Product: "Pen", Amount: 1})
+	h.Expect(h.Request(http.MethodDelete, fmt.Sprintf("/order/%d", cancelled.ID), bob.AccessToken, nil), fiber.StatusOK)
+
+	rows := exportCSV(t, h, "/order/export?status=pending", admin.AccessToken)
+	if len(rows) != 3 {
+		t.Fatalf("expected header and 2 item rows, got %v", rows)
+	}
+	if rows[0][0] != "OrderID" || rows[0][13] != "Product" {
+		t.Fatalf("unexpected header: %v", rows[0])
+	}
+	orderID := fmt.Sprint(first.ID)
+	if rows[1][0] != orderID || rows[1][13] != "Pen" || rows[1][15] != "2" || rows[1][16] != "10" ||
+		rows[2][0] != orderID || rows[2][13] != "Book" || rows[2][10] != "17" {
+		t.Fatalf("unexpected item rows: %v", rows[1:])
+	}
+
+	rows = exportCSV(t, h, fmt.Sprintf("/order/export?buyer=%d", bob.UserID), admin.AccessToken)
+	if len(rows) != 2 || rows[1][3] != m.OrderCancelled {
+		t.Fatalf("expected only bob's order, got %v", rows)
+	}
+
+	h.Expect(h.Request(http.MethodGet, "/order/export", alice.AccessToken, nil), fiber.StatusForbidden)
+	h.ExpectProblem(h.Request(http.MethodGet, "/order/export?format=pdf", admin.AccessToken, nil), fiber.StatusBadRequest, apperr.CodeValidation)
+}
+
+func TestExportProductsAsXLSX(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	h.CreateProduct(admin.AccessToken, "=HYPERLINK(\"x\")", 5, 0)
+	h.CreateProduct(admin.AccessToken, "Book <hardcover> & more", 70, 3)
+
+	resp := h.Expect(h.Request(http.MethodGet, "/product/export?format=xlsx&in_stock=true", admin.AccessToken, nil), fiber.StatusOK)
+	if got := resp.Header.Get(fiber.HeaderContentType); !strings.Contains(got, "spreadsheetml") {
+		t.Fatalf("expected an XLSX content type, got %q", got)
+	}
+	rows := xlsxRows(t, resp.Body)
+	if len(rows) != 2 || rows[0][3] != "Product_Name" || rows[1][3] != "Book <hardcover> & more" || rows[1][4] != "70" {
+		t.Fatalf("unexpected rows: %v", rows)
+	}
+
+	// ข้อความที่ขึ้นต้นเหมือน formula ถูกป้องกันใน CSV
+	rows = exportCSV(t, h, "/product/export?in_stock=false", admin.AccessToken)
+	if len(rows) != 2 || rows[1][3] != "'=HYPERLINK(\"x\")" {
+		t.Fatalf("expected formula to be escaped, got %v", rows)
+	}
+}
+
+func TestExportUsersStreamsEveryBatch(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+
+	// มากกว่าหนึ่ง batch ของ store
+	users := make([]m.User, 1200)
+	for i := range users {
+		users[i] = m.User{Username: fmt.Sprintf("user%04d", i), Password: "x", Role: "user", Approve: true}
+	}
+	if err := h.DB.CreateInBatches(users, 200).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	rows := exportCSV(t, h, "/user/export?role=user", admin.AccessToken)
+	if len(rows) != 1201 {
+		t.Fatalf("expected header and 1200 users, got %d rows", len(rows))
+	}
+	if strings.Join(rows[0], ",") != "ID,CreatedAt,UpdatedAt,Username,FirstName,LastName,Role,Approve" {
+		t.Fatalf("unexpected header: %v", rows[0])
+	}
+	if rows[1][3] != "user0000" || rows[1200][3] != "user1199" {
+		t.Fatalf("expected users in id order, got %v ... %v", rows[1], rows[1200])
+	}
+}
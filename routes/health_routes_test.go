@@ -0,0 +1,75 @@
+package routes_test
+
+import (
+	"go-fiber-test/config"
+	"go-fiber-test/routes"
+	"go-fiber-test/testutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestHealthz(t *testing.T) {
+	h := testutil.New(t)
+
+	resp := h.Expect(h.Request(http.MethodGet, routes.HealthzPath, "", nil), fiber.StatusOK)
+	if status := resp.Map(t)["status"]; status != "ok" {
+		t.Fatalf("unexpected status: %v", status)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	h := testutil.New(t)
+
+	resp := h.Expect(h.Request(http.MethodGet, routes.ReadyzPath, "", nil), fiber.StatusOK)
+	checks := resp.Map(t)["checks"].(map[string]any)
+	if checks["database"] != "ok" || checks["uploads"] != "ok" {
+		t.Fatalf("unexpected checks: %v", checks)
+	}
+}
+
+func TestReadyzReportsUnwritableUploads(t *testing.T) {
+	cfg := testutil.Config(t)
+	cfg.App.UploadDir = filepath.Join(t.TempDir(), "missing")
+	h := testutil.NewWithConfig(t, cfg)
+
+	resp := h.Expect(h.Request(http.MethodGet, routes.ReadyzPath, "", nil), fiber.StatusServiceUnavailable)
+	checks := resp.Map(t)["checks"].(map[string]any)
+	if checks["database"] != "ok" || checks["uploads"] == "ok" {
+		t.Fatalf("unexpected checks: %v", checks)
+	}
+}
+
+func TestReadyzReportsClosedDatabase(t *testing.T) {
+	h := testutil.New(t)
+
+	sqlDB, err := h.DB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+
+	resp := h.Expect(h.Request(http.MethodGet, routes.ReadyzPath, "", nil), fiber.StatusServiceUnavailable)
+	checks := resp.Map(t)["checks"].(map[string]any)
+	if checks["database"] == "ok" {
+		t.Fatalf("expected database check to fail: %v", checks)
+	}
+}
+
+func TestProbesSkipRateLimiter(t *testing.T) {
+	cfg := testutil.Config(t)
+	cfg.RateLimit = config.RateLimitConfig{Max: 2, Expiration: time.Minute}
+	h := testutil.NewWithConfig(t, cfg)
+
+	for i := 0; i < 5; i++ {
+		h.Expect(h.Request(http.MethodGet, routes.HealthzPath, "", nil), fiber.StatusOK)
+		h.Expect(h.Request(http.MethodGet, routes.ReadyzPath, "", nil), fiber.StatusOK)
+	}
+
+	h.Expect(h.Request(http.MethodGet, "/no-such-route", "", nil), fiber.StatusNotFound)
+	h.Expect(h.Request(http.MethodGet, "/no-such-route", "", nil), fiber.StatusNotFound)
+	h.Expect(h.Request(http.MethodGet, "/no-such-route", "", nil), fiber.StatusTooManyRequests)
+}
@@ -0,0 +1,149 @@
+package routes_test
+
+import (
+	"bytes"
+	"fmt"
+	"go-fiber-test/apperr"
+	md "go-fiber-test/middleware"
+	m "go-fiber-test/models"
+	"go-fiber-test/testutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// placeWithKey สั่งซื้อผ่าน POST /order/:userId พร้อม Idempotency-Key
+func placeWithKey(h *testutil.Harness, tokens testutil.Tokens, key string, items ...testutil.ItemRequest) *testutil.Response {
+	req := h.NewRequest(http.MethodPost, fmt.Sprintf("/order/%d", tokens.UserID), tokens.AccessToken, map[string]any{
+		"Items": items,
+	})
+	req.Header.Set(md.IdempotencyKeyHeader, key)
+	return h.Do(req)
+}
+
+func countOrders(t *testing.T, h *testutil.Harness) int64 {
+	t.Helper()
+	var count int64
+	if err := h.DB.Model(&m.Order{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestIdempotentOrderIsPlacedOnce(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	bob := h.LoginUser(admin.AccessToken, "bob")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	item := testutil.ItemRequest{Product: "Pen", Amount: 2}
+
+	first := h.Expect(placeWithKey(h, alice, "order-1", item), fiber.StatusCreated)
+	retry := h.Expect(placeWithKey(h, alice, "order-1", item), fiber.StatusCreated)
+	if !bytes.Equal(first.Body, retry.Body) {
+		t.Fatalf("expected the first response to be replayed, got %s and %s", first.Body, retry.Body)
+	}
+	if first.Header.Get(md.IdempotentReplayedHeader) != "" || retry.Header.Get(md.IdempotentReplayedHeader) != "true" {
+		t.Fatalf("expected only the retry to be marked as replayed")
+	}
+	if ct := retry.Header.Get(fiber.HeaderContentType); ct != first.Header.Get(fiber.HeaderContentType) {
+		t.Fatalf("expected replayed content type %q, got %q", first.Header.Get(fiber.HeaderContentType), ct)
+	}
+	if count := countOrders(t, h); count != 1 {
+		t.Fatalf("expected a single order, got %d", count)
+	}
+	expectStock(t, h, pen, 8)
+
+	// คีย์เดิมกับ payload อื่นถูกปฏิเสธ
+	h.ExpectProblem(placeWithKey(h, alice, "order-1", testutil.ItemRequest{Product: "Pen", Amount: 3}),
+		fiber.StatusUnprocessableEntity, apperr.CodeIdempotencyMismatch)
+
+	// คีย์แยกตาม user และ request ที่ไม่มีคีย์ทำงานตามปกติ
+	h.Expect(placeWithKey(h, bob, "order-1", item), fiber.StatusCreated)
+	h.PlaceOrder(alice, item)
+	if count := countOrders(t, h); count != 3 {
+		t.Fatalf("expected 3 orders, got %d", count)
+	}
+	expectStock(t, h, pen, 4)
+}
+
+func TestFailedIdempotentRequestCanBeRetried(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 1)
+	item := testutil.ItemRequest{Product: "Pen", Amount: 3}
+
+	h.ExpectProblem(placeWithKey(h, alice, "order-1", item), fiber.StatusConflict, apperr.CodeInsufficientStock)
+
+	h.Expect(h.Request(http.MethodPost, fmt.Sprintf("/product/%d/stock", pen.ID), admin.AccessToken, map[string]any{
+		"Delta": 5, "Reason": m.StockRestock,
+	}), fiber.StatusCreated)
+
+	retry := h.Expect(placeWithKey(h, alice, "order-1", item), fiber.StatusCreated)
+	if retry.Header.Get(md.IdempotentReplayedHeader) != "" {
+		t.Fatal("a failed request must not be replayed")
+	}
+	expectStock(t, h, pen, 3)
+}
+
+func TestIdempotencyKeyExpires(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	item := testutil.ItemRequest{Product: "Pen", Amount: 1}
+
+	h.Expect(placeWithKey(h, alice, "order-1", item), fiber.StatusCreated)
+
+	// เลยเวลาเก็บแล้วคีย์เดิมถูกนับเป็น request ใหม่
+	if err := h.DB.Model(&m.IdempotencyKey{}).Where("1 = 1").Update("expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	retry := h.Expect(placeWithKey(h, alice, "order-1", testutil.ItemRequest{Product: "Pen", Amount: 2}), fiber.StatusCreated)
+	if retry.Header.Get(md.IdempotentReplayedHeader) != "" {
+		t.Fatal("an expired key must not be replayed")
+	}
+	if count := countOrders(t, h); count != 2 {
+		t.Fatalf("expected 2 orders, got %d", count)
+	}
+
+	deleted, err := h.Store.Idempotency().DeleteExpired(time.Now().Add(h.Config.Idempotency.TTL + time.Second))
+	if err != nil || deleted != 1 {
+		t.Fatalf("expected the remaining key to be purged, got %d (%v)", deleted, err)
+	}
+}
+
+func TestIdempotentProductCreation(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+
+	create := func(name string) *testutil.Response {
+		// สร้าง form ใหม่ทุกครั้งเหมือน client ที่ส่งซ้ำ ซึ่ง boundary ของ multipart จะไม่เหมือนเดิม
+		req := h.NewForm(http.MethodPost, "/product", admin.AccessToken, map[string]string{
+			"Product_Name": name,
+			"Price":        "5",
+			"Amount":       "10",
+		}, testutil.PNG("pen.png"))
+		req.Header.Set(md.IdempotencyKeyHeader, "product-1")
+		return h.Do(req)
+	}
+
+	first := h.Expect(create("Pen"), fiber.StatusCreated)
+	retry := h.Expect(create("Pen"), fiber.StatusCreated)
+	if !bytes.Equal(first.Body, retry.Body) || retry.Header.Get(md.IdempotentReplayedHeader) != "true" {
+		t.Fatalf("expected the first product response to be replayed, got %s", retry.Body)
+	}
+
+	var count int64
+	if err := h.DB.Model(&m.Product{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("expected a single product, got %d", count)
+	}
+
+	h.ExpectProblem(create("Pencil"), fiber.StatusUnprocessableEntity, apperr.CodeIdempotencyMismatch)
+}
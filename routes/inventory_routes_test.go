@@ -0,0 +1,150 @@
+package routes_test
+
+import (
+	"fmt"
+	"go-fiber-test/apperr"
+	"go-fiber-test/dto"
+	m "go-fiber-test/models"
+	"go-fiber-test/testutil"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// stockHistory โหลดประวัติสต็อกของสินค้าเรียงตามลำดับที่เกิดขึ้น
+func stockHistory(t *testing.T, h *testutil.Harness, token string, productID uint) []dto.StockMovementResponse {
+	t.Helper()
+	var out struct {
+		Data []dto.StockMovementResponse `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodGet, fmt.Sprintf("/product/%d/stock", productID), token, nil), fiber.StatusOK).JSON(t, &out)
+	return out.Data
+}
+
+func reconcile(t *testing.T, h *testutil.Harness, method, token string, productID uint) dto.StockReconcileResponse {
+	t.Helper()
+	var out struct {
+		Data dto.StockReconcileResponse `json:"data"`
+	}
+	h.Expect(h.Request(method, fmt.Sprintf("/product/%d/stock/reconcile", productID), token, nil), fiber.StatusOK).JSON(t, &out)
+	return out.Data
+}
+
+func TestStockLedgerRecordsOrderLifecycle(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+
+	order := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 3})
+	// สินค้าที่กันไว้ยังไม่ถูกบันทึกใน ledger จนกว่า order จะถูกยืนยัน
+	if history := stockHistory(t, h, admin.AccessToken, pen.ID); len(history) != 1 {
+		t.Fatalf("expected only the initial movement before confirmation, got %+v", history)
+	}
+	h.Expect(h.Request(http.MethodPost, fmt.Sprintf("/order/%d/confirm", order.ID), user.AccessToken, nil), fiber.StatusOK)
+	h.Expect(h.Request(http.MethodPut, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{ProductID: pen.ID, Amount: 5}},
+	}), fiber.StatusOK)
+	h.Expect(h.Request(http.MethodDelete, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, nil), fiber.StatusOK)
+
+	history := stockHistory(t, h, admin.AccessToken, pen.ID)
+	want := []struct {
+		reason         string
+		delta, balance int
+	}{
+		{m.StockInitial, 10, 10},
+		{m.StockOrderPlaced, -3, 7},
+		{m.StockOrderEdited, -2, 5},
+		{m.StockOrderCancelled, 5, 10},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("expected %d movements, got %+v", len(want), history)
+	}
+	reference := m.OrderReference(order.ID)
+	for i, w := range want {
+		move := history[i]
+		if move.Reason != w.reason || move.Delta != w.delta || move.Balance != w.balance {
+			t.Fatalf("movement %d: expected %+v, got %+v", i, w, move)
+		}
+		if i > 0 && move.Reference != reference {
+			t.Fatalf("movement %d: expected reference %s, got %q", i, reference, move.Reference)
+		}
+		if move.ActorID == nil {
+			t.Fatalf("movement %d: expected an actor", i)
+		}
+	}
+	if buyer := fmt.Sprint(*history[1].ActorID); buyer != order.Buyer {
+		t.Fatalf("expected order movement to be made by buyer %s, got %s", order.Buyer, buyer)
+	}
+
+	if got := reconcile(t, h, http.MethodGet, admin.AccessToken, pen.ID); got.Amount != 10 || got.Ledger != 10 || got.Difference != 0 {
+		t.Fatalf("expected stock to match the ledger, got %+v", got)
+	}
+}
+
+func TestManualStockAdjustments(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	path := fmt.Sprintf("/product/%d/stock", pen.ID)
+
+	var out struct {
+		Data dto.StockMovementResponse `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodPost, path, admin.AccessToken, map[string]any{
+		"Delta": 5, "Reason": m.StockRestock, "Note": "PO-7",
+	}), fiber.StatusCreated).JSON(t, &out)
+	if out.Data.Balance != 15 || out.Data.Reason != m.StockRestock || out.Data.Note != "PO-7" {
+		t.Fatalf("unexpected movement: %+v", out.Data)
+	}
+
+	// ลดเกินจำนวนที่มีไม่ได้ และ restock ต้องเป็นการเพิ่มเสมอ
+	h.ExpectProblem(h.Request(http.MethodPost, path, admin.AccessToken, map[string]any{
+		"Delta": -20, "Reason": m.StockAdjustment,
+	}), fiber.StatusConflict, apperr.CodeInsufficientStock)
+	h.ExpectProblem(h.Request(http.MethodPost, path, admin.AccessToken, map[string]any{
+		"Delta": -1, "Reason": m.StockRestock,
+	}), fiber.StatusBadRequest, apperr.CodeValidation)
+	h.ExpectProblem(h.Request(http.MethodPost, path, admin.AccessToken, map[string]any{
+		"Delta": 0, "Reason": m.StockAdjustment,
+	}), fiber.StatusBadRequest, apperr.CodeValidation)
+	h.Expect(h.Request(http.MethodPost, path, user.AccessToken, map[string]any{
+		"Delta": 1, "Reason": m.StockRestock,
+	}), fiber.StatusForbidden)
+	h.Expect(h.Request(http.MethodGet, path, user.AccessToken, nil), fiber.StatusForbidden)
+
+	// การแก้ Amount ผ่าน PUT /product ถูกบันทึกเป็น adjustment ตามส่วนต่าง
+	h.Expect(h.Form(http.MethodPut, fmt.Sprintf("/product/%d", pen.ID), admin.AccessToken, map[string]string{
+		"Amount": "12",
+	}), fiber.StatusOK)
+	expectStock(t, h, pen, 12)
+
+	history := stockHistory(t, h, admin.AccessToken, pen.ID)
+	last := history[len(history)-1]
+	if len(history) != 3 || last.Reason != m.StockAdjustment || last.Delta != -3 || last.Balance != 12 {
+		t.Fatalf("expected product update to be an adjustment of -3, got %+v", history)
+	}
+}
+
+func TestReconcileStockWithLedger(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+
+	// แก้ amount ตรง ๆ โดยไม่ผ่าน ledger
+	if err := h.DB.Model(&m.Product{}).Where("id = ?", pen.ID).Update("amount", 4).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if got := reconcile(t, h, http.MethodGet, admin.AccessToken, pen.ID); got.Amount != 4 || got.Ledger != 10 || got.Difference != -6 {
+		t.Fatalf("expected a difference of -6, got %+v", got)
+	}
+	if got := reconcile(t, h, http.MethodPost, admin.AccessToken, pen.ID); got.Amount != 10 || got.Difference != 0 {
+		t.Fatalf("expected stock to be reconciled, got %+v", got)
+	}
+	expectStock(t, h, pen, 10)
+
+	h.Expect(h.Request(http.MethodGet, "/product/999/stock/reconcile", admin.AccessToken, nil), fiber.StatusNotFound)
+}
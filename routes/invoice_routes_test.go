@@ -0,0 +1,132 @@
+package routes_test
+
+import (
+	"bytes"
+	"fmt"
+	"go-fiber-test/apperr"
+	m "go-fiber-test/models"
+	"go-fiber-test/payment"
+	"go-fiber-test/testutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// paidOrder สั่งซื้อและชำระเงินผ่าน webhook ใบแจ้งหนี้ออกให้เฉพาะ order ที่ชำระแล้ว
+func paidOrder(h *testutil.Harness, tokens testutil.Tokens, items ...testutil.ItemRequest) m.Order {
+	order := h.PlaceOrder(tokens, items...)
+	pay := h.Pay(tokens, order.ID)
+	h.Expect(h.Webhook(payment.Event{ID: "evt_" + pay.ProviderRef, Type: payment.EventSucceeded, IntentID: pay.ProviderRef}), fiber.StatusOK)
+	return order
+}
+
+func TestInvoicePDFHasSequentialNumber(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	first := paidOrder(h, user, testutil.ItemRequest{Product: "Pen", Amount: 1})
+	second := paidOrder(h, user, testutil.ItemRequest{Product: "Pen", Amount: 2})
+
+	// ใบที่สองถูกขอก่อน จึงได้เลขแรก
+	expectInvoice := func(orderID uint, number string) {
+		t.Helper()
+		resp := h.Expect(h.Request(http.MethodGet, fmt.Sprintf("/order/%d/invoice", orderID), user.AccessToken, nil), fiber.StatusOK)
+		if got := resp.Header.Get(fiber.HeaderContentType); got != "application/pdf" {
+			t.Fatalf("expected a PDF, got %q", got)
+		}
+		if !bytes.HasPrefix(resp.Body, []byte("%PDF-")) {
+			t.Fatalf("expected a PDF body, got %q", resp.Body[:min(len(resp.Body), 20)])
+		}
+		if got := resp.Header.Get(fiber.HeaderContentDisposition); !strings.Contains(got, number+".pdf") {
+			t.Fatalf("expected invoice %s, got %q", number, got)
+		}
+	}
+	expectInvoice(second.ID, "INV-000001")
+	expectInvoice(first.ID, "INV-000002")
+	// ขอซ้ำได้เลขเดิม
+	expectInvoice(second.ID, "INV-000001")
+}
+
+func TestInvoiceNumbersHaveNoGaps(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	pending := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 1})
+	first := paidOrder(h, user, testutil.ItemRequest{Product: "Pen", Amount: 1})
+	second := paidOrder(h, user, testutil.ItemRequest{Product: "Pen", Amount: 1})
+
+	// order ที่ยังไม่ชำระเงินไม่ได้ใบแจ้งหนี้และไม่ใช้เลขที่
+	h.ExpectProblem(h.Request(http.MethodGet, fmt.Sprintf("/order/%d/invoice", pending.ID), user.AccessToken, nil),
+		fiber.StatusConflict, apperr.CodeOrderStatus)
+
+	// การเปิดใบเดิมซ้ำต้องไม่ใช้เลขที่ไป ใบถัดไปจึงได้เลขต่อกัน
+	for _, orderID := range []uint{first.ID, first.ID, second.ID} {
+		h.Expect(h.Request(http.MethodGet, fmt.Sprintf("/order/%d/invoice", orderID), user.AccessToken, nil), fiber.StatusOK)
+	}
+	var invoices []m.Invoice
+	if err := h.DB.Order("id").Find(&invoices).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(invoices) != 2 || invoices[0].Number() != "INV-000001" || invoices[1].Number() != "INV-000002" ||
+		invoices[0].OrderID != first.ID || invoices[1].OrderID != second.ID {
+		t.Fatalf("expected INV-000001 and INV-000002 for the two orders, got %+v", invoices)
+	}
+}
+
+func TestInvoiceHTML(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	h.CreateProduct(admin.AccessToken, "Fountain Pen", 1250, 10)
+	createCoupon(t, h, admin.AccessToken, map[string]any{"Code": "SAVE100", "Type": "fixed", "Value": 100})
+	var order struct {
+		Data struct{ ID uint } `json:"data"`
+	}
+	h.Expect(orderWithCoupon(h, user, "SAVE100", testutil.ItemRequest{Product: "Fountain Pen", Amount: 2}), fiber.StatusCreated).JSON(t, &order)
+	pay := h.Pay(user, order.Data.ID)
+	h.Expect(h.Webhook(payment.Event{ID: "evt_1", Type: payment.EventSucceeded, IntentID: pay.ProviderRef}), fiber.StatusOK)
+
+	path := fmt.Sprintf("/order/%d/invoice?format=html", order.Data.ID)
+	expectHTML := func() {
+		t.Helper()
+		resp := h.Expect(h.Request(http.MethodGet, path, user.AccessToken, nil), fiber.StatusOK)
+		if got := resp.Header.Get(fiber.HeaderContentType); !strings.HasPrefix(got, fiber.MIMETextHTML) {
+			t.Fatalf("expected HTML, got %q", got)
+		}
+		body := string(resp.Body)
+		for _, want := range []string{"INV-000001", "alice", "Fountain Pen", "1,250 THB", "2,500 THB", "Discount (SAVE100)", "-100 THB", "2,400 THB"} {
+			if !strings.Contains(body, want) {
+				t.Fatalf("expected invoice to contain %q:\n%s", want, body)
+			}
+		}
+	}
+	expectHTML()
+
+	// ใบแจ้งหนี้แสดงยอดที่บันทึกไว้ตอนออก แม้ order จะถูกแก้หรือคืนเงินภายหลัง
+	if err := h.DB.Model(&m.Item{}).Where("order_id = ?", order.Data.ID).Updates(map[string]any{"product": "Pencil", "amount": 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	h.Expect(h.Request(http.MethodPut, fmt.Sprintf("/order/%d/status", order.Data.ID), admin.AccessToken, map[string]string{
+		"Status": m.OrderRefunded,
+	}), fiber.StatusOK)
+	expectHTML()
+}
+
+func TestInvoiceIsRestrictedToOwnerAndAdmin(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	bob := h.LoginUser(admin.AccessToken, "bob")
+	h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	order := paidOrder(h, alice, testutil.ItemRequest{Product: "Pen", Amount: 1})
+	path := fmt.Sprintf("/order/%d/invoice", order.ID)
+
+	h.Expect(h.Request(http.MethodGet, path, bob.AccessToken, nil), fiber.StatusUnauthorized)
+	h.Expect(h.Request(http.MethodGet, path, admin.AccessToken, nil), fiber.StatusOK)
+	h.Expect(h.Request(http.MethodGet, "/order/999/invoice", admin.AccessToken, nil), fiber.StatusNotFound)
+	h.ExpectProblem(h.Request(http.MethodGet, path+"?format=doc", alice.AccessToken, nil), fiber.StatusBadRequest, apperr.CodeValidation)
+}
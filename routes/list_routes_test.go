@@ -0,0 +1,214 @@
+package routes_test
+
+import (
+	"fmt"
+	"go-fiber-test/apperr"
+	m "go-fiber-test/models"
+	"go-fiber-test/testutil"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// listPage คือ response ของ list ที่แบ่งหน้า
+type listPage[T any] struct {
+	Data []T `json:"data"`
+	Meta struct {
+		Total      int64   `json:"total"`
+		Limit      int     `json:"limit"`
+		Offset     *int    `json:"offset"`
+		NextCursor *string `json:"next_cursor"`
+		PrevCursor *string `json:"prev_cursor"`
+	} `json:"meta"`
+	Links struct {
+		Self string  `json:"self"`
+		Next *string `json:"next"`
+		Prev *string `json:"prev"`
+	} `json:"links"`
+}
+
+func getList[T any](t *testing.T, h *testutil.Harness, path, token string) listPage[T] {
+	t.Helper()
+	var page listPage[T]
+	h.Expect(h.Request(http.MethodGet, path, token, nil), fiber.StatusOK).JSON(t, &page)
+	return page
+}
+
+func productNames(products []m.Product) []string {
+	names := make([]string, len(products))
+	for i, p := range products {
+		names[i] = p.Product_Name
+	}
+	return names
+}
+
+func TestGetProductsOffsetPagination(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	for i := 1; i <= 5; i++ {
+		h.CreateProduct(admin.AccessToken, fmt.Sprintf("P%d", i), i*10, i)
+	}
+
+	first := getList[m.Product](t, h, "/product?limit=2", "")
+	if got := productNames(first.Data); !slices.Equal(got, []string{"P1", "P2"}) {
+		t.Fatalf("unexpected first page: %v", got)
+	}
+	if first.Meta.Total != 5 || first.Meta.Limit != 2 || first.Links.Prev != nil || first.Links.Next == nil {
+		t.Fatalf("unexpected first page meta: %+v %+v", first.Meta, first.Links)
+	}
+
+	second := getList[m.Product](t, h, *first.Links.Next, "")
+	if got := productNames(second.Data); !slices.Equal(got, []string{"P3", "P4"}) {
+		t.Fatalf("unexpected second page: %v", got)
+	}
+
+	last := getList[m.Product](t, h, *second.Links.Next, "")
+	if got := productNames(last.Data); !slices.Equal(got, []string{"P5"}) || last.Links.Next != nil {
+		t.Fatalf("unexpected last page: %v next=%v", got, last.Links.Next)
+	}
+
+	back := getList[m.Product](t, h, *last.Links.Prev, "")
+	if got := productNames(back.Data); !slices.Equal(got, []string{"P3", "P4"}) {
+		t.Fatalf("unexpected page after prev: %v", got)
+	}
+}
+
+func TestGetProductsCursorPagination(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	// ราคาซ้ำกันเพื่อให้ต้องใช้ id ตัดสินลำดับ
+	prices := map[string]int{"A": 30, "B": 10, "C": 30, "D": 20, "E": 10}
+	for _, name := range []string{"A", "B", "C", "D", "E"} {
+		h.CreateProduct(admin.AccessToken, name, prices[name], 1)
+	}
+
+	for sort, want := range map[string][]string{
+		"-price":      {"C", "A", "D", "E", "B"},
+		"price":       {"B", "E", "D", "A", "C"},
+		"-created_at": {"E", "D", "C", "B", "A"},
+	} {
+		var got []string
+		var pages []listPage[m.Product]
+		path := "/product?limit=2&sort=" + url.QueryEscape(sort)
+		for {
+			page := getList[m.Product](t, h, path, "")
+			pages = append(pages, page)
+			got = append(got, productNames(page.Data)...)
+			if page.Meta.NextCursor == nil {
+				break
+			}
+			path = "/product?limit=2&sort=" + url.QueryEscape(sort) + "&cursor=" + *page.Meta.NextCursor
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("sort %s: expected %v, got %v", sort, want, got)
+		}
+
+		// ย้อนกลับจากหน้าสุดท้ายด้วย prev link ต้องได้หน้าก่อนหน้าเดิม
+		last := pages[len(pages)-1]
+		prev := getList[m.Product](t, h, *last.Links.Prev, "")
+		if !slices.Equal(productNames(prev.Data), productNames(pages[len(pages)-2].Data)) {
+			t.Fatalf("sort %s: prev page %v, expected %v", sort, productNames(prev.Data), productNames(pages[len(pages)-2].Data))
+		}
+	}
+}
+
+func TestGetProductsFilters(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	h.CreateProduct(admin.AccessToken, "Cheap", 5, 0)
+	h.CreateProduct(admin.AccessToken, "Mid", 50, 3)
+	h.CreateProduct(admin.AccessToken, "Pricey", 500, 1)
+
+	for query, want := range map[string][]string{
+		"min_price=10":              {"Mid", "Pricey"},
+		"max_price=50":              {"Cheap", "Mid"},
+		"min_price=10&max_price=60": {"Mid"},
+		"in_stock=true":             {"Mid", "Pricey"},
+		"in_stock=false":            {"Cheap"},
+		"created_to=2000-01-01":     {},
+		"created_from=" + time.Now().Format("2006-01-02"): {"Cheap", "Mid", "Pricey"},
+	} {
+		page := getList[m.Product](t, h, "/product?"+query, "")
+		if got := productNames(page.Data); !slices.Equal(got, want) || page.Meta.Total != int64(len(want)) {
+			t.Fatalf("%s: expected %v, got %v (total %d)", query, want, got, page.Meta.Total)
+		}
+	}
+}
+
+func TestListQueryValidation(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	h.CreateProduct(admin.AccessToken, "Pen", 5, 1)
+	h.CreateProduct(admin.AccessToken, "Ink", 5, 1)
+
+	for query, field := range map[string]string{
+		"limit=500":             "limit:max",
+		"offset=-1":             "offset:min",
+		"sort=-password":        "sort:sort",
+		"min_price=-5":          "min_price:gte",
+		"created_from=tomorrow": "created_from:timestamp",
+	} {
+		problem := h.ExpectProblem(h.Request(http.MethodGet, "/product?"+query, "", nil), fiber.StatusBadRequest, apperr.CodeValidation)
+		expectFieldErrors(t, problem, field)
+	}
+
+	h.ExpectProblem(h.Request(http.MethodGet, "/product?cursor=garbage", "", nil), fiber.StatusBadRequest, apperr.CodeValidation)
+
+	// cursor ผูกกับ sort ที่ใช้สร้าง
+	page := getList[m.Product](t, h, "/product?limit=1&sort=price", "")
+	h.ExpectProblem(h.Request(http.MethodGet, "/product?limit=1&sort=-price&cursor="+*page.Meta.NextCursor, "", nil), fiber.StatusBadRequest, apperr.CodeValidation)
+}
+
+func TestGetOrdersFilters(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	bob := h.LoginUser(admin.AccessToken, "bob")
+	h.CreateProduct(admin.AccessToken, "Pen", 10, 100)
+	h.PlaceOrder(alice, testutil.ItemRequest{Product: "Pen", Amount: 1})
+	h.PlaceOrder(bob, testutil.ItemRequest{Product: "Pen", Amount: 5})
+	h.PlaceOrder(alice, testutil.ItemRequest{Product: "Pen", Amount: 3})
+
+	page := getList[m.Order](t, h, fmt.Sprintf("/order?buyer=%d&sort=-total_price", alice.UserID), admin.AccessToken)
+	if len(page.Data) != 2 || page.Data[0].Total_Price != 30 || page.Data[1].Total_Price != 10 || page.Meta.Total != 2 {
+		t.Fatalf("unexpected orders for alice: %+v", page.Data)
+	}
+
+	page = getList[m.Order](t, h, "/order?created_to=2000-01-01", admin.AccessToken)
+	if len(page.Data) != 0 || page.Meta.Total != 0 {
+		t.Fatalf("expected no orders, got %+v", page.Data)
+	}
+}
+
+func TestGetUsersFilters(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	h.LoginUser(admin.AccessToken, "alice")
+	h.Register("bob", testutil.DefaultPassword)
+
+	usernames := func(query string) []string {
+		page := getList[m.User](t, h, "/user?"+query, admin.AccessToken)
+		names := make([]string, len(page.Data))
+		for i, u := range page.Data {
+			names[i] = u.Username
+		}
+		return names
+	}
+
+	if got := usernames("role=admin"); !slices.Equal(got, []string{"admin"}) {
+		t.Fatalf("role=admin: %v", got)
+	}
+	if got := usernames("approved=false"); !slices.Equal(got, []string{"bob"}) {
+		t.Fatalf("approved=false: %v", got)
+	}
+	if got := usernames("role=user&approved=true&sort=-username"); !slices.Equal(got, []string{"alice"}) {
+		t.Fatalf("role=user&approved=true: %v", got)
+	}
+	if got := usernames("sort=-username"); !slices.Equal(got, []string{"bob", "alice", "admin"}) {
+		t.Fatalf("sort=-username: %v", got)
+	}
+}
@@ -0,0 +1,630 @@
+package routes_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-fiber-test/apperr"
+	"go-fiber-test/migrations"
+	m "go-fiber-test/models"
+	"go-fiber-test/store"
+	"go-fiber-test/testutil"
+	"net/http"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// expectStock ตรวจจำนวนที่ยังขายได้ของสินค้า ซึ่งลดลงทันทีที่ order กันสินค้าไว้
+func expectStock(t *testing.T, h *testutil.Harness, product m.Product, want int) {
+	t.Helper()
+	if got := h.Product(product.ID).Available(); got != want {
+		t.Fatalf("expected %s stock %d, got %d", product.Product_Name, want, got)
+	}
+}
+
+func TestAddOrderDeductsStock(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 15, 10)
+	book := h.CreateProduct(admin.AccessToken, "Book", 120, 3)
+
+	order := h.PlaceOrder(user,
+		testutil.ItemRequest{Product: "Pen", Amount: 4},
+		testutil.ItemRequest{Product: "Book", Amount: 1},
+	)
+
+	if order.Total_Price != 4*15+120 {
+		t.Fatalf("expected total %d, got %d", 4*15+120, order.Total_Price)
+	}
+	if order.Buyer != fmt.Sprint(user.UserID) || len(order.Items) != 2 {
+		t.Fatalf("unexpected order: %+v", order)
+	}
+	expectStock(t, h, pen, 6)
+	expectStock(t, h, book, 2)
+}
+
+func TestAddOrderRejectsInsufficientStock(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 15, 2)
+
+	h.ExpectProblem(h.Request(http.MethodPost, fmt.Sprintf("/order/%d", user.UserID), user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 3}},
+	}), fiber.StatusConflict, apperr.CodeInsufficientStock)
+
+	h.ExpectProblem(h.Request(http.MethodPost, fmt.Sprintf("/order/%d", user.UserID), user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pencil", Amount: 1}},
+	}), fiber.StatusNotFound, apperr.CodeNotFound)
+
+	expectStock(t, h, pen, 2)
+}
+
+func TestAddOrderValidatesItems(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 15, 5)
+	path := fmt.Sprintf("/order/%d", user.UserID)
+
+	problem := h.ExpectProblem(h.Request(http.MethodPost, path, user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{},
+	}), fiber.StatusBadRequest, apperr.CodeValidation)
+	expectFieldErrors(t, problem, "Items:min")
+
+	// จำนวนติดลบจะทำให้สต็อกเพิ่มขึ้น จึงต้องถูกปฏิเสธ
+	problem = h.ExpectProblem(h.Request(http.MethodPost, path, user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pen", Amount: -3}, {Product: "", Amount: 0}},
+	}), fiber.StatusBadRequest, apperr.CodeValidation)
+	expectFieldErrors(t, problem, "Items[0].Amount:gt", "Items[1].Product:required_without", "Items[1].Amount:gt")
+
+	problem = h.ExpectProblem(h.Request(http.MethodPost, path, user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 1}, {Product: "Pen", Amount: 1}},
+	}), fiber.StatusBadRequest, apperr.CodeValidation)
+	expectFieldErrors(t, problem, "Items:unique")
+
+	// ระบุสินค้าตัวเดียวกันด้วย ID และชื่อก็ถือว่าซ้ำ
+	problem = h.ExpectProblem(h.Request(http.MethodPost, path, user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{ProductID: pen.ID, Amount: 1}, {Product: "Pen", Amount: 1}},
+	}), fiber.StatusBadRequest, apperr.CodeValidation)
+	expectFieldErrors(t, problem, "Items:unique")
+
+	expectStock(t, h, pen, 5)
+}
+
+func TestOrderItemsKeepPurchaseSnapshot(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	ink := h.CreateProduct(admin.AccessToken, "Ink", 3, 10)
+
+	order := h.PlaceOrder(user, testutil.ItemRequest{ProductID: pen.ID, Amount: 2})
+	item := order.Items[0]
+	if item.ProductID != pen.ID || item.Product != "Pen" || item.Unit_Price != 5 || item.Line_Total != 10 || order.Total_Price != 10 {
+		t.Fatalf("unexpected snapshot: %+v total %d", item, order.Total_Price)
+	}
+
+	// เปลี่ยนชื่อและราคาสินค้าหลังสั่งซื้อ order เดิมต้องยังแก้ไขได้และใช้ราคาเดิม
+	h.Expect(h.Form(http.MethodPut, fmt.Sprintf("/product/%d", pen.ID), admin.AccessToken, map[string]string{
+		"Product_Name": "Fountain Pen",
+		"Price":        "9",
+	}), fiber.StatusOK)
+
+	var out struct {
+		Data m.Order `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodPut, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{
+			{ProductID: pen.ID, Amount: 3},
+			{Product: "Ink", Amount: 1},
+		},
+	}), fiber.StatusOK).JSON(t, &out)
+
+	if want := 3*5 + 1*3; out.Data.Total_Price != want {
+		t.Fatalf("expected total %d, got %d", want, out.Data.Total_Price)
+	}
+	for _, item := range out.Data.Items {
+		if item.ProductID == pen.ID && (item.Product != "Pen" || item.Unit_Price != 5 || item.Line_Total != 15) {
+			t.Fatalf("expected the original pen snapshot, got %+v", item)
+		}
+	}
+	expectStock(t, h, pen, 7)
+	expectStock(t, h, ink, 9)
+
+	h.Expect(h.Request(http.MethodDelete, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, nil), fiber.StatusOK)
+	expectStock(t, h, pen, 10)
+	expectStock(t, h, ink, 10)
+}
+
+func TestRemoveOrderRestocksDeletedProduct(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	order := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 4})
+
+	h.Expect(h.Request(http.MethodDelete, fmt.Sprintf("/product/%d", pen.ID), admin.AccessToken, nil), fiber.StatusCreated)
+	h.Expect(h.Request(http.MethodDelete, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, nil), fiber.StatusOK)
+
+	h.Expect(h.Request(http.MethodPut, fmt.Sprintf("/product/restore/%d", pen.ID), admin.AccessToken, nil), fiber.StatusCreated)
+	expectStock(t, h, pen, 10)
+}
+
+func TestMigrationBackfillsItemSnapshots(t *testing.T) {
+	h := testutil.New(t)
+	// ย้อนกลับไปเหลือแค่ schema แรก
+	if _, err := migrations.Down(h.DB, len(migrations.All())-1); err != nil {
+		t.Fatal(err)
+	}
+
+	// ข้อมูลแบบเดิมที่ item อ้างอิงสินค้าด้วยชื่ออย่างเดียว
+	now := time.Now()
+	exec := func(sql string, args ...any) {
+		t.Helper()
+		if err := h.DB.Exec(sql, args...).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	exec("INSERT INTO products (id, created_at, updated_at, product_name, price, amount) VALUES (1, ?, ?, 'Pen', 5, 10)", now, now)
+	// ชื่อซ้ำ: ใช้ตัวที่ยังไม่ถูกลบก่อน แล้วจึงเป็นตัวที่ใหม่ที่สุด
+	exec("INSERT INTO products (id, created_at, updated_at, deleted_at, product_name, price, amount) VALUES (2, ?, ?, ?, 'Pen', 7, 0)", now, now, now)
+	exec("INSERT INTO products (id, created_at, updated_at, product_name, price, amount) VALUES (3, ?, ?, 'Book', 3, 5), (4, ?, ?, 'Book', 4, 5)", now, now, now, now)
+	exec("INSERT INTO orders (id, created_at, updated_at, buyer, total_price) VALUES (1, ?, ?, '1', 10)", now, now)
+	exec("INSERT INTO items (id, created_at, updated_at, product, amount, order_id) VALUES (1, ?, ?, 'Pen', 2, 1), (2, ?, ?, 'Gone', 1, 1), (3, ?, ?, 'Book', 2, 1)",
+		now, now, now, now, now, now)
+
+	if _, err := migrations.Up(h.DB); err != nil {
+		t.Fatal(err)
+	}
+
+	var items []m.Item
+	if err := h.DB.Order("id").Find(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(items))
+	}
+	if items[0].ProductID != 1 || items[0].Unit_Price != 5 || items[0].Line_Total != 10 {
+		t.Fatalf("expected Pen to be linked with its price, got %+v", items[0])
+	}
+	if items[1].ProductID != 0 || items[1].Product != "Gone" || items[1].Line_Total != 0 {
+		t.Fatalf("expected unknown product to stay unlinked, got %+v", items[1])
+	}
+	if items[2].ProductID != 4 || items[2].Unit_Price != 4 || items[2].Line_Total != 8 {
+		t.Fatalf("expected Book to be linked to the newest product, got %+v", items[2])
+	}
+
+	order := loadOrder(t, h, 1)
+	if order.Subtotal != 10 || order.Shipping != 0 || order.Tax != 0 || order.Total_Price != 10 {
+		t.Fatalf("expected order totals to be backfilled, got %+v", order)
+	}
+
+	ledger, err := h.Store.Products().LedgerBalance(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ledger != 10 {
+		t.Fatalf("expected an opening balance of 10 in the ledger, got %d", ledger)
+	}
+}
+
+func TestOrdersAreScopedToOwner(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	bob := h.LoginUser(admin.AccessToken, "bob")
+	h.CreateProduct(admin.AccessToken, "Pen", 15, 10)
+	order := h.PlaceOrder(alice, testutil.ItemRequest{Product: "Pen", Amount: 1})
+
+	var own struct {
+		Data []m.Order `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodGet, fmt.Sprintf("/order/%d", alice.UserID), alice.AccessToken, nil), fiber.StatusOK).JSON(t, &own)
+	if len(own.Data) != 1 || own.Data[0].ID != order.ID {
+		t.Fatalf("expected alice to see her order, got %+v", own.Data)
+	}
+
+	h.Expect(h.Request(http.MethodGet, fmt.Sprintf("/order/%d", alice.UserID), bob.AccessToken, nil), fiber.StatusUnauthorized)
+	h.Expect(h.Request(http.MethodPost, fmt.Sprintf("/order/%d", alice.UserID), bob.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 1}},
+	}), fiber.StatusUnauthorized)
+	h.Expect(h.Request(http.MethodPut, fmt.Sprintf("/order/%d", order.ID), bob.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 5}},
+	}), fiber.StatusUnauthorized)
+	h.Expect(h.Request(http.MethodDelete, fmt.Sprintf("/order/%d", order.ID), bob.AccessToken, nil), fiber.StatusUnauthorized)
+}
+
+func TestGetOrdersRequiresAdmin(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	bob := h.LoginUser(admin.AccessToken, "bob")
+	h.CreateProduct(admin.AccessToken, "Pen", 15, 10)
+	h.PlaceOrder(alice, testutil.ItemRequest{Product: "Pen", Amount: 1})
+	h.PlaceOrder(bob, testutil.ItemRequest{Product: "Pen", Amount: 2})
+
+	h.Expect(h.Request(http.MethodGet, "/order", alice.AccessToken, nil), fiber.StatusForbidden)
+
+	var all struct {
+		Data []m.Order `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodGet, "/order", admin.AccessToken, nil), fiber.StatusOK).JSON(t, &all)
+	if len(all.Data) != 2 {
+		t.Fatalf("expected 2 orders, got %d", len(all.Data))
+	}
+}
+
+func TestUpdateOrderStockArithmetic(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	book := h.CreateProduct(admin.AccessToken, "Book", 7, 10)
+	ink := h.CreateProduct(admin.AccessToken, "Ink", 3, 10)
+
+	order := h.PlaceOrder(user,
+		testutil.ItemRequest{Product: "Pen", Amount: 2},
+		testutil.ItemRequest{Product: "Book", Amount: 3},
+	)
+	expectStock(t, h, pen, 8)
+	expectStock(t, h, book, 7)
+
+	// เพิ่ม Pen จาก 2 เป็น 5, เพิ่ม Ink ใหม่ และไม่ส่ง Book มา (Book ต้องคงเดิม)
+	var out struct {
+		Data m.Order `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodPut, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{
+			{Product: "Pen", Amount: 5},
+			{Product: "Ink", Amount: 4},
+		},
+	}), fiber.StatusOK).JSON(t, &out)
+
+	expectStock(t, h, pen, 8+2-5)
+	expectStock(t, h, book, 7)
+	expectStock(t, h, ink, 6)
+	if want := 5*5 + 3*7 + 4*3; out.Data.Total_Price != want {
+		t.Fatalf("expected total %d, got %d", want, out.Data.Total_Price)
+	}
+	if len(out.Data.Items) != 3 {
+		t.Fatalf("expected 3 items, got %+v", out.Data.Items)
+	}
+
+	// ลด Pen จาก 5 เหลือ 1 ต้องคืนสินค้า 4 ชิ้น
+	h.Expect(h.Request(http.MethodPut, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 1}},
+	}), fiber.StatusOK)
+	expectStock(t, h, pen, 9)
+}
+
+func TestUpdateOrderRejectsMoreThanAvailable(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	order := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 2})
+
+	problem := h.ExpectProblem(h.Request(http.MethodPut, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 0}},
+	}), fiber.StatusBadRequest, apperr.CodeValidation)
+	expectFieldErrors(t, problem, "Items[0].Amount:gt")
+
+	// คงเหลือ 8 + ของเดิมใน order 2 = 10 จึงขอ 11 ไม่ได้
+	h.ExpectProblem(h.Request(http.MethodPut, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 11}},
+	}), fiber.StatusConflict, apperr.CodeInsufficientStock)
+	expectStock(t, h, pen, 8)
+
+	// ขอได้พอดีทั้งหมด 10
+	h.Expect(h.Request(http.MethodPut, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 10}},
+	}), fiber.StatusOK)
+	expectStock(t, h, pen, 0)
+}
+
+func TestRemoveOrderRestocks(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	order := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 6})
+	expectStock(t, h, pen, 4)
+
+	var out struct {
+		Data m.Order `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodDelete, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, nil), fiber.StatusOK).JSON(t, &out)
+	expectStock(t, h, pen, 10)
+	if out.Data.Status != m.OrderCancelled || out.Data.CancelledAt == nil {
+		t.Fatalf("expected order to be cancelled, got %+v", out.Data)
+	}
+
+	// ยกเลิกซ้ำไม่ได้และต้องไม่คืนสต็อกซ้ำ
+	h.ExpectProblem(h.Request(http.MethodDelete, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, nil), fiber.StatusConflict, apperr.CodeOrderStatus)
+	expectStock(t, h, pen, 10)
+	h.Expect(h.Request(http.MethodDelete, "/order/999", user.AccessToken, nil), fiber.StatusNotFound)
+}
+
+// concurrently ส่ง request ทั้งหมดพร้อมกันและคืน status ของแต่ละ request
+func concurrently(h *testutil.Harness, requests []func() *testutil.Response) []int {
+	statuses := make([]int, len(requests))
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i, request := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			statuses[i] = request().Status
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return statuses
+}
+
+func countStatus(statuses []int, status int) int {
+	n := 0
+	for _, s := range statuses {
+		if s == status {
+			n++
+		}
+	}
+	return n
+}
+
+// overlapGoroutines เพิ่มจำนวน thread ระหว่าง test บนเครื่องที่มี CPU น้อย goroutine แทบไม่ทำงานซ้อนกัน
+// และ transaction จะรันต่อกันทีละตัวจน test จับ race ไม่ได้
+func overlapGoroutines(t *testing.T) {
+	if procs := runtime.GOMAXPROCS(0); procs < 4 {
+		runtime.GOMAXPROCS(4)
+		t.Cleanup(func() { runtime.GOMAXPROCS(procs) })
+	}
+}
+
+func TestConcurrentOrdersNeverOversell(t *testing.T) {
+	overlapGoroutines(t)
+	h := testutil.NewWithConfig(t, testutil.ConcurrentConfig(t))
+	admin := h.LoginAdmin("admin")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+
+	var requests []func() *testutil.Response
+	for u := 0; u < 5; u++ {
+		buyer := h.LoginUser(admin.AccessToken, fmt.Sprintf("buyer%d", u))
+		for i := 0; i < 5; i++ {
+			requests = append(requests, func() *testutil.Response {
+				return h.Request(http.MethodPost, fmt.Sprintf("/order/%d", buyer.UserID), buyer.AccessToken, map[string]any{
+					"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 1}},
+				})
+			})
+		}
+	}
+
+	statuses := concurrently(h, requests)
+	if created, rejected := countStatus(statuses, fiber.StatusCreated), countStatus(statuses, fiber.StatusConflict); created != 10 || rejected != 15 {
+		t.Fatalf("expected 10 created and 15 rejected orders, got %d and %d: %v", created, rejected, statuses)
+	}
+	expectStock(t, h, pen, 0)
+
+	var all struct {
+		Data []m.Order `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodGet, "/order?limit=100", admin.AccessToken, nil), fiber.StatusOK).JSON(t, &all)
+	if len(all.Data) != 10 {
+		t.Fatalf("expected 10 orders, got %d", len(all.Data))
+	}
+}
+
+// TestConcurrentStockChangesAreAtomic เรียก store จากหลาย goroutine บนหลาย connection โดยไม่มี transaction ครอบ
+// ถ้าการตรวจและเปลี่ยนจำนวนไม่ได้อยู่ใน UPDATE เดียวกัน สินค้าจะถูกขายเกินหรือ request จะชนกันจน error
+func TestConcurrentStockChangesAreAtomic(t *testing.T) {
+	// ใช้ transaction แบบ deferred ให้ transaction ซ้อนกันได้เหมือน mysql/postgres
+	// ถ้าเริ่มแบบ immediate sqlite จะรัน transaction ทีละตัวและซ่อนการตรวจที่อยู่นอก UPDATE
+	cfg := testutil.ConcurrentConfig(t)
+	cfg.Database.Name += "&_txlock=deferred"
+	h := testutil.NewWithConfig(t, cfg)
+	admin := h.LoginAdmin("admin")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	ink := h.CreateProduct(admin.AccessToken, "Ink", 5, 10)
+	products := h.Store.Products()
+
+	overlapGoroutines(t)
+
+	// เปิด connection ไว้ล่วงหน้าเพื่อให้ทุก goroutine ได้ connection ของตัวเองและทำงานซ้อนกันจริง
+	sqlDB, err := h.DB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxIdleConns(25)
+	conns := make([]*sql.Conn, 25)
+	for i := range conns {
+		if conns[i], err = sqlDB.Conn(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, conn := range conns {
+		conn.Close()
+	}
+
+	run := func(fn func() error) (succeeded, rejected int) {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for i := 0; i < 25; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				err := fn()
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					succeeded++
+				case errors.Is(err, store.ErrInsufficientStock):
+					rejected++
+				default:
+					t.Error(err)
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+		return succeeded, rejected
+	}
+
+	if ok, rejected := run(func() error { return products.Reserve(pen.ID, 1) }); ok != 10 || rejected != 15 {
+		t.Fatalf("expected 10 reservations and 15 rejections, got %d and %d", ok, rejected)
+	}
+	expectStock(t, h, pen, 0)
+
+	if ok, rejected := run(func() error {
+		return products.AdjustStock(&m.StockMovement{ProductID: ink.ID, Delta: -1, Reason: m.StockAdjustment})
+	}); ok != 10 || rejected != 15 {
+		t.Fatalf("expected 10 adjustments and 15 rejections, got %d and %d", ok, rejected)
+	}
+	expectStock(t, h, ink, 0)
+	if balance, err := products.LedgerBalance(ink.ID); err != nil || balance != 0 {
+		t.Fatalf("expected the ledger to balance at 0, got %d (%v)", balance, err)
+	}
+}
+
+func TestFailedOrderRollsBackStock(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	book := h.CreateProduct(admin.AccessToken, "Book", 50, 1)
+	path := fmt.Sprintf("/order/%d", user.UserID)
+
+	// Pen ถูกตัดก่อนแล้วค่อยพบว่า Book ไม่พอ ต้องคืน Pen ทั้งหมด
+	h.ExpectProblem(h.Request(http.MethodPost, path, user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 4}, {Product: "Book", Amount: 2}},
+	}), fiber.StatusConflict, apperr.CodeInsufficientStock)
+	h.ExpectProblem(h.Request(http.MethodPost, path, user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 4}, {Product: "Missing", Amount: 1}},
+	}), fiber.StatusNotFound, apperr.CodeNotFound)
+
+	expectStock(t, h, pen, 10)
+	expectStock(t, h, book, 1)
+	orders, err := h.Store.Orders().ListByBuyer(fmt.Sprint(user.UserID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 0 {
+		t.Fatalf("expected no orders, got %+v", orders)
+	}
+
+	// update ที่ล้มเหลวกลางทางต้องไม่เปลี่ยนทั้งสต็อกและ order เดิม
+	order := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 2})
+	h.ExpectProblem(h.Request(http.MethodPut, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 6}, {Product: "Book", Amount: 5}},
+	}), fiber.StatusConflict, apperr.CodeInsufficientStock)
+	expectStock(t, h, pen, 8)
+	expectStock(t, h, book, 1)
+
+	saved, err := h.Store.Orders().Get(order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Items) != 1 || saved.Items[0].Amount != 2 || saved.Total_Price != 10 {
+		t.Fatalf("expected order to be unchanged, got %+v", saved)
+	}
+}
+
+func TestConcurrentRemoveOrderRestocksOnce(t *testing.T) {
+	overlapGoroutines(t)
+	h := testutil.NewWithConfig(t, testutil.ConcurrentConfig(t))
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	order := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 4})
+
+	requests := make([]func() *testutil.Response, 8)
+	for i := range requests {
+		requests[i] = func() *testutil.Response {
+			return h.Request(http.MethodDelete, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, nil)
+		}
+	}
+
+	statuses := concurrently(h, requests)
+	if ok, conflict := countStatus(statuses, fiber.StatusOK), countStatus(statuses, fiber.StatusConflict); ok != 1 || conflict != 7 {
+		t.Fatalf("expected exactly one successful cancel, got %v", statuses)
+	}
+	expectStock(t, h, pen, 10)
+}
+
+func TestOrderStatusTransitions(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	order := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 2})
+	if order.Status != m.OrderPending {
+		t.Fatalf("expected new order to be pending, got %q", order.Status)
+	}
+
+	statusPath := fmt.Sprintf("/order/%d/status", order.ID)
+	setStatus := func(status string) *testutil.Response {
+		return h.Request(http.MethodPut, statusPath, admin.AccessToken, map[string]string{"Status": status})
+	}
+
+	h.Expect(h.Request(http.MethodPut, statusPath, user.AccessToken, map[string]string{"Status": m.OrderPaid}), fiber.StatusForbidden)
+	problem := h.ExpectProblem(setStatus("lost"), fiber.StatusBadRequest, apperr.CodeValidation)
+	expectFieldErrors(t, problem, "Status:oneof")
+	h.ExpectProblem(setStatus(m.OrderShipped), fiber.StatusConflict, apperr.CodeOrderStatus)
+
+	var out struct {
+		Data m.Order `json:"data"`
+	}
+	h.Expect(setStatus(m.OrderPaid), fiber.StatusOK).JSON(t, &out)
+	if out.Data.Status != m.OrderPaid || out.Data.PaidAt == nil {
+		t.Fatalf("expected paid order with timestamp, got %+v", out.Data)
+	}
+
+	// order ที่ชำระเงินแล้วผู้ซื้อแก้ไขหรือยกเลิกเองไม่ได้
+	h.ExpectProblem(h.Request(http.MethodPut, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 1}},
+	}), fiber.StatusConflict, apperr.CodeOrderStatus)
+	h.ExpectProblem(h.Request(http.MethodDelete, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, nil), fiber.StatusConflict, apperr.CodeOrderStatus)
+
+	h.Expect(setStatus(m.OrderProcessing), fiber.StatusOK)
+	h.Expect(setStatus(m.OrderShipped), fiber.StatusOK)
+	h.Expect(setStatus(m.OrderDelivered), fiber.StatusOK).JSON(t, &out)
+	if out.Data.ProcessingAt == nil || out.Data.ShippedAt == nil || out.Data.DeliveredAt == nil {
+		t.Fatalf("expected a timestamp for every transition, got %+v", out.Data)
+	}
+	h.ExpectProblem(setStatus(m.OrderPending), fiber.StatusConflict, apperr.CodeOrderStatus)
+
+	// คืนเงินหลังส่งของไปแล้วไม่คืนสินค้าเข้าคลัง
+	h.Expect(setStatus(m.OrderRefunded), fiber.StatusOK)
+	expectStock(t, h, pen, 8)
+	h.ExpectProblem(setStatus(m.OrderCancelled), fiber.StatusConflict, apperr.CodeOrderStatus)
+}
+
+func TestAdminCancelRestocksPaidOrder(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	order := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 4})
+	statusPath := fmt.Sprintf("/order/%d/status", order.ID)
+
+	h.Expect(h.Request(http.MethodPut, statusPath, admin.AccessToken, map[string]string{"Status": m.OrderPaid}), fiber.StatusOK)
+	expectStock(t, h, pen, 6)
+
+	h.Expect(h.Request(http.MethodPut, statusPath, admin.AccessToken, map[string]string{"Status": m.OrderCancelled}), fiber.StatusOK)
+	expectStock(t, h, pen, 10)
+
+	page := getList[m.Order](t, h, "/order?status=cancelled", admin.AccessToken)
+	if len(page.Data) != 1 || page.Data[0].ID != order.ID {
+		t.Fatalf("expected the cancelled order to be listed, got %+v", page.Data)
+	}
+}
@@ -0,0 +1,288 @@
+package routes_test
+
+import (
+	"fmt"
+	"go-fiber-test/apperr"
+	m "go-fiber-test/models"
+	"go-fiber-test/payment"
+	"go-fiber-test/testutil"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// loadOrder โหลด order จากฐานข้อมูลโดยตรงเพื่อตรวจสถานะหลัง webhook
+func loadOrder(t *testing.T, h *testutil.Harness, id uint) *m.Order {
+	t.Helper()
+	order, err := h.Store.Orders().Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+func loadPayments(t *testing.T, h *testutil.Harness, orderID uint) []m.Payment {
+	t.Helper()
+	payments, err := h.Store.Payments().ListByOrder(orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payments
+}
+
+func TestPaymentSucceededWebhookMarksOrderPaid(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	order := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 3})
+
+	pay := h.Pay(user, order.ID)
+	if pay.Amount != 15 || pay.Currency != "THB" || pay.Status != m.PaymentPending || pay.ClientSecret == "" {
+		t.Fatalf("unexpected payment: %+v", pay)
+	}
+	if status := loadOrder(t, h, order.ID).Status; status != m.OrderPending {
+		t.Fatalf("order must stay pending until the webhook arrives, got %s", status)
+	}
+
+	event := payment.Event{ID: "evt_1", Type: payment.EventSucceeded, IntentID: pay.ProviderRef}
+	h.Expect(h.Webhook(event), fiber.StatusOK)
+	// webhook ซ้ำต้องไม่มีผลซ้ำ
+	h.Expect(h.Webhook(event), fiber.StatusOK)
+
+	paid := loadOrder(t, h, order.ID)
+	if paid.Status != m.OrderPaid || paid.PaidAt == nil {
+		t.Fatalf("expected order to be paid, got %+v", paid)
+	}
+	if payments := loadPayments(t, h, order.ID); len(payments) != 1 || payments[0].Status != m.PaymentSucceeded {
+		t.Fatalf("unexpected payments: %+v", payments)
+	}
+
+	// order ที่ชำระแล้วจ่ายซ้ำไม่ได้
+	h.ExpectProblem(h.Request(http.MethodPost, fmt.Sprintf("/order/%d/payment", order.ID), user.AccessToken, nil),
+		fiber.StatusConflict, apperr.CodeOrderStatus)
+}
+
+func TestPaymentAuthorizedWebhookCaptures(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	order := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 1})
+	pay := h.Pay(user, order.ID)
+
+	h.Expect(h.Webhook(payment.Event{ID: "evt_1", Type: payment.EventAuthorized, IntentID: pay.ProviderRef}), fiber.StatusOK)
+
+	if status := loadOrder(t, h, order.ID).Status; status != m.OrderPaid {
+		t.Fatalf("expected captured order to be paid, got %s", status)
+	}
+}
+
+func TestPaymentFailedWebhookAllowsRetry(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	order := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 2})
+
+	first := h.Pay(user, order.ID)
+	h.Expect(h.Webhook(payment.Event{ID: "evt_1", Type: payment.EventFailed, IntentID: first.ProviderRef}), fiber.StatusOK)
+
+	failed := loadOrder(t, h, order.ID)
+	if failed.Status != m.OrderFailed || failed.FailedAt == nil {
+		t.Fatalf("expected order to be failed, got %+v", failed)
+	}
+	// สินค้ายังถูกกันไว้ให้ order ที่จ่ายไม่สำเร็จ
+	expectStock(t, h, pen, 8)
+
+	second := h.Pay(user, order.ID)
+	h.Expect(h.Webhook(payment.Event{ID: "evt_2", Type: payment.EventSucceeded, IntentID: second.ProviderRef}), fiber.StatusOK)
+	if status := loadOrder(t, h, order.ID).Status; status != m.OrderPaid {
+		t.Fatalf("expected retried order to be paid, got %s", status)
+	}
+}
+
+func TestWebhookRejectsInvalidSignature(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	order := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 1})
+	pay := h.Pay(user, order.ID)
+
+	payload := []byte(fmt.Sprintf(`{"id":"evt_1","type":%q,"intent_id":%q}`, payment.EventSucceeded, pay.ProviderRef))
+	h.ExpectProblem(h.SignedWebhook(payload, "deadbeef"), fiber.StatusUnauthorized, apperr.CodeInvalidSignature)
+	h.ExpectProblem(h.SignedWebhook(payload, ""), fiber.StatusUnauthorized, apperr.CodeInvalidSignature)
+
+	if status := loadOrder(t, h, order.ID).Status; status != m.OrderPending {
+		t.Fatalf("order must not change on an unsigned webhook, got %s", status)
+	}
+
+	h.ExpectProblem(h.Webhook(payment.Event{ID: "evt_2", Type: payment.EventSucceeded, IntentID: "unknown"}), fiber.StatusNotFound, apperr.CodeNotFound)
+}
+
+func TestPaymentIsRestrictedToOwner(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	bob := h.LoginUser(admin.AccessToken, "bob")
+	h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	order := h.PlaceOrder(alice, testutil.ItemRequest{Product: "Pen", Amount: 1})
+
+	h.Expect(h.Request(http.MethodPost, fmt.Sprintf("/order/%d/payment", order.ID), bob.AccessToken, nil), fiber.StatusUnauthorized)
+	h.Expect(h.Request(http.MethodPost, "/order/999/payment", alice.AccessToken, nil), fiber.StatusNotFound)
+}
+
+func TestRefundingPaidOrderRefundsPayment(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	order := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 2})
+	pay := h.Pay(user, order.ID)
+	h.Expect(h.Webhook(payment.Event{ID: "evt_1", Type: payment.EventSucceeded, IntentID: pay.ProviderRef}), fiber.StatusOK)
+
+	h.Expect(h.Request(http.MethodPut, fmt.Sprintf("/order/%d/status", order.ID), admin.AccessToken, map[string]string{
+		"Status": m.OrderRefunded,
+	}), fiber.StatusOK)
+
+	if payments := loadPayments(t, h, order.ID); payments[0].Status != m.PaymentRefunded {
+		t.Fatalf("expected payment to be refunded, got %+v", payments)
+	}
+	expectStock(t, h, pen, 10)
+}
+
+func TestLatePaymentForCancelledOrderIsRefunded(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	order := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 1})
+	pay := h.Pay(user, order.ID)
+
+	h.Expect(h.Request(http.MethodDelete, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, nil), fiber.StatusOK)
+	h.Expect(h.Webhook(payment.Event{ID: "evt_1", Type: payment.EventSucceeded, IntentID: pay.ProviderRef}), fiber.StatusOK)
+
+	if status := loadOrder(t, h, order.ID).Status; status != m.OrderCancelled {
+		t.Fatalf("expected order to stay cancelled, got %s", status)
+	}
+	if payments := loadPayments(t, h, order.ID); payments[0].Status != m.PaymentRefunded {
+		t.Fatalf("expected late payment to be refunded, got %+v", payments)
+	}
+}
+
+func TestPaymentMustMatchOrderTotal(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	order := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 2})
+	pay := h.Pay(user, order.ID)
+
+	// ยอดของ intent ถูกกำหนดตอนสร้าง จึงแก้ order ไม่ได้ระหว่างรอผลการชำระเงิน
+	h.ExpectProblem(h.Request(http.MethodPut, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 5}},
+	}), fiber.StatusConflict, apperr.CodeOrderStatus)
+	expectStock(t, h, pen, 8)
+
+	// ถ้ายอดของ order ไม่ตรงกับยอดที่ตัดเงินไป order ไม่ถูกนับว่าชำระแล้วและเงินถูกคืน
+	if err := h.DB.Model(&m.Order{}).Where("id = ?", order.ID).Update("total_price", 25).Error; err != nil {
+		t.Fatal(err)
+	}
+	h.Expect(h.Webhook(payment.Event{ID: "evt_1", Type: payment.EventSucceeded, IntentID: pay.ProviderRef}), fiber.StatusOK)
+	if status := loadOrder(t, h, order.ID).Status; status != m.OrderPending {
+		t.Fatalf("expected order to stay pending, got %s", status)
+	}
+	if payments := loadPayments(t, h, order.ID); payments[0].Status != m.PaymentRefunded {
+		t.Fatalf("expected the short payment to be refunded, got %+v", payments)
+	}
+
+	// เมื่อไม่มี payment ที่รอผลแล้วก็แก้ order ได้อีก
+	h.Expect(h.Request(http.MethodPut, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 5}},
+	}), fiber.StatusOK)
+	expectStock(t, h, pen, 5)
+}
+
+// setPaymentStatus เปลี่ยนสถานะ payment ในฐานข้อมูลโดยตรง เพื่อจำลองว่าเรียก provider ไปแล้วแต่บันทึกผลไม่สำเร็จ
+func setPaymentStatus(t *testing.T, h *testutil.Harness, ref, status string) {
+	t.Helper()
+	if err := h.DB.Model(&m.Payment{}).Where("provider_ref = ?", ref).Update("status", status).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebhookRetryResumesCaptureAndRefund(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+
+	// payment ที่ค้างที่ capturing ถูกตัดเงินต่อเมื่อ provider ส่ง webhook ซ้ำ
+	order := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 1})
+	pay := h.Pay(user, order.ID)
+	setPaymentStatus(t, h, pay.ProviderRef, m.PaymentCapturing)
+	h.ExpectProblem(h.Request(http.MethodPut, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 2}},
+	}), fiber.StatusConflict, apperr.CodeOrderStatus)
+	h.Expect(h.Webhook(payment.Event{ID: "evt_1", Type: payment.EventAuthorized, IntentID: pay.ProviderRef}), fiber.StatusOK)
+	if status := loadOrder(t, h, order.ID).Status; status != m.OrderPaid {
+		t.Fatalf("expected the resumed capture to pay the order, got %s", status)
+	}
+	if payments := loadPayments(t, h, order.ID); payments[0].Status != m.PaymentSucceeded {
+		t.Fatalf("unexpected payments: %+v", payments)
+	}
+
+	// payment ที่ค้างที่ refunding ถูกคืนเงินต่อเมื่อมี webhook ของ payment นั้นเข้ามา
+	// ครั้งที่สองจำลองว่า provider คืนเงินไปแล้วแต่บันทึกผลไม่สำเร็จ การคืนเงินซ้ำต้องไม่ล้มเหลว
+	setPaymentStatus(t, h, pay.ProviderRef, m.PaymentRefunding)
+	h.Expect(h.Webhook(payment.Event{ID: "evt_2", Type: payment.EventSucceeded, IntentID: pay.ProviderRef}), fiber.StatusOK)
+	setPaymentStatus(t, h, pay.ProviderRef, m.PaymentRefunding)
+	h.Expect(h.Webhook(payment.Event{ID: "evt_3", Type: "payment.updated", IntentID: pay.ProviderRef}), fiber.StatusOK)
+	if payments := loadPayments(t, h, order.ID); payments[0].Status != m.PaymentRefunded {
+		t.Fatalf("expected the resumed refund to finish, got %+v", payments)
+	}
+}
+
+func TestFailedRefundCanBeRetried(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	order := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 2})
+	pay := h.Pay(user, order.ID)
+	h.Expect(h.Webhook(payment.Event{ID: "evt_1", Type: payment.EventSucceeded, IntentID: pay.ProviderRef}), fiber.StatusOK)
+
+	// event failed ที่มาช้าไม่เปลี่ยน payment ที่สำเร็จแล้ว แต่ทำให้ fake provider คืนเงินไม่ได้
+	h.Expect(h.Webhook(payment.Event{ID: "evt_2", Type: payment.EventFailed, IntentID: pay.ProviderRef}), fiber.StatusOK)
+	path := fmt.Sprintf("/order/%d/status", order.ID)
+	h.ExpectProblem(h.Request(http.MethodPut, path, admin.AccessToken, map[string]string{
+		"Status": m.OrderRefunded,
+	}), fiber.StatusBadGateway, apperr.CodePaymentProvider)
+
+	// สถานะ order ถูกบันทึกแล้ว และ payment บอกว่ายังต้องคืนเงิน
+	if status := loadOrder(t, h, order.ID).Status; status != m.OrderRefunded {
+		t.Fatalf("expected the order to be refunded, got %s", status)
+	}
+	if payments := loadPayments(t, h, order.ID); payments[0].Status != m.PaymentRefunding {
+		t.Fatalf("expected the payment to wait for its refund, got %+v", payments)
+	}
+	expectStock(t, h, pen, 10)
+
+	// webhook ถัดไปของ payment นี้คืนเงินต่อ
+	h.Expect(h.Webhook(payment.Event{ID: "evt_3", Type: payment.EventSucceeded, IntentID: pay.ProviderRef}), fiber.StatusOK)
+	if payments := loadPayments(t, h, order.ID); payments[0].Status != m.PaymentRefunded {
+		t.Fatalf("expected the webhook to finish the refund, got %+v", payments)
+	}
+
+	// admin ส่งสถานะเดิมซ้ำเพื่อคืนเงินที่ค้างอยู่ได้เช่นกัน
+	setPaymentStatus(t, h, pay.ProviderRef, m.PaymentRefunding)
+	h.Expect(h.Request(http.MethodPut, path, admin.AccessToken, map[string]string{
+		"Status": m.OrderRefunded,
+	}), fiber.StatusOK)
+	if payments := loadPayments(t, h, order.ID); payments[0].Status != m.PaymentRefunded {
+		t.Fatalf("expected the retried refund to finish, got %+v", payments)
+	}
+	expectStock(t, h, pen, 10)
+}
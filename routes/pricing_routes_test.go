@@ -0,0 +1,148 @@
+package routes_test
+
+import (
+	"fmt"
+	"go-fiber-test/apperr"
+	"go-fiber-test/config"
+	"go-fiber-test/dto"
+	m "go-fiber-test/models"
+	"go-fiber-test/testutil"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// newPricingHarness boot แอปด้วยวิธีคิดภาษีและค่าส่งที่กำหนด
+func newPricingHarness(t *testing.T, pricing config.PricingConfig) *testutil.Harness {
+	cfg := testutil.Config(t)
+	cfg.Pricing = pricing
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return testutil.NewWithConfig(t, cfg)
+}
+
+func createWeightedProduct(t *testing.T, h *testutil.Harness, token, name string, price, weight int) m.Product {
+	t.Helper()
+	var out struct {
+		Data m.Product `json:"data"`
+	}
+	h.Expect(h.Form(http.MethodPost, "/product", token, map[string]string{
+		"Product_Name": name,
+		"Price":        strconv.Itoa(price),
+		"Amount":       "10",
+		"Weight":       strconv.Itoa(weight),
+	}), fiber.StatusCreated).JSON(t, &out)
+	return out.Data
+}
+
+func quote(t *testing.T, h *testutil.Harness, token string, body map[string]any) dto.QuoteResponse {
+	t.Helper()
+	var out struct {
+		Data dto.QuoteResponse `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodPost, "/order/quote", token, body), fiber.StatusOK).JSON(t, &out)
+	return out.Data
+}
+
+func TestWeightShippingWithVAT(t *testing.T) {
+	h := newPricingHarness(t, config.PricingConfig{
+		Tax:              config.TaxVAT,
+		VATRate:          7,
+		Shipping:         config.ShippingWeight,
+		ShippingBase:     30,
+		ShippingPerKg:    20,
+		FreeShippingOver: 500,
+	})
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := createWeightedProduct(t, h, admin.AccessToken, "Pen", 100, 300)
+	book := createWeightedProduct(t, h, admin.AccessToken, "Book", 250, 1200)
+
+	// 1.8 กิโลกรัมคิดเป็น 2 กิโลกรัม: 30 + 2*20 = 70 และภาษี 7% ของ 450 + 70
+	items := []testutil.ItemRequest{{ProductID: pen.ID, Amount: 2}, {ProductID: book.ID, Amount: 1}}
+	q := quote(t, h, user.AccessToken, map[string]any{"Items": items})
+	if q.Subtotal != 450 || q.Shipping != 70 || q.Tax != 36 || q.Total_Price != 556 {
+		t.Fatalf("unexpected quote: %+v", q)
+	}
+
+	// การประเมินราคาไม่สร้าง order และไม่ตัดสต็อก
+	expectStock(t, h, pen, 10)
+	var count int64
+	h.DB.Model(&m.Order{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("quote must not create an order, got %d", count)
+	}
+
+	order := h.PlaceOrder(user, items...)
+	if order.Subtotal != q.Subtotal || order.Shipping != q.Shipping || order.Tax != q.Tax || order.Total_Price != q.Total_Price {
+		t.Fatalf("expected order to match its quote %+v, got %+v", q, order)
+	}
+
+	// ยอดถึง 500 ส่งฟรี
+	var updated struct {
+		Data m.Order `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodPut, fmt.Sprintf("/order/%d", order.ID), user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{ProductID: book.ID, Amount: 2}},
+	}), fiber.StatusOK).JSON(t, &updated)
+	if updated.Data.Subtotal != 700 || updated.Data.Shipping != 0 || updated.Data.Tax != 49 || updated.Data.Total_Price != 749 {
+		t.Fatalf("expected totals to be recalculated, got %+v", updated.Data)
+	}
+	if pay := h.Pay(user, order.ID); pay.Amount != 749 {
+		t.Fatalf("expected payment of the grand total, got %d", pay.Amount)
+	}
+}
+
+func TestFlatShippingQuoteWithCoupon(t *testing.T) {
+	h := newPricingHarness(t, config.PricingConfig{
+		Tax:          config.TaxNone,
+		Shipping:     config.ShippingFlat,
+		ShippingFlat: 50,
+	})
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := createWeightedProduct(t, h, admin.AccessToken, "Pen", 100, 0)
+	coupon := createCoupon(t, h, admin.AccessToken, map[string]any{"Code": "ONCE", "Type": "fixed", "Value": 20, "UsageLimit": 1})
+
+	body := map[string]any{"Items": []testutil.ItemRequest{{ProductID: pen.ID, Amount: 1}}, "Coupon": "once"}
+	for i := 0; i < 2; i++ {
+		q := quote(t, h, user.AccessToken, body)
+		if q.CouponCode != "ONCE" || q.Discount != 20 || q.Shipping != 50 || q.Total_Price != 130 {
+			t.Fatalf("unexpected quote: %+v", q)
+		}
+	}
+
+	// การประเมินราคาไม่นับการใช้ coupon
+	var got struct {
+		Data dto.CouponResponse `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodGet, fmt.Sprintf("/coupon/%d", coupon.ID), admin.AccessToken, nil), fiber.StatusOK).JSON(t, &got)
+	if got.Data.Used != 0 {
+		t.Fatalf("quote must not redeem the coupon, got %d uses", got.Data.Used)
+	}
+
+	h.Expect(orderWithCoupon(h, user, "ONCE", testutil.ItemRequest{ProductID: pen.ID, Amount: 1}), fiber.StatusCreated)
+	h.ExpectProblem(h.Request(http.MethodPost, "/order/quote", user.AccessToken, body), fiber.StatusBadRequest, apperr.CodeCouponInvalid)
+}
+
+func TestQuoteWithoutPricingConfig(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	h.CreateProduct(admin.AccessToken, "Pen", 15, 10)
+
+	q := quote(t, h, user.AccessToken, map[string]any{"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 2}}})
+	if q.Subtotal != 30 || q.Shipping != 0 || q.Tax != 0 || q.Total_Price != 30 {
+		t.Fatalf("unexpected quote: %+v", q)
+	}
+
+	h.Expect(h.Request(http.MethodPost, "/order/quote", user.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Nope", Amount: 1}},
+	}), fiber.StatusNotFound)
+	h.Expect(h.Request(http.MethodPost, "/order/quote", "", map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 1}},
+	}), fiber.StatusUnauthorized)
+}
@@ -0,0 +1,255 @@
+package routes_test
+
+import (
+	"fmt"
+	"go-fiber-test/apperr"
+	m "go-fiber-test/models"
+	"go-fiber-test/testutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func uploadedFile(h *testutil.Harness, image m.ProductImage) string {
+	return filepath.Join(h.Config.App.UploadDir, filepath.Base(image.ImageURL))
+}
+
+func TestAddProductWithImages(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+
+	product := h.CreateProduct(admin.AccessToken, "Pen", 15, 20, testutil.PNG("a.png"), testutil.PNG("b.png"))
+
+	if product.Product_Name != "Pen" || product.Price != 15 || product.Amount != 20 {
+		t.Fatalf("unexpected product: %+v", product)
+	}
+	if len(product.Images) != 2 {
+		t.Fatalf("expected 2 images, got %d", len(product.Images))
+	}
+	for _, image := range product.Images {
+		if _, err := os.Stat(uploadedFile(h, image)); err != nil {
+			t.Errorf("image %s was not saved: %v", image.ImageURL, err)
+		}
+	}
+}
+
+func TestAddProductRejectsInvalidPrice(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+
+	h.Expect(h.Form(http.MethodPost, "/product", admin.AccessToken, map[string]string{
+		"Product_Name": "Pen",
+		"Price":        "free",
+		"Amount":       "1",
+	}), fiber.StatusBadRequest)
+}
+
+func TestAddProductValidatesFields(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+
+	problem := h.ExpectProblem(h.Form(http.MethodPost, "/product", admin.AccessToken, map[string]string{
+		"Product_Name": "",
+		"Price":        "-1",
+		"Amount":       "-5",
+	}), fiber.StatusBadRequest, apperr.CodeValidation)
+	expectFieldErrors(t, problem, "Product_Name:required", "Price:gte", "Amount:gte")
+
+	problem = h.ExpectProblem(h.Form(http.MethodPost, "/product", admin.AccessToken, map[string]string{
+		"Product_Name": "Pen",
+	}), fiber.StatusBadRequest, apperr.CodeValidation)
+	expectFieldErrors(t, problem, "Price:required", "Amount:required")
+
+	// สินค้าราคา 0 และหมดสต็อกสร้างได้
+	h.CreateProduct(admin.AccessToken, "Sample", 0, 0)
+}
+
+func TestProductWritesRequireAdmin(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+
+	fields := map[string]string{"Product_Name": "Pen", "Price": "1", "Amount": "1"}
+	h.Expect(h.Form(http.MethodPost, "/product", "", fields), fiber.StatusUnauthorized)
+	h.Expect(h.Form(http.MethodPost, "/product", user.AccessToken, fields), fiber.StatusForbidden)
+}
+
+func TestGetProducts(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 15, 20, testutil.PNG("pen.png"))
+	h.CreateProduct(admin.AccessToken, "Book", 120, 3)
+
+	var list struct {
+		Data []m.Product `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodGet, "/product", "", nil), fiber.StatusOK).JSON(t, &list)
+	if len(list.Data) != 2 {
+		t.Fatalf("expected 2 products, got %d", len(list.Data))
+	}
+
+	var one struct {
+		Data m.Product `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodGet, fmt.Sprintf("/product/%d", pen.ID), "", nil), fiber.StatusOK).JSON(t, &one)
+	if one.Data.Product_Name != "Pen" || len(one.Data.Images) != 1 {
+		t.Fatalf("unexpected product: %+v", one.Data)
+	}
+
+	var image struct {
+		Data m.ProductImage `json:"data"`
+	}
+	path := fmt.Sprintf("/product/%d/image/%d", pen.ID, pen.Images[0].ID)
+	h.Expect(h.Request(http.MethodGet, path, "", nil), fiber.StatusOK).JSON(t, &image)
+	if image.Data.ImageURL != pen.Images[0].ImageURL {
+		t.Fatalf("expected image %s, got %s", pen.Images[0].ImageURL, image.Data.ImageURL)
+	}
+
+	h.Expect(h.Request(http.MethodGet, "/product/999", "", nil), fiber.StatusNotFound)
+}
+
+func TestUpdateProduct(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 15, 20)
+
+	var out struct {
+		Data m.Product `json:"data"`
+	}
+	h.Expect(h.Form(http.MethodPut, fmt.Sprintf("/product/%d", pen.ID), admin.AccessToken, map[string]string{
+		"Price": "18",
+	}, testutil.PNG("new.png")), fiber.StatusOK).JSON(t, &out)
+
+	if out.Data.Product_Name != "Pen" || out.Data.Price != 18 || out.Data.Amount != 20 {
+		t.Fatalf("unexpected product after update: %+v", out.Data)
+	}
+	if len(out.Data.Images) != 1 {
+		t.Fatalf("expected uploaded image to be attached, got %d images", len(out.Data.Images))
+	}
+
+	problem := h.ExpectProblem(h.Form(http.MethodPut, fmt.Sprintf("/product/%d", pen.ID), admin.AccessToken, map[string]string{
+		"Product_Name": "",
+		"Amount":       "-1",
+	}), fiber.StatusBadRequest, apperr.CodeValidation)
+	expectFieldErrors(t, problem, "Product_Name:min", "Amount:gte")
+	expectStock(t, h, pen, 20)
+}
+
+func TestFailedUpdateProductChangesNothing(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 15, 20)
+	h.PlaceOrder(alice, testutil.ItemRequest{Product: "Pen", Amount: 5})
+
+	// จำนวนใหม่ต่ำกว่าที่ถูกกันไว้ ชื่อ ราคา และรูปภาพที่ส่งมาพร้อมกันต้องไม่ถูกบันทึกด้วย
+	h.ExpectProblem(h.Form(http.MethodPut, fmt.Sprintf("/product/%d", pen.ID), admin.AccessToken, map[string]string{
+		"Product_Name": "Blue Pen",
+		"Price":        "18",
+		"Amount":       "2",
+	}, testutil.PNG("new.png")), fiber.StatusConflict, apperr.CodeInsufficientStock)
+
+	got := h.Product(pen.ID)
+	if got.Product_Name != "Pen" || got.Price != 15 || got.Amount != 20 || len(got.Images) != 0 {
+		t.Fatalf("expected the product to be unchanged, got %+v", got)
+	}
+	uploads, err := os.ReadDir(h.Config.App.UploadDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uploads) != 0 {
+		t.Fatalf("expected the uploaded file to be removed, found %d files", len(uploads))
+	}
+}
+
+func TestFailedAddProductLeavesNothing(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+
+	// request ที่ไม่ใช่ multipart form ถูกปฏิเสธก่อนสร้าง product
+	h.ExpectProblem(h.Request(http.MethodPost, "/product", admin.AccessToken, map[string]any{
+		"Product_Name": "Pen", "Price": 15, "Amount": 20,
+	}), fiber.StatusBadRequest, apperr.CodeBadRequest)
+
+	// บันทึกรูปภาพไม่สำเร็จ product และ ledger ต้องไม่ถูกบันทึก และไฟล์ที่ upload ไปแล้วถูกลบ
+	if err := h.DB.Migrator().DropTable(&m.ProductImage{}); err != nil {
+		t.Fatal(err)
+	}
+	h.ExpectProblem(h.Form(http.MethodPost, "/product", admin.AccessToken, map[string]string{
+		"Product_Name": "Pen",
+		"Price":        "15",
+		"Amount":       "20",
+	}, testutil.PNG("pen.png")), fiber.StatusInternalServerError, apperr.CodeInternal)
+
+	var products, movements int64
+	if err := h.DB.Unscoped().Model(&m.Product{}).Count(&products).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := h.DB.Model(&m.StockMovement{}).Count(&movements).Error; err != nil {
+		t.Fatal(err)
+	}
+	if products != 0 || movements != 0 {
+		t.Fatalf("expected no product and no stock movement, got %d and %d", products, movements)
+	}
+	uploads, err := os.ReadDir(h.Config.App.UploadDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uploads) != 0 {
+		t.Fatalf("expected the uploaded file to be removed, found %d files", len(uploads))
+	}
+}
+
+func TestSoftDeleteRestoreAndHardDeleteProduct(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 15, 20, testutil.PNG("pen.png"))
+	path := fmt.Sprintf("/product/%d", pen.ID)
+
+	// hard delete ได้เฉพาะสินค้าที่อยู่ในถังขยะ
+	h.Expect(h.Request(http.MethodDelete, "/product/bin/"+fmt.Sprint(pen.ID), admin.AccessToken, nil), fiber.StatusNotFound)
+
+	h.Expect(h.Request(http.MethodDelete, path, admin.AccessToken, nil), fiber.StatusCreated)
+	h.Expect(h.Request(http.MethodGet, path, "", nil), fiber.StatusNotFound)
+
+	var restored struct {
+		Data m.Product `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodPut, "/product/restore/"+fmt.Sprint(pen.ID), admin.AccessToken, nil), fiber.StatusCreated).JSON(t, &restored)
+	if len(restored.Data.Images) != 1 {
+		t.Fatalf("expected images to be restored, got %d", len(restored.Data.Images))
+	}
+	h.Expect(h.Request(http.MethodGet, path, "", nil), fiber.StatusOK)
+
+	h.Expect(h.Request(http.MethodDelete, path, admin.AccessToken, nil), fiber.StatusCreated)
+	h.Expect(h.Request(http.MethodDelete, "/product/bin/"+fmt.Sprint(pen.ID), admin.AccessToken, nil), fiber.StatusCreated)
+
+	if _, err := os.Stat(uploadedFile(h, pen.Images[0])); !os.IsNotExist(err) {
+		t.Fatalf("expected image file to be removed, stat error: %v", err)
+	}
+	h.Expect(h.Request(http.MethodPut, "/product/restore/"+fmt.Sprint(pen.ID), admin.AccessToken, nil), fiber.StatusNotFound)
+}
+
+func TestRemoveImage(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 15, 20, testutil.PNG("a.png"), testutil.PNG("b.png"))
+	removed := pen.Images[0]
+
+	var out struct {
+		Data m.Product `json:"data"`
+	}
+	path := fmt.Sprintf("/product/%d/image/%d", pen.ID, removed.ID)
+	h.Expect(h.Request(http.MethodDelete, path, admin.AccessToken, nil), fiber.StatusOK).JSON(t, &out)
+
+	if len(out.Data.Images) != 1 || out.Data.Images[0].ID == removed.ID {
+		t.Fatalf("expected only the other image to remain, got %+v", out.Data.Images)
+	}
+	if _, err := os.Stat(uploadedFile(h, removed)); !os.IsNotExist(err) {
+		t.Fatalf("expected image file to be removed, stat error: %v", err)
+	}
+	h.Expect(h.Request(http.MethodGet, path, "", nil), fiber.StatusNotFound)
+}
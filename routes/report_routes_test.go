@@ -0,0 +1,181 @@
+package routes_test
+
+import (
+	"go-fiber-test/apperr"
+	"go-fiber-test/config"
+	"go-fiber-test/dto"
+	m "go-fiber-test/models"
+	"go-fiber-test/payment"
+	"go-fiber-test/testutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// seedSales สร้าง order ที่ชำระแล้วสี่รายการของลูกค้าสามคนในวันที่กำหนด และ order ที่ไม่นับเป็นยอดขายอีกสองรายการ
+func seedSales(t *testing.T, h *testutil.Harness) testutil.Tokens {
+	t.Helper()
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	bob := h.LoginUser(admin.AccessToken, "bob")
+	carol := h.LoginUser(admin.AccessToken, "carol")
+	h.CreateProduct(admin.AccessToken, "Pen", 5, 100)
+	h.CreateProduct(admin.AccessToken, "Book", 10, 100)
+
+	place := func(tokens testutil.Tokens, status string, day time.Time, items ...testutil.ItemRequest) {
+		t.Helper()
+		order := h.PlaceOrder(tokens, items...)
+		if err := h.DB.Model(&m.Order{}).Where("id = ?", order.ID).Updates(map[string]any{
+			"status":     status,
+			"created_at": day,
+		}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(2026, month, day, 12, 0, 0, 0, time.UTC)
+	}
+
+	place(alice, m.OrderPaid, date(1, 5), testutil.ItemRequest{Product: "Pen", Amount: 5})
+	place(alice, m.OrderDelivered, date(1, 7), testutil.ItemRequest{Product: "Book", Amount: 1})
+	place(bob, m.OrderShipped, date(1, 12), testutil.ItemRequest{Product: "Pen", Amount: 1})
+	place(bob, m.OrderPending, date(1, 12), testutil.ItemRequest{Product: "Book", Amount: 9})
+	place(carol, m.OrderCancelled, date(1, 20), testutil.ItemRequest{Product: "Book", Amount: 9})
+	place(carol, m.OrderProcessing, date(2, 3), testutil.ItemRequest{Product: "Book", Amount: 3})
+	return admin
+}
+
+func getReport[T any](t *testing.T, h *testutil.Harness, path, token string) T {
+	t.Helper()
+	var out struct {
+		Data T `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodGet, path, token, nil), fiber.StatusOK).JSON(t, &out)
+	return out.Data
+}
+
+func TestSalesReportByInterval(t *testing.T) {
+	h := testutil.New(t)
+	admin := seedSales(t, h)
+
+	expect := func(interval string, want []dto.SalesPointResponse) {
+		t.Helper()
+		report := getReport[dto.SalesReportResponse](t, h, "/report/sales?interval="+interval, admin.AccessToken)
+		if len(report.Points) != len(want) {
+			t.Fatalf("%s: expected %v, got %v", interval, want, report.Points)
+		}
+		for i := range want {
+			if report.Points[i] != want[i] {
+				t.Fatalf("%s: expected %v, got %v", interval, want, report.Points)
+			}
+		}
+		if report.Summary != (dto.SalesSummaryResponse{Orders: 4, Revenue: 70, AverageOrderValue: 17.5, NewCustomers: 3}) {
+			t.Fatalf("unexpected summary: %+v", report.Summary)
+		}
+	}
+
+	expect("day", []dto.SalesPointResponse{
+		{Period: "2026-01-05", Orders: 1, Revenue: 25, AverageOrderValue: 25},
+		{Period: "2026-01-07", Orders: 1, Revenue: 10, AverageOrderValue: 10},
+		{Period: "2026-01-12", Orders: 1, Revenue: 5, AverageOrderValue: 5},
+		{Period: "2026-02-03", Orders: 1, Revenue: 30, AverageOrderValue: 30},
+	})
+	expect("week", []dto.SalesPointResponse{
+		{Period: "2026-01-05", Orders: 2, Revenue: 35, AverageOrderValue: 17.5},
+		{Period: "2026-01-12", Orders: 1, Revenue: 5, AverageOrderValue: 5},
+		{Period: "2026-02-02", Orders: 1, Revenue: 30, AverageOrderValue: 30},
+	})
+	expect("month", []dto.SalesPointResponse{
+		{Period: "2026-01-01", Orders: 3, Revenue: 40, AverageOrderValue: 13.33},
+		{Period: "2026-02-01", Orders: 1, Revenue: 30, AverageOrderValue: 30},
+	})
+}
+
+func TestSalesSummaryAndCustomersWithinRange(t *testing.T) {
+	h := testutil.New(t)
+	admin := seedSales(t, h)
+
+	// alice ซื้อครั้งแรกก่อนช่วงนี้จึงไม่นับเป็นลูกค้าใหม่
+	summary := getReport[dto.SalesSummaryResponse](t, h, "/report/summary?from=2026-01-06&to=2026-01-31", admin.AccessToken)
+	if summary != (dto.SalesSummaryResponse{Orders: 2, Revenue: 15, AverageOrderValue: 7.5, NewCustomers: 1}) {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+
+	customers := getReport[dto.CustomersReportResponse](t, h, "/report/customers?interval=month", admin.AccessToken)
+	if customers.Total != 3 || len(customers.Points) != 2 ||
+		customers.Points[0] != (dto.CustomerPointResponse{Period: "2026-01-01", Customers: 2}) ||
+		customers.Points[1] != (dto.CustomerPointResponse{Period: "2026-02-01", Customers: 1}) {
+		t.Fatalf("unexpected customers report: %+v", customers)
+	}
+
+	empty := getReport[dto.SalesSummaryResponse](t, h, "/report/summary?from=2025-01-01&to=2025-12-31", admin.AccessToken)
+	if empty != (dto.SalesSummaryResponse{}) {
+		t.Fatalf("expected an empty summary, got %+v", empty)
+	}
+}
+
+func TestRevenueExcludesTaxAndShipping(t *testing.T) {
+	h := newPricingHarness(t, config.PricingConfig{
+		Tax:          config.TaxVAT,
+		VATRate:      7,
+		Shipping:     config.ShippingFlat,
+		ShippingFlat: 50,
+	})
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	h.CreateProduct(admin.AccessToken, "Pen", 100, 10)
+	createCoupon(t, h, admin.AccessToken, map[string]any{"Code": "SAVE10", "Type": "percent", "Value": 10})
+
+	// 200 - ส่วนลด 20 + ค่าส่ง 50 + ภาษี 7% ของ 230 = 246 แต่ยอดขายคือ 180
+	var out struct {
+		Data m.Order `json:"data"`
+	}
+	orderWithCoupon(h, user, "SAVE10", testutil.ItemRequest{Product: "Pen", Amount: 2}).JSON(t, &out)
+	if out.Data.Total_Price != 246 {
+		t.Fatalf("unexpected order total: %+v", out.Data)
+	}
+	pay := h.Pay(user, out.Data.ID)
+	h.Expect(h.Webhook(payment.Event{ID: "evt_" + pay.ProviderRef, Type: payment.EventSucceeded, IntentID: pay.ProviderRef}), fiber.StatusOK)
+
+	summary := getReport[dto.SalesSummaryResponse](t, h, "/report/summary", admin.AccessToken)
+	if summary != (dto.SalesSummaryResponse{Orders: 1, Revenue: 180, AverageOrderValue: 180, NewCustomers: 1}) {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+	report := getReport[dto.SalesReportResponse](t, h, "/report/sales", admin.AccessToken)
+	if len(report.Points) != 1 || report.Points[0].Revenue != 180 || report.Points[0].AverageOrderValue != 180 {
+		t.Fatalf("unexpected sales report: %+v", report)
+	}
+}
+
+func TestTopProducts(t *testing.T) {
+	h := testutil.New(t)
+	admin := seedSales(t, h)
+
+	byUnits := getReport[[]dto.ProductSalesResponse](t, h, "/report/top-products", admin.AccessToken)
+	if len(byUnits) != 2 || byUnits[0].Product != "Pen" || byUnits[0].Units != 6 || byUnits[0].Revenue != 30 ||
+		byUnits[1].Product != "Book" || byUnits[1].Units != 4 || byUnits[1].Revenue != 40 {
+		t.Fatalf("unexpected top products by units: %+v", byUnits)
+	}
+
+	byRevenue := getReport[[]dto.ProductSalesResponse](t, h, "/report/top-products?by=revenue&limit=1", admin.AccessToken)
+	if len(byRevenue) != 1 || byRevenue[0].Product != "Book" {
+		t.Fatalf("unexpected top products by revenue: %+v", byRevenue)
+	}
+
+	inJanuary := getReport[[]dto.ProductSalesResponse](t, h, "/report/top-products?by=revenue&to=2026-01-31", admin.AccessToken)
+	if len(inJanuary) != 2 || inJanuary[0].Product != "Pen" || inJanuary[1].Revenue != 10 {
+		t.Fatalf("unexpected top products in January: %+v", inJanuary)
+	}
+}
+
+func TestReportsAreAdminOnly(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+
+	h.Expect(h.Request(http.MethodGet, "/report/sales", user.AccessToken, nil), fiber.StatusForbidden)
+	h.ExpectProblem(h.Request(http.MethodGet, "/report/sales?interval=year", admin.AccessToken, nil), fiber.StatusBadRequest, apperr.CodeValidation)
+	h.ExpectProblem(h.Request(http.MethodGet, "/report/top-products?by=profit", admin.AccessToken, nil), fiber.StatusBadRequest, apperr.CodeValidation)
+}
@@ -0,0 +1,180 @@
+package routes_test
+
+import (
+	"fmt"
+	"go-fiber-test/apperr"
+	"go-fiber-test/controllers"
+	"go-fiber-test/dto"
+	m "go-fiber-test/models"
+	"go-fiber-test/payment"
+	"go-fiber-test/testutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// getProduct โหลดสินค้าผ่าน GET /product/:productId เพื่อตรวจจำนวนที่ client เห็น
+func getProduct(t *testing.T, h *testutil.Harness, id uint) dto.ProductResponse {
+	t.Helper()
+	var out struct {
+		Data dto.ProductResponse `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodGet, fmt.Sprintf("/product/%d", id), "", nil), fiber.StatusOK).JSON(t, &out)
+	return out.Data
+}
+
+func expectOnHand(t *testing.T, h *testutil.Harness, id uint, amount, reserved int) {
+	t.Helper()
+	got := getProduct(t, h, id)
+	if got.Amount != amount || got.Reserved != reserved || got.Available != amount-reserved {
+		t.Fatalf("expected amount %d with %d reserved, got %+v", amount, reserved, got)
+	}
+}
+
+func TestOrderReservesStockUntilConfirmed(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	bob := h.LoginUser(admin.AccessToken, "bob")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+
+	before := time.Now()
+	order := h.PlaceOrder(alice, testutil.ItemRequest{Product: "Pen", Amount: 3})
+	if order.ReservedUntil == nil || order.ReservedUntil.Before(before.Add(h.Config.Inventory.ReservationTTL)) {
+		t.Fatalf("expected the order to be reserved for %s, got %v", h.Config.Inventory.ReservationTTL, order.ReservedUntil)
+	}
+	expectOnHand(t, h, pen.ID, 10, 3)
+
+	// ของที่ถูกกันไว้ขายให้คนอื่นไม่ได้
+	h.ExpectProblem(h.Request(http.MethodPost, fmt.Sprintf("/order/%d", bob.UserID), bob.AccessToken, map[string]any{
+		"Items": []testutil.ItemRequest{{Product: "Pen", Amount: 8}},
+	}), fiber.StatusConflict, apperr.CodeInsufficientStock)
+
+	path := fmt.Sprintf("/order/%d/confirm", order.ID)
+	h.Expect(h.Request(http.MethodPost, path, bob.AccessToken, nil), fiber.StatusUnauthorized)
+
+	var out struct {
+		Data dto.OrderResponse `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodPost, path, alice.AccessToken, nil), fiber.StatusOK).JSON(t, &out)
+	if out.Data.ReservedUntil != nil || out.Data.Status != m.OrderPending {
+		t.Fatalf("expected a confirmed pending order, got %+v", out.Data)
+	}
+	expectOnHand(t, h, pen.ID, 7, 0)
+
+	h.ExpectProblem(h.Request(http.MethodPost, path, alice.AccessToken, nil), fiber.StatusConflict, apperr.CodeOrderStatus)
+
+	// ยกเลิกหลังยืนยันแล้วคืนสินค้าเข้าคลัง
+	h.Expect(h.Request(http.MethodDelete, fmt.Sprintf("/order/%d", order.ID), alice.AccessToken, nil), fiber.StatusOK)
+	expectOnHand(t, h, pen.ID, 10, 0)
+}
+
+func TestExpiredReservationsAreReleased(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	order := h.PlaceOrder(alice, testutil.ItemRequest{Product: "Pen", Amount: 4})
+	h.PlaceOrder(alice, testutil.ItemRequest{Product: "Pen", Amount: 6})
+
+	// สินค้าที่ถูกกันไว้หมดแล้วไม่นับเป็นสินค้าที่มีของ
+	var list struct {
+		Data []dto.ProductResponse `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodGet, "/product?in_stock=true", "", nil), fiber.StatusOK).JSON(t, &list)
+	if len(list.Data) != 0 {
+		t.Fatalf("expected fully reserved product to be out of stock, got %+v", list.Data)
+	}
+
+	released, err := controllers.ReleaseExpiredReservations(h.Store, time.Now())
+	if err != nil || released != 0 {
+		t.Fatalf("expected nothing to expire yet, got %d (%v)", released, err)
+	}
+
+	later := time.Now().Add(h.Config.Inventory.ReservationTTL + time.Second)
+	// ยังไม่ถูก sweep แต่หมดเวลาแล้วก็ยืนยันไม่ได้
+	if err := h.DB.Model(&m.Order{}).Where("id = ?", order.ID).Update("reserved_until", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	h.ExpectProblem(h.Request(http.MethodPost, fmt.Sprintf("/order/%d/confirm", order.ID), alice.AccessToken, nil),
+		fiber.StatusConflict, apperr.CodeOrderStatus)
+
+	released, err = controllers.ReleaseExpiredReservations(h.Store, later)
+	if err != nil || released != 2 {
+		t.Fatalf("expected both orders to expire, got %d (%v)", released, err)
+	}
+
+	expired := loadOrder(t, h, order.ID)
+	if expired.Status != m.OrderCancelled || expired.ReservedUntil != nil {
+		t.Fatalf("expected expired order to be cancelled, got %+v", expired)
+	}
+	expectOnHand(t, h, pen.ID, 10, 0)
+
+	// sweep ซ้ำไม่มีผล
+	if released, err := controllers.ReleaseExpiredReservations(h.Store, later); err != nil || released != 0 {
+		t.Fatalf("expected nothing left to release, got %d (%v)", released, err)
+	}
+}
+
+func TestPaymentConfirmsReservation(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	order := h.PlaceOrder(alice, testutil.ItemRequest{Product: "Pen", Amount: 2})
+
+	pay := h.Pay(alice, order.ID)
+	h.Expect(h.Webhook(payment.Event{ID: "evt_1", Type: payment.EventSucceeded, IntentID: pay.ProviderRef}), fiber.StatusOK)
+
+	paid := loadOrder(t, h, order.ID)
+	if paid.Status != m.OrderPaid || paid.ReservedUntil != nil {
+		t.Fatalf("expected paid order to be confirmed, got %+v", paid)
+	}
+	expectOnHand(t, h, pen.ID, 8, 0)
+
+	later := time.Now().Add(h.Config.Inventory.ReservationTTL + time.Second)
+	if released, err := controllers.ReleaseExpiredReservations(h.Store, later); err != nil || released != 0 {
+		t.Fatalf("expected paid order to be left alone, got %d (%v)", released, err)
+	}
+	expectOnHand(t, h, pen.ID, 8, 0)
+}
+
+func TestProductAmountCannotDropBelowReserved(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	h.PlaceOrder(alice, testutil.ItemRequest{Product: "Pen", Amount: 4})
+	path := fmt.Sprintf("/product/%d", pen.ID)
+
+	// ของที่ order กันไว้ต้องยังมีอยู่จริง admin จึงลดจำนวนให้ต่ำกว่านั้นไม่ได้
+	h.ExpectProblem(h.Form(http.MethodPut, path, admin.AccessToken, map[string]string{"Amount": "3"}),
+		fiber.StatusConflict, apperr.CodeInsufficientStock)
+	expectOnHand(t, h, pen.ID, 10, 4)
+
+	h.Expect(h.Form(http.MethodPut, path, admin.AccessToken, map[string]string{"Amount": "4"}), fiber.StatusOK)
+	expectOnHand(t, h, pen.ID, 4, 4)
+}
+
+func TestExpiredReservationSweepSkipsFailingOrders(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	stuck := h.PlaceOrder(alice, testutil.ItemRequest{Product: "Pen", Amount: 4})
+	h.PlaceOrder(alice, testutil.ItemRequest{Product: "Pen", Amount: 6})
+
+	// order แรกอยู่ในสถานะที่ยกเลิกไม่ได้ การ sweep ต้องข้ามไปยกเลิก order ถัดไป
+	if err := h.DB.Model(&m.Order{}).Where("id = ?", stuck.ID).Update("status", m.OrderDelivered).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	later := time.Now().Add(h.Config.Inventory.ReservationTTL + time.Second)
+	released, err := controllers.ReleaseExpiredReservations(h.Store, later)
+	if err != nil || released != 1 {
+		t.Fatalf("expected the second order to be released, got %d (%v)", released, err)
+	}
+	expectOnHand(t, h, pen.ID, 10, 4)
+}
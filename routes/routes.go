@@ -1,40 +1,150 @@
 package routes
 
 import (
+	"go-fiber-test/config"
 	c "go-fiber-test/controllers"
 	md "go-fiber-test/middleware"
+	"go-fiber-test/payment"
+	"go-fiber-test/pricing"
+	"go-fiber-test/store"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 )
 
-func Routes(app *fiber.App) {
+// path ของ health probe สำหรับ orchestrator ซึ่งไม่ผ่าน rate limiter และไม่ต้อง login
+const (
+	HealthzPath = "/healthz"
+	ReadyzPath  = "/readyz"
+)
+
+// isCatalogRead ตรงกับ GET ของ /product และ /product/:productId ซึ่งมี limiter ของตัวเอง
+func isCatalogRead(c *fiber.Ctx) bool {
+	if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+		return false
+	}
+	path := strings.TrimSuffix(c.Path(), "/")
+	if path == "/product" {
+		return true
+	}
+	id, ok := strings.CutPrefix(path, "/product/")
+	return ok && id != "" && id != "export" && !strings.Contains(id, "/")
+}
+
+// Routes ผูก route ทั้งหมดเข้ากับ app โดย catalog คือ cache ของรายการสินค้าที่ route ซึ่งเปลี่ยนสินค้าหรือสต็อกจะล้างให้
+// ส่วนงานเบื้องหลังที่เปลี่ยนสต็อก (เช่น sweeper) ต้องล้าง cache เอง
+func Routes(app *fiber.App, cfg *config.Config, st store.Store, catalog *md.CatalogCache) {
+	// ทุก request มี X-Request-ID เพื่อใช้อ้างอิงใน error response และ log
+	// และ panic จะถูกแปลงเป็น error ให้ apperr.Handler ตอบกลับแทนการปิด connection
+	app.Use(requestid.New())
+	app.Use(recover.New())
+
+	// ใช้ limiter middleware จาก go fiber (ปิดได้ด้วยการตั้ง RATE_LIMIT_MAX=0)
+	// health probe ไม่ถูกจำกัด ส่วน GET ของรายการสินค้าใช้ limiter ของ catalog แทน
+	if cfg.RateLimit.Max > 0 {
+		app.Use(md.RateLimiter(cfg.RateLimit, func(c *fiber.Ctx) bool {
+			return c.Path() == HealthzPath || c.Path() == ReadyzPath || isCatalogRead(c)
+		}))
+	}
+	// limiter ของรายการสินค้า (ปิดได้ด้วยการตั้ง CATALOG_RATE_LIMIT_MAX=0)
+	catalogLimit := func(c *fiber.Ctx) error { return c.Next() }
+	if cfg.Catalog.RateLimit.Max > 0 {
+		catalogLimit = md.RateLimiter(cfg.Catalog.RateLimit, nil)
+	}
+
+	authRequired := md.AuthRequired(cfg.Auth, st.Sessions())
+	// request ที่ client ส่งซ้ำได้ด้วย Idempotency-Key จะไม่ถูกทำซ้ำ
+	idempotent := md.Idempotency(cfg.Idempotency, st.Idempotency())
+
+	health := c.NewHealthController(st, cfg.App.UploadDir)
+	app.Get(HealthzPath, health.Healthz)
+	app.Get(ReadyzPath, health.Readyz)
+
+	products := c.NewProductController(st, cfg.App.UploadDir)
+	provider := payment.New(cfg.Payment)
+	pricer := pricing.New(cfg.Pricing)
+	orders := c.NewOrderController(st, provider, pricer, cfg.Inventory.ReservationTTL)
+	payments := c.NewPaymentController(st, provider, cfg.Payment.Currency)
+	carts := c.NewCartController(st, pricer, cfg.Inventory.ReservationTTL)
+	invoices := c.NewInvoiceController(st, cfg.Payment.Currency)
+	stock := c.NewStockController(st)
+	exports := c.NewExportController(st)
+	reports := c.NewReportController(st)
+	coupons := c.NewCouponController(st)
+	users := c.NewUserController(st)
+	authentication := c.NewAuthController(st, cfg.Auth)
+
 	product := app.Group("/product")
-	product.Get("/", c.GetProducts)
-	product.Get("/:product_id/image/:image_id", c.GetProductImage)
-	product.Get("/:productId", c.GetProduct)
-	product.Post("/", md.AuthRequired, md.RoleRequired("admin"), c.AddProduct)
-	product.Put("/:productId", md.AuthRequired, md.RoleRequired("admin"), c.UpdateProduct)
-	product.Put("/restore/:productId", md.AuthRequired, md.RoleRequired("admin"), c.RestoreProduct)
-	product.Delete("/:productId", md.AuthRequired, md.RoleRequired("admin"), c.SoftDeleteProduct)
-	product.Delete("/bin/:productId", md.AuthRequired, md.RoleRequired("admin"), c.HardDeleteProduct)
-	product.Delete("/:product_id/image/:image_id", md.AuthRequired, md.RoleRequired("admin"), c.RemoveImage)
+	product.Get("/", catalogLimit, catalog.Cache, products.GetProducts)
+	product.Get("/export", authRequired, md.RoleRequired("admin"), exports.ExportProducts)
+	product.Get("/:product_id/image/:image_id", products.GetProductImage)
+	product.Get("/:productId", catalogLimit, catalog.Cache, products.GetProduct)
+	product.Get("/:productId/stock", authRequired, md.RoleRequired("admin"), stock.GetStockHistory)
+	product.Post("/:productId/stock", authRequired, md.RoleRequired("admin"), catalog.Invalidate, stock.AdjustStock)
+	product.Get("/:productId/stock/reconcile", authRequired, md.RoleRequired("admin"), stock.GetStockReconcile)
+	product.Post("/:productId/stock/reconcile", authRequired, md.RoleRequired("admin"), catalog.Invalidate, stock.ReconcileStock)
+	product.Post("/", authRequired, md.RoleRequired("admin"), idempotent, catalog.Invalidate, products.AddProduct)
+	product.Put("/:productId", authRequired, md.RoleRequired("admin"), catalog.Invalidate, products.UpdateProduct)
+	product.Put("/restore/:productId", authRequired, md.RoleRequired("admin"), catalog.Invalidate, products.RestoreProduct)
+	product.Delete("/:productId", authRequired, md.RoleRequired("admin"), catalog.Invalidate, products.SoftDeleteProduct)
+	product.Delete("/bin/:productId", authRequired, md.RoleRequired("admin"), catalog.Invalidate, products.HardDeleteProduct)
+	product.Delete("/:product_id/image/:image_id", authRequired, md.RoleRequired("admin"), catalog.Invalidate, products.RemoveImage)
 
+	// route ที่กัน ปล่อย หรือตัดสต็อกของ order ล้าง cache ของรายการสินค้าด้วย
 	order := app.Group("/order")
-	order.Get("/", md.AuthRequired, md.RoleRequired("admin"), c.GetOrders)
-	order.Get("/:userId", md.AuthRequired, c.GetOrder)
-	order.Post("/:userId", md.AuthRequired, md.RoleRequired("user"), c.AddOrder)
-	order.Put("/:orderId", md.AuthRequired, md.RoleRequired("user"), c.UpdateOrder)
-	order.Delete("/:orderId", md.AuthRequired, md.RoleRequired("user"), c.RemoveOrder)
+	order.Get("/", authRequired, md.RoleRequired("admin"), orders.GetOrders)
+	order.Get("/export", authRequired, md.RoleRequired("admin"), exports.ExportOrders)
+	order.Get("/:userId", authRequired, orders.GetOrder)
+	order.Post("/quote", authRequired, md.RoleRequired("user"), orders.QuoteOrder)
+	order.Post("/:userId", authRequired, md.RoleRequired("user"), idempotent, catalog.Invalidate, orders.AddOrder)
+	order.Put("/:orderId", authRequired, md.RoleRequired("user"), catalog.Invalidate, orders.UpdateOrder)
+	order.Put("/:orderId/status", authRequired, md.RoleRequired("admin"), catalog.Invalidate, orders.UpdateOrderStatus)
+	order.Delete("/:orderId", authRequired, md.RoleRequired("user"), catalog.Invalidate, orders.RemoveOrder)
+	order.Post("/:orderId/confirm", authRequired, catalog.Invalidate, orders.ConfirmOrder)
+	order.Post("/:orderId/payment", authRequired, md.RoleRequired("user"), payments.CreatePayment)
+	order.Get("/:orderId/invoice", authRequired, invoices.GetInvoice)
+
+	// provider เรียก webhook โดยตรงจึงไม่ต้อง login แต่ต้องมีลายเซ็นที่ถูกต้อง
+	app.Post("/payment/webhook", catalog.Invalidate, payments.Webhook)
+
+	coupon := app.Group("/coupon", authRequired, md.RoleRequired("admin"))
+	coupon.Get("/", coupons.GetCoupons)
+	coupon.Get("/:couponId", coupons.GetCoupon)
+	coupon.Post("/", coupons.AddCoupon)
+	coupon.Put("/:couponId", coupons.UpdateCoupon)
+	coupon.Delete("/:couponId", coupons.RemoveCoupon)
+
+	report := app.Group("/report", authRequired, md.RoleRequired("admin"))
+	report.Get("/sales", reports.GetSales)
+	report.Get("/summary", reports.GetSummary)
+	report.Get("/top-products", reports.GetTopProducts)
+	report.Get("/customers", reports.GetCustomers)
+
+	// ตะกร้าเป็นของ user ที่ login อยู่เสมอ จึงไม่มี userId ใน path
+	cart := app.Group("/cart", authRequired, md.RoleRequired("user"))
+	cart.Get("/", carts.GetCart)
+	cart.Delete("/", carts.ClearCart)
+	cart.Post("/items", carts.AddCartItem)
+	cart.Put("/items/:productId", carts.UpdateCartItem)
+	cart.Delete("/items/:productId", carts.RemoveCartItem)
+	cart.Post("/checkout", catalog.Invalidate, carts.Checkout)
 
 	user := app.Group("/user")
-	user.Get("/", md.AuthRequired, md.RoleRequired("admin"), c.GetUsers)
-	user.Post("/register", c.Register)
-	user.Post("/login", c.Login)
-	user.Post("/logout", c.Logout)
-	user.Post("/refresh-token", c.RefreshToken)
-	user.Put("/approve", md.AuthRequired, md.RoleRequired("admin"), c.Approve)
-	user.Put("/:userId", md.AuthRequired, c.UpdateUser)
-	user.Put("/restore/:userId", md.AuthRequired, md.RoleRequired("admin"), c.RestoreUser)
-	user.Delete("/:userId", md.AuthRequired, c.SoftDeleteUser)
-	user.Delete("/bin/:userId", md.AuthRequired, md.RoleRequired("admin"), c.HardDeleteUser)
+	user.Get("/", authRequired, md.RoleRequired("admin"), users.GetUsers)
+	user.Get("/export", authRequired, md.RoleRequired("admin"), exports.ExportUsers)
+	user.Post("/register", authentication.Register)
+	user.Post("/login", authentication.Login)
+	user.Post("/logout", authentication.Logout)
+	user.Post("/refresh-token", authentication.RefreshToken)
+	user.Put("/approve", authRequired, md.RoleRequired("admin"), authentication.Approve)
+	user.Put("/role", authRequired, md.RoleRequired("admin"), authentication.UpdateRole)
+	user.Put("/:userId", authRequired, users.UpdateUser)
+	user.Put("/restore/:userId", authRequired, md.RoleRequired("admin"), users.RestoreUser)
+	// การลบ user ยกเลิก order ที่ยังกันสินค้าไว้
+	user.Delete("/:userId", authRequired, catalog.Invalidate, users.SoftDeleteUser)
+	user.Delete("/bin/:userId", authRequired, md.RoleRequired("admin"), users.HardDeleteUser)
+
+	app.Static("/uploads", cfg.App.UploadDir)
 }
@@ -0,0 +1,355 @@
+package routes_test
+
+import (
+	"bytes"
+	"fmt"
+	"go-fiber-test/apperr"
+	m "go-fiber-test/models"
+	"go-fiber-test/payment"
+	"go-fiber-test/testutil"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRegisterValidatesUsername(t *testing.T) {
+	h := testutil.New(t)
+
+	problem := h.ExpectProblem(h.Request(http.MethodPost, "/user/register", "", map[string]string{
+		"Username":  "bad name!",
+		"Password":  testutil.DefaultPassword,
+		"FirstName": "Bad",
+		"LastName":  "Name",
+	}), fiber.StatusBadRequest, apperr.CodeValidation)
+	expectFieldErrors(t, problem, "Username:username")
+
+	h.Register("alice", testutil.DefaultPassword)
+	h.ExpectProblem(h.Request(http.MethodPost, "/user/register", "", map[string]string{
+		"Username":  "alice",
+		"Password":  testutil.DefaultPassword,
+		"FirstName": "Alice",
+		"LastName":  "Again",
+	}), fiber.StatusConflict, apperr.CodeConflict)
+}
+
+func TestRegisterReportsFieldErrors(t *testing.T) {
+	h := testutil.New(t)
+
+	problem := h.ExpectProblem(h.Request(http.MethodPost, "/user/register", "", map[string]string{
+		"Username": "alice",
+		"Password": "123",
+	}), fiber.StatusBadRequest, apperr.CodeValidation)
+	expectFieldErrors(t, problem, "Password:min", "FirstName:required", "LastName:required")
+
+	problem = h.ExpectProblem(h.Request(http.MethodPost, "/user/register", "", map[string]string{
+		"Username":  "alice",
+		"Password":  "a-password-that-is-far-too-long",
+		"FirstName": "Alice",
+		"LastName":  "Liddell",
+	}), fiber.StatusBadRequest, apperr.CodeValidation)
+	expectFieldErrors(t, problem, "Password:max")
+}
+
+func TestLoginRequiresApprovalAndPassword(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	id := h.Register("alice", testutil.DefaultPassword)
+
+	login := map[string]string{"Username": "alice", "Password": testutil.DefaultPassword}
+	h.Expect(h.Request(http.MethodPost, "/user/login", "", login), fiber.StatusBadRequest)
+
+	h.Approve(admin.AccessToken, id)
+	h.Expect(h.Form(http.MethodPut, "/user/approve", admin.AccessToken, map[string]string{
+		"UserID": fmt.Sprint(id),
+	}), fiber.StatusBadRequest)
+
+	h.Expect(h.Request(http.MethodPost, "/user/login", "", map[string]string{
+		"Username": "alice",
+		"Password": "wrong-password",
+	}), fiber.StatusBadRequest)
+
+	tokens := h.Login("alice", testutil.DefaultPassword)
+	if tokens.UserID != id || tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("unexpected tokens: %+v", tokens)
+	}
+}
+
+func TestApproveRequiresAdmin(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	bobID := h.Register("bob", testutil.DefaultPassword)
+
+	h.Expect(h.Form(http.MethodPut, "/user/approve", alice.AccessToken, map[string]string{
+		"UserID": fmt.Sprint(bobID),
+	}), fiber.StatusForbidden)
+}
+
+func TestRefreshTokenAndLogout(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+
+	var refreshed struct {
+		AccessToken string `json:"accessToken"`
+	}
+	h.Expect(h.Form(http.MethodPost, "/user/refresh-token", "", map[string]string{
+		"refreshToken": user.RefreshToken,
+	}), fiber.StatusOK).JSON(t, &refreshed)
+	if refreshed.AccessToken == "" {
+		t.Fatal("expected a new access token")
+	}
+
+	h.Expect(h.Form(http.MethodPost, "/user/refresh-token", "", map[string]string{
+		"refreshToken": user.AccessToken,
+	}), fiber.StatusUnauthorized)
+
+	h.Expect(h.Request(http.MethodPost, "/user/logout", user.AccessToken, nil), fiber.StatusOK)
+
+	// หลัง logout session ถูกลบ token เดิมจึงใช้ไม่ได้อีก
+	h.ExpectProblem(h.Request(http.MethodGet, fmt.Sprintf("/order/%d", user.UserID), user.AccessToken, nil), fiber.StatusUnauthorized, apperr.CodeSessionExpired)
+}
+
+func TestAuthRequiredRejectsMissingOrInvalidToken(t *testing.T) {
+	h := testutil.New(t)
+
+	h.Expect(h.Request(http.MethodGet, "/user", "", nil), fiber.StatusUnauthorized)
+	h.Expect(h.Request(http.MethodGet, "/user", "not-a-token", nil), fiber.StatusUnauthorized)
+}
+
+func TestSessionTimeout(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	path := fmt.Sprintf("/order/%d", user.UserID)
+
+	// request ภายในเวลา session timeout ผ่านได้ และต่ออายุ session
+	h.Expect(h.Request(http.MethodGet, path, user.AccessToken, nil), fiber.StatusOK)
+
+	h.ExpireSession(user.UserID)
+	problem := h.ExpectProblem(h.Request(http.MethodGet, path, user.AccessToken, nil), fiber.StatusUnauthorized, apperr.CodeSessionExpired)
+	if problem.Detail != "Session expired, Please login again." {
+		t.Fatalf("unexpected detail: %v", problem.Detail)
+	}
+
+	if _, err := h.Store.Sessions().Get(user.UserID); err == nil {
+		t.Fatal("expected expired session to be deleted")
+	}
+
+	// login ใหม่แล้วใช้งานได้อีกครั้ง
+	again := h.Login("alice", testutil.DefaultPassword)
+	h.Expect(h.Request(http.MethodGet, path, again.AccessToken, nil), fiber.StatusOK)
+}
+
+func TestGetUsersRequiresAdmin(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+
+	h.Expect(h.Request(http.MethodGet, "/user", user.AccessToken, nil), fiber.StatusForbidden)
+
+	var out struct {
+		Data []m.User `json:"data"`
+	}
+	h.Expect(h.Request(http.MethodGet, "/user", admin.AccessToken, nil), fiber.StatusOK).JSON(t, &out)
+	if len(out.Data) != 2 {
+		t.Fatalf("expected 2 users, got %d", len(out.Data))
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+
+	var out struct {
+		Data m.User `json:"data"`
+	}
+	h.Expect(h.Form(http.MethodPut, fmt.Sprintf("/user/%d", user.UserID), user.AccessToken, map[string]string{
+		"FirstName": "Alice",
+		"Password":  "new-secret",
+	}), fiber.StatusCreated).JSON(t, &out)
+	if out.Data.FirstName != "Alice" || out.Data.Username != "alice" {
+		t.Fatalf("unexpected user after update: %+v", out.Data)
+	}
+
+	h.Login("alice", "new-secret")
+
+	// password เดิมและ password ที่สั้นเกินไปใช้ไม่ได้
+	h.ExpectProblem(h.Form(http.MethodPut, fmt.Sprintf("/user/%d", user.UserID), user.AccessToken, map[string]string{
+		"Password": "new-secret",
+	}), fiber.StatusBadRequest, apperr.CodeBadRequest)
+	problem := h.ExpectProblem(h.Form(http.MethodPut, fmt.Sprintf("/user/%d", user.UserID), user.AccessToken, map[string]string{
+		"Password": "123",
+	}), fiber.StatusBadRequest, apperr.CodeValidation)
+	expectFieldErrors(t, problem, "Password:min")
+}
+
+func TestSoftDeleteUserCancelsUnpaidOrders(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+	pen := h.CreateProduct(admin.AccessToken, "Pen", 5, 10)
+	h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 3})
+	paid := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 2})
+	h.Expect(h.Request(http.MethodPut, fmt.Sprintf("/order/%d/status", paid.ID), admin.AccessToken, map[string]string{
+		"Status": m.OrderPaid,
+	}), fiber.StatusOK)
+	// order ที่ชำระเงินไม่สำเร็จยังกันสินค้าไว้ให้จ่ายใหม่
+	failed := h.PlaceOrder(user, testutil.ItemRequest{Product: "Pen", Amount: 1})
+	pay := h.Pay(user, failed.ID)
+	h.Expect(h.Webhook(payment.Event{ID: "evt_1", Type: payment.EventFailed, IntentID: pay.ProviderRef}), fiber.StatusOK)
+	expectStock(t, h, pen, 4)
+
+	// order ที่ยังไม่ชำระเงิน (pending และ failed) ถูกยกเลิกและคืนสต็อก ส่วน order ที่ชำระแล้วยังคงอยู่
+	h.Expect(h.Request(http.MethodDelete, fmt.Sprintf("/user/%d", user.UserID), user.AccessToken, nil), fiber.StatusOK)
+	expectStock(t, h, pen, 8)
+
+	orders, err := h.Store.Orders().ListByBuyer(fmt.Sprint(user.UserID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[uint]string{}
+	for _, order := range orders {
+		statuses[order.ID] = order.Status
+	}
+	if len(orders) != 3 || statuses[paid.ID] != m.OrderPaid {
+		t.Fatalf("unexpected orders after delete: %v", statuses)
+	}
+	for id, status := range statuses {
+		if id != paid.ID && status != m.OrderCancelled {
+			t.Fatalf("expected unpaid order %d to be cancelled, got %s", id, status)
+		}
+	}
+}
+
+func TestRestoreAndHardDeleteUser(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	id := h.Register("alice", testutil.DefaultPassword)
+	path := fmt.Sprintf("/user/%d", id)
+
+	// hard delete ได้เฉพาะ user ที่ถูก soft delete แล้ว
+	h.Expect(h.Request(http.MethodDelete, fmt.Sprintf("/user/bin/%d", id), admin.AccessToken, nil), fiber.StatusNotFound)
+
+	h.Expect(h.Request(http.MethodDelete, path, admin.AccessToken, nil), fiber.StatusOK)
+	h.Expect(h.Request(http.MethodPut, fmt.Sprintf("/user/restore/%d", id), admin.AccessToken, nil), fiber.StatusOK)
+	if _, err := h.Store.Users().Get(id); err != nil {
+		t.Fatalf("expected user to be restored: %v", err)
+	}
+
+	h.Expect(h.Request(http.MethodDelete, path, admin.AccessToken, nil), fiber.StatusOK)
+	h.Expect(h.Request(http.MethodDelete, fmt.Sprintf("/user/bin/%d", id), admin.AccessToken, nil), fiber.StatusOK)
+	if _, err := h.Store.Users().GetDeleted(id); err == nil {
+		t.Fatal("expected user to be removed permanently")
+	}
+}
+
+func TestRegisterIgnoresPrivilegedFields(t *testing.T) {
+	h := testutil.New(t)
+
+	resp := h.Expect(h.Request(http.MethodPost, "/user/register", "", map[string]any{
+		"Username":  "mallory",
+		"Password":  testutil.DefaultPassword,
+		"FirstName": "Mallory",
+		"LastName":  "Evil",
+		"Role":      "admin",
+		"Approve":   true,
+	}), fiber.StatusCreated)
+
+	var out struct {
+		Data m.User `json:"data"`
+	}
+	resp.JSON(t, &out)
+	if out.Data.Role != "user" || out.Data.Approve {
+		t.Fatalf("expected privileged fields to be ignored, got %+v", out.Data)
+	}
+	h.ExpectProblem(h.Request(http.MethodPost, "/user/login", "", map[string]string{
+		"Username": "mallory",
+		"Password": testutil.DefaultPassword,
+	}), fiber.StatusBadRequest, apperr.CodeNotApproved)
+}
+
+func TestUserResponsesNeverIncludePassword(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	user := h.LoginUser(admin.AccessToken, "alice")
+
+	expectNoPassword := func(resp *testutil.Response) {
+		t.Helper()
+		if bytes.Contains(resp.Body, []byte("Password")) || bytes.Contains(resp.Body, []byte("$2a$")) {
+			t.Fatalf("response leaks password: %s", resp.Body)
+		}
+	}
+
+	expectNoPassword(h.Expect(h.Request(http.MethodPost, "/user/register", "", map[string]string{
+		"Username":  "bob",
+		"Password":  testutil.DefaultPassword,
+		"FirstName": "Bob",
+		"LastName":  "Builder",
+	}), fiber.StatusCreated))
+	expectNoPassword(h.Expect(h.Request(http.MethodGet, "/user", admin.AccessToken, nil), fiber.StatusOK))
+	expectNoPassword(h.Expect(h.Form(http.MethodPut, fmt.Sprintf("/user/%d", user.UserID), user.AccessToken, map[string]string{
+		"LastName": "Liddell",
+	}), fiber.StatusCreated))
+
+	h.Expect(h.Request(http.MethodDelete, fmt.Sprintf("/user/%d", user.UserID), admin.AccessToken, nil), fiber.StatusOK)
+	expectNoPassword(h.Expect(h.Request(http.MethodPut, fmt.Sprintf("/user/restore/%d", user.UserID), admin.AccessToken, nil), fiber.StatusOK))
+}
+
+func TestUsersCannotManageOtherUsers(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	bob := h.LoginUser(admin.AccessToken, "bob")
+
+	h.Expect(h.Form(http.MethodPut, fmt.Sprintf("/user/%d", alice.UserID), bob.AccessToken, map[string]string{
+		"Password": "hijacked",
+	}), fiber.StatusUnauthorized)
+	h.Expect(h.Request(http.MethodDelete, fmt.Sprintf("/user/%d", alice.UserID), bob.AccessToken, nil), fiber.StatusUnauthorized)
+
+	// admin แก้ไขข้อมูลของ user คนอื่นได้
+	h.Expect(h.Form(http.MethodPut, fmt.Sprintf("/user/%d", alice.UserID), admin.AccessToken, map[string]string{
+		"FirstName": "Alice",
+	}), fiber.StatusCreated)
+	h.Login("alice", testutil.DefaultPassword)
+}
+
+func TestUpdateRoleRequiresAdmin(t *testing.T) {
+	h := testutil.New(t)
+	admin := h.LoginAdmin("admin")
+	alice := h.LoginUser(admin.AccessToken, "alice")
+	bob := h.LoginUser(admin.AccessToken, "bob")
+
+	h.Expect(h.Form(http.MethodPut, "/user/role", alice.AccessToken, map[string]string{
+		"UserID": fmt.Sprint(alice.UserID),
+		"Role":   "admin",
+	}), fiber.StatusForbidden)
+
+	problem := h.ExpectProblem(h.Form(http.MethodPut, "/user/role", admin.AccessToken, map[string]string{
+		"UserID": fmt.Sprint(bob.UserID),
+		"Role":   "root",
+	}), fiber.StatusBadRequest, apperr.CodeValidation)
+	expectFieldErrors(t, problem, "Role:oneof")
+
+	h.ExpectProblem(h.Form(http.MethodPut, "/user/role", admin.AccessToken, map[string]string{
+		"UserID": fmt.Sprint(admin.UserID),
+		"Role":   "user",
+	}), fiber.StatusBadRequest, apperr.CodeBadRequest)
+
+	var out struct {
+		Data m.User `json:"data"`
+	}
+	h.Expect(h.Form(http.MethodPut, "/user/role", admin.AccessToken, map[string]string{
+		"UserID": fmt.Sprint(bob.UserID),
+		"Role":   "admin",
+	}), fiber.StatusOK).JSON(t, &out)
+	if out.Data.Role != "admin" {
+		t.Fatalf("expected bob to be admin, got %+v", out.Data)
+	}
+
+	// token ใหม่ของ bob มี role admin
+	again := h.Login("bob", testutil.DefaultPassword)
+	h.Expect(h.Request(http.MethodGet, "/user", again.AccessToken, nil), fiber.StatusOK)
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// gormStore คือ Store ที่ใช้ GORM ใช้ได้กับทุก driver ที่ database.Open รองรับ
+type gormStore struct {
+	db *gorm.DB
+}
+
+// New สร้าง Store จากการเชื่อมต่อ GORM
+func New(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) Products() ProductStore        { return &gormProductStore{db: s.db} }
+func (s *gormStore) Orders() OrderStore            { return &gormOrderStore{db: s.db} }
+func (s *gormStore) Users() UserStore              { return &gormUserStore{db: s.db} }
+func (s *gormStore) Sessions() SessionStore        { return &gormSessionStore{db: s.db} }
+func (s *gormStore) Carts() CartStore              { return &gormCartStore{db: s.db} }
+func (s *gormStore) Payments() PaymentStore        { return &gormPaymentStore{db: s.db} }
+func (s *gormStore) Coupons() CouponStore          { return &gormCouponStore{db: s.db} }
+func (s *gormStore) Invoices() InvoiceStore        { return &gormInvoiceStore{db: s.db} }
+func (s *gormStore) Reports() ReportStore          { return &gormReportStore{db: s.db} }
+func (s *gormStore) Idempotency() IdempotencyStore { return &gormIdempotencyStore{db: s.db} }
+
+func (s *gormStore) Ping(ctx context.Context) error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (s *gormStore) Transaction(fn func(tx Store) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&gormStore{db: tx})
+	})
+}
+
+// notFound แปลง gorm.ErrRecordNotFound ให้เป็น ErrNotFound เพื่อไม่ให้ controller ต้องรู้จัก GORM
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	m "go-fiber-test/models"
+
+	"gorm.io/gorm"
+)
+
+type gormCartStore struct {
+	db *gorm.DB
+}
+
+func (s *gormCartStore) List(userID uint) ([]m.CartItem, error) {
+	var items []m.CartItem
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (s *gormCartStore) Get(userID, productID uint) (*m.CartItem, error) {
+	var item m.CartItem
+	if err := s.db.Where("user_id = ? AND product_id = ?", userID, productID).First(&item).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &item, nil
+}
+
+func (s *gormCartStore) Save(item *m.CartItem) error {
+	return s.db.Save(item).Error
+}
+
+func (s *gormCartStore) Delete(item *m.CartItem) error {
+	return s.db.Delete(item).Error
+}
+
+func (s *gormCartStore) Clear(userID uint) error {
+	return s.db.Where("user_id = ?", userID).Delete(&m.CartItem{}).Error
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	m "go-fiber-test/models"
+
+	"gorm.io/gorm"
+)
+
+type gormCouponStore struct {
+	db *gorm.DB
+}
+
+func (s *gormCouponStore) List(page Page) (Result[m.Coupon], error) {
+	return list[m.Coupon](s.db.Model(&m.Coupon{}), page)
+}
+
+func (s *gormCouponStore) Get(id uint) (*m.Coupon, error) {
+	var coupon m.Coupon
+	if err := s.db.Where("id = ?", id).First(&coupon).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &coupon, nil
+}
+
+func (s *gormCouponStore) GetAny(id uint) (*m.Coupon, error) {
+	var coupon m.Coupon
+	if err := s.db.Unscoped().Where("id = ?", id).First(&coupon).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &coupon, nil
+}
+
+func (s *gormCouponStore) GetByCode(code string) (*m.Coupon, error) {
+	var coupon m.Coupon
+	if err := s.db.Where("code = ?", code).First(&coupon).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &coupon, nil
+}
+
+func (s *gormCouponStore) CodeExists(code string) (bool, error) {
+	var count int64
+	if err := s.db.Unscoped().Model(&m.Coupon{}).Where("code = ?", code).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *gormCouponStore) Create(coupon *m.Coupon) error {
+	return s.db.Create(coupon).Error
+}
+
+func (s *gormCouponStore) Save(coupon *m.Coupon) error {
+	return s.db.Save(coupon).Error
+}
+
+func (s *gormCouponStore) Delete(coupon *m.Coupon) error {
+	return s.db.Delete(coupon).Error
+}
+
+func (s *gormCouponStore) Redeem(id uint) error {
+	// ตรวจ limit และเพิ่มจำนวนใน UPDATE เดียวกันแบบเดียวกับ AdjustStock จึงใช้เกิน limit ไม่ได้แม้มีหลาย request พร้อมกัน
+	result := s.db.Model(&m.Coupon{}).
+		Where("id = ? AND (usage_limit = 0 OR used < usage_limit)", id).
+		Update("used", gorm.Expr("used + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := s.Get(id); err != nil {
+			return err
+		}
+		return ErrUsageLimit
+	}
+	return nil
+}
+
+func (s *gormCouponStore) Release(id, orderID uint) error {
+	result := s.db.Where("coupon_id = ? AND order_id = ?", id, orderID).Delete(&m.CouponRedemption{})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	return s.db.Unscoped().Model(&m.Coupon{}).
+		Where("id = ? AND used > 0", id).
+		Update("used", gorm.Expr("used - 1")).Error
+}
+
+func (s *gormCouponStore) CountRedemptions(couponID, userID uint) (int64, error) {
+	var count int64
+	err := s.db.Model(&m.CouponRedemption{}).Where("coupon_id = ? AND user_id = ?", couponID, userID).Count(&count).Error
+	return count, err
+}
+
+func (s *gormCouponStore) CreateRedemption(redemption *m.CouponRedemption) error {
+	return s.db.Create(redemption).Error
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	m "go-fiber-test/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormIdempotencyStore struct {
+	db *gorm.DB
+}
+
+func (s *gormIdempotencyStore) Begin(record *m.IdempotencyKey, now time.Time) (*m.IdempotencyKey, bool, error) {
+	// record ที่หมดอายุแล้วถูกลบก่อน คีย์เดิมจึงใช้กับ request ใหม่ได้
+	if err := s.db.Where("user_id = ? AND idempotency_key = ? AND expires_at <= ?", record.UserID, record.Key, now).
+		Delete(&m.IdempotencyKey{}).Error; err != nil {
+		return nil, false, err
+	}
+
+	// ถ้ามี request ที่ใช้คีย์เดียวกันพร้อมกัน unique index ทำให้มีแค่ request เดียวที่สร้าง record ได้
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return record, true, nil
+	}
+
+	var existing m.IdempotencyKey
+	if err := s.db.Where("user_id = ? AND idempotency_key = ?", record.UserID, record.Key).First(&existing).Error; err != nil {
+		return nil, false, notFound(err)
+	}
+	return &existing, false, nil
+}
+
+func (s *gormIdempotencyStore) Complete(record *m.IdempotencyKey) error {
+	record.Completed = true
+	return s.db.Model(record).Select("Completed", "Status", "ContentType", "Body").Updates(record).Error
+}
+
+func (s *gormIdempotencyStore) Abandon(record *m.IdempotencyKey) error {
+	return s.db.Delete(record).Error
+}
+
+func (s *gormIdempotencyStore) DeleteExpired(now time.Time) (int64, error) {
+	result := s.db.Where("expires_at <= ?", now).Delete(&m.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"errors"
+	m "go-fiber-test/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormInvoiceStore struct {
+	db *gorm.DB
+}
+
+func (s *gormInvoiceStore) GetOrCreate(invoice *m.Invoice) (*m.Invoice, error) {
+	// ค้นหาก่อนแล้วค่อย insert เมื่อยังไม่มี เพราะ mysql/postgres ใช้ค่า auto increment ไปแล้วแม้ insert จะชน unique index
+	// ซึ่งทำให้เลขที่ใบแจ้งหนี้ข้าม
+	existing, err := s.getByOrder(invoice.OrderID)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// ถ้ามี request ออกใบแจ้งหนี้ของ order เดียวกันพร้อมกัน unique index ของ order_id ทำให้มีแค่ใบเดียวถูกสร้าง
+	// รายการสินค้าถูกบันทึกใน transaction เดียวกับใบแจ้งหนี้เฉพาะเมื่อใบนี้ถูกสร้างจริง
+	created := false
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Omit("Lines").Clauses(clause.OnConflict{DoNothing: true}).Create(invoice)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		created = true
+		for i := range invoice.Lines {
+			invoice.Lines[i].InvoiceID = invoice.ID
+		}
+		if len(invoice.Lines) == 0 {
+			return nil
+		}
+		return tx.Create(&invoice.Lines).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		return invoice, nil
+	}
+
+	existing, err = s.getByOrder(invoice.OrderID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return existing, nil
+}
+
+func (s *gormInvoiceStore) getByOrder(orderID uint) (*m.Invoice, error) {
+	var invoice m.Invoice
+	err := s.db.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Where("order_id = ?", orderID).First(&invoice).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	m "go-fiber-test/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormOrderStore struct {
+	db *gorm.DB
+}
+
+func (s *gormOrderStore) List(filter OrderFilter, page Page) (Result[m.Order], error) {
+	// ใช้ GORM Preloading เพื่อให้ GORM สามารถโหลดข้อมูลที่สัมพันธ์กับ Orders มาได้
+	return list[m.Order](s.filter(filter), page, "Items")
+}
+
+func (s *gormOrderStore) Each(filter OrderFilter, fn func(batch []m.Order) error) error {
+	return each(s.filter(filter), fn, "Items")
+}
+
+func (s *gormOrderStore) filter(filter OrderFilter) *gorm.DB {
+	query := s.db.Model(&m.Order{})
+	if filter.Buyer != "" {
+		query = query.Where("buyer = ?", filter.Buyer)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	return createdBetween(query, filter.CreatedFrom, filter.CreatedTo)
+}
+
+func (s *gormOrderStore) ListByBuyer(buyer string) ([]m.Order, error) {
+	var orders []m.Order
+	if err := s.db.Preload("Items").Where("buyer = ?", buyer).Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (s *gormOrderStore) Get(id uint) (*m.Order, error) {
+	var order m.Order
+	if err := s.db.Preload("Items").Where("id = ?", id).First(&order).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &order, nil
+}
+
+func (s *gormOrderStore) GetForUpdate(id uint) (*m.Order, error) {
+	var order m.Order
+	if err := s.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&order).Error; err != nil {
+		return nil, notFound(err)
+	}
+	if err := s.db.Where("order_id = ?", id).Find(&order.Items).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (s *gormOrderStore) Create(order *m.Order) error {
+	return s.db.Create(order).Error
+}
+
+func (s *gormOrderStore) Save(order *m.Order) error {
+	return saveVersioned(s.db, order, &order.Version, clause.Associations)
+}
+
+func (s *gormOrderStore) CreateItem(item *m.Item) error {
+	return s.db.Create(item).Error
+}
+
+func (s *gormOrderStore) SaveItem(item *m.Item) error {
+	return s.db.Save(item).Error
+}
+
+func (s *gormOrderStore) Delete(order *m.Order) error {
+	// ลบรายการสินค้าในคำสั่งซื้อนั้น
+	if err := s.db.Unscoped().Where("order_id = ?", order.ID).Delete(&m.Item{}).Error; err != nil {
+		return err
+	}
+	return s.db.Unscoped().Delete(order).Error
+}
+
+func (s *gormOrderStore) ListExpiredReservations(now time.Time) ([]m.Order, error) {
+	var orders []m.Order
+	if err := s.db.Where("reserved_until IS NOT NULL AND reserved_until <= ?", now).Order("id").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
@@ -0,0 +1,35 @@
+package store
+
+import (
+	m "go-fiber-test/models"
+
+	"gorm.io/gorm"
+)
+
+type gormPaymentStore struct {
+	db *gorm.DB
+}
+
+func (s *gormPaymentStore) Create(payment *m.Payment) error {
+	return s.db.Create(payment).Error
+}
+
+func (s *gormPaymentStore) Save(payment *m.Payment) error {
+	return s.db.Save(payment).Error
+}
+
+func (s *gormPaymentStore) GetByProviderRef(provider, ref string) (*m.Payment, error) {
+	var payment m.Payment
+	if err := s.db.Where("provider = ? AND provider_ref = ?", provider, ref).First(&payment).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &payment, nil
+}
+
+func (s *gormPaymentStore) ListByOrder(orderID uint) ([]m.Payment, error) {
+	var payments []m.Payment
+	if err := s.db.Where("order_id = ?", orderID).Order("id").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
@@ -0,0 +1,289 @@
+package store
+
+import (
+	m "go-fiber-test/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormProductStore struct {
+	db *gorm.DB
+}
+
+func (s *gormProductStore) List(filter ProductFilter, page Page) (Result[m.Product], error) {
+	return list[m.Product](s.filter(filter), page, "Images")
+}
+
+func (s *gormProductStore) Each(filter ProductFilter, fn func(batch []m.Product) error) error {
+	return each(s.filter(filter), fn, "Images")
+}
+
+func (s *gormProductStore) filter(filter ProductFilter) *gorm.DB {
+	query := s.db.Model(&m.Product{})
+	if filter.MinPrice != nil {
+		query = query.Where("price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		query = query.Where("price <= ?", *filter.MaxPrice)
+	}
+	if filter.InStock != nil {
+		if *filter.InStock {
+			query = query.Where("amount - reserved > 0")
+		} else {
+			query = query.Where("amount - reserved <= 0")
+		}
+	}
+	return createdBetween(query, filter.CreatedFrom, filter.CreatedTo)
+}
+
+func (s *gormProductStore) Get(id uint) (*m.Product, error) {
+	var product m.Product
+	if err := s.db.Preload("Images").Where("id = ?", id).First(&product).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &product, nil
+}
+
+func (s *gormProductStore) GetForUpdate(id uint) (*m.Product, error) {
+	var product m.Product
+	if err := s.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&product).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &product, nil
+}
+
+func (s *gormProductStore) GetByName(name string) (*m.Product, error) {
+	var product m.Product
+	if err := s.db.Where("product_name = ?", name).First(&product).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &product, nil
+}
+
+func (s *gormProductStore) GetDeleted(id uint) (*m.Product, error) {
+	var product m.Product
+	if err := s.db.Unscoped().Preload("Images").Where("id = ? AND deleted_at IS NOT NULL", id).First(&product).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &product, nil
+}
+
+func (s *gormProductStore) Create(product *m.Product, actorID *uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(product).Error; err != nil {
+			return err
+		}
+		return tx.Create(&m.StockMovement{
+			ProductID: product.ID,
+			Delta:     product.Amount,
+			Balance:   product.Amount,
+			Reason:    m.StockInitial,
+			ActorID:   actorID,
+		}).Error
+	})
+}
+
+func (s *gormProductStore) Save(product *m.Product) error {
+	// amount เปลี่ยนได้ผ่าน AdjustStock/Restock/SetStock เท่านั้น เพื่อให้ทุกการเปลี่ยนแปลงมีใน ledger
+	// ส่วน reserved เปลี่ยนผ่าน Reserve/Release/ConfirmReservation
+	return saveVersioned(s.db, product, &product.Version, clause.Associations, "Amount", "Reserved")
+}
+
+func (s *gormProductStore) AdjustStock(move *m.StockMovement) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// เงื่อนไขอยู่ใน UPDATE เดียวกัน ฐานข้อมูลจึงตรวจและลดให้แบบ atomic และไม่ลดจนต่ำกว่าจำนวนที่ถูกกันไว้
+		result := tx.Model(&m.Product{}).
+			Where("id = ? AND amount - reserved + ? >= 0", move.ProductID, move.Delta).
+			Updates(map[string]any{"amount": gorm.Expr("amount + ?", move.Delta), "version": nextVersion})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			if _, err := (&gormProductStore{db: tx}).Get(move.ProductID); err != nil {
+				return err
+			}
+			return ErrInsufficientStock
+		}
+		return record(tx, move)
+	})
+}
+
+func (s *gormProductStore) Reserve(id uint, amount int) error {
+	result := s.db.Model(&m.Product{}).
+		Where("id = ? AND amount - reserved >= ?", id, amount).
+		Updates(map[string]any{"reserved": gorm.Expr("reserved + ?", amount), "version": nextVersion})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := s.Get(id); err != nil {
+			return err
+		}
+		return ErrInsufficientStock
+	}
+	return nil
+}
+
+func (s *gormProductStore) Release(id uint, amount int) error {
+	return s.db.Unscoped().Model(&m.Product{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"reserved": gorm.Expr("reserved - ?", amount), "version": nextVersion}).Error
+}
+
+func (s *gormProductStore) ConfirmReservation(move *m.StockMovement) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&m.Product{}).
+			Where("id = ?", move.ProductID).
+			Updates(map[string]any{
+				"amount":   gorm.Expr("amount + ?", move.Delta),
+				"reserved": gorm.Expr("reserved + ?", move.Delta),
+				"version":  nextVersion,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return record(tx, move)
+	})
+}
+
+func (s *gormProductStore) Restock(move *m.StockMovement) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&m.Product{}).
+			Where("id = ?", move.ProductID).
+			Updates(map[string]any{"amount": gorm.Expr("amount + ?", move.Delta), "version": nextVersion})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return record(tx, move)
+	})
+}
+
+func (s *gormProductStore) SetStock(move *m.StockMovement, amount int) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var product m.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", move.ProductID).First(&product).Error; err != nil {
+			return notFound(err)
+		}
+		// ตรวจขณะถือ lock ของแถว จึงไม่มี order ใหม่มากันสินค้าเพิ่มระหว่างตรวจกับบันทึก
+		if amount < product.Reserved {
+			return ErrInsufficientStock
+		}
+		move.Delta = amount - product.Amount
+		if move.Delta == 0 {
+			return nil
+		}
+		if err := tx.Model(&product).Updates(map[string]any{"amount": amount, "version": nextVersion}).Error; err != nil {
+			return err
+		}
+		move.Balance = amount
+		return tx.Create(move).Error
+	})
+}
+
+// record บันทึก move ลง ledger โดยอ่านจำนวนหลังเปลี่ยนแปลงจากแถวที่เพิ่งถูก UPDATE (ซึ่งยัง lock อยู่) มาเป็น Balance
+// ใช้ Unscoped เพราะ Restock คืนสินค้าให้ product ที่ถูก soft delete ได้
+func record(tx *gorm.DB, move *m.StockMovement) error {
+	if err := tx.Unscoped().Model(&m.Product{}).Select("amount").Where("id = ?", move.ProductID).Scan(&move.Balance).Error; err != nil {
+		return err
+	}
+	return tx.Create(move).Error
+}
+
+func (s *gormProductStore) Movements(productID uint, page Page) (Result[m.StockMovement], error) {
+	return list[m.StockMovement](s.db.Model(&m.StockMovement{}).Where("product_id = ?", productID), page)
+}
+
+func (s *gormProductStore) LedgerBalance(productID uint) (int, error) {
+	var balance int
+	err := s.db.Model(&m.StockMovement{}).Where("product_id = ?", productID).
+		Select("COALESCE(SUM(delta), 0)").Scan(&balance).Error
+	return balance, err
+}
+
+func (s *gormProductStore) Reconcile(id uint) (int, error) {
+	var balance int
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var product m.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&product).Error; err != nil {
+			return notFound(err)
+		}
+		var err error
+		if balance, err = (&gormProductStore{db: tx}).LedgerBalance(id); err != nil {
+			return err
+		}
+		if balance == product.Amount {
+			return nil
+		}
+		return tx.Model(&product).Updates(map[string]any{"amount": balance, "version": nextVersion}).Error
+	})
+	return balance, err
+}
+
+func (s *gormProductStore) SoftDelete(product *m.Product) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := softDeleteVersioned(tx, product, &product.Version); err != nil {
+			return err
+		}
+		// soft delete images ในฐานข้อมูล
+		return tx.Where("product_id = ?", product.ID).Delete(&m.ProductImage{}).Error
+	})
+}
+
+func (s *gormProductStore) Restore(id uint, version int) (*m.Product, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// restore product
+		if err := restoreVersioned(tx, &m.Product{}, id, version); err != nil {
+			return err
+		}
+		// restore images ในฐานข้อมูล
+		return tx.Unscoped().Model(&m.ProductImage{}).Where("product_id = ?", id).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+func (s *gormProductStore) HardDelete(product *m.Product) error {
+	// hard delete images ในฐานข้อมูล
+	if err := s.db.Unscoped().Where("product_id = ?", product.ID).Delete(&m.ProductImage{}).Error; err != nil {
+		return err
+	}
+	return s.db.Unscoped().Delete(product).Error
+}
+
+func (s *gormProductStore) GetImage(productID, imageID uint) (*m.ProductImage, error) {
+	var image m.ProductImage
+	if err := s.db.Where("id = ? AND product_id = ?", imageID, productID).First(&image).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &image, nil
+}
+
+func (s *gormProductStore) AddImage(image *m.ProductImage) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(image).Error; err != nil {
+			return err
+		}
+		return bumpVersion(tx, image.ProductID)
+	})
+}
+
+func (s *gormProductStore) DeleteImage(image *m.ProductImage) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(image).Error; err != nil {
+			return err
+		}
+		return bumpVersion(tx, image.ProductID)
+	})
+}
+
+// nextVersion เพิ่ม version ของ product ใน UPDATE ที่เปลี่ยนจำนวนสินค้าหรือรูปภาพ ซึ่งอยู่ใน response ของ product ด้วย
+// ETag ของ product จึงเปลี่ยนตาม และการแก้ไขที่ส่ง If-Match ของ version ก่อนหน้ามาจะได้ 412
+var nextVersion = gorm.Expr("version + 1")
+
+// bumpVersion เพิ่ม version ของ product เมื่อรูปภาพเปลี่ยน gorm เลื่อน UpdatedAt ให้ด้วย
+func bumpVersion(tx *gorm.DB, productID uint) error {
+	return tx.Model(&m.Product{}).Where("id = ?", productID).Update("version", nextVersion).Error
+}
@@ -0,0 +1,37 @@
+package store
+
+import (
+	m "go-fiber-test/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type gormSessionStore struct {
+	db *gorm.DB
+}
+
+func (s *gormSessionStore) Get(userID uint) (*m.Session, error) {
+	var session m.Session
+	if err := s.db.Where("user_id = ?", userID).First(&session).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &session, nil
+}
+
+func (s *gormSessionStore) Touch(userID uint, at time.Time) error {
+	session := m.Session{}
+
+	// ตรวจสอบว่ามี Session อยู่แล้วหรือไม่ หรือสร้างใหม่ถ้าไม่มี
+	if err := s.db.Where("user_id = ?", userID).Attrs(m.Session{LastActive: at}).FirstOrCreate(&session, m.Session{UserID: userID}).Error; err != nil {
+		return err
+	}
+
+	// update เวลาการกระทำล่าสุด
+	session.LastActive = at
+	return s.db.Save(&session).Error
+}
+
+func (s *gormSessionStore) Delete(userID uint) error {
+	return s.db.Delete(&m.Session{}, "user_id = ?", userID).Error
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	m "go-fiber-test/models"
+
+	"gorm.io/gorm"
+)
+
+type gormUserStore struct {
+	db *gorm.DB
+}
+
+func (s *gormUserStore) List(filter UserFilter, page Page) (Result[m.User], error) {
+	return list[m.User](s.filter(filter), page)
+}
+
+func (s *gormUserStore) Each(filter UserFilter, fn func(batch []m.User) error) error {
+	return each(s.filter(filter), fn)
+}
+
+func (s *gormUserStore) filter(filter UserFilter) *gorm.DB {
+	query := s.db.Model(&m.User{})
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Approved != nil {
+		query = query.Where("approve = ?", *filter.Approved)
+	}
+	return createdBetween(query, filter.CreatedFrom, filter.CreatedTo)
+}
+
+func (s *gormUserStore) Get(id uint) (*m.User, error) {
+	var user m.User
+	if err := s.db.Where("id = ?", id).First(&user).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (s *gormUserStore) GetByUsername(username string) (*m.User, error) {
+	var user m.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (s *gormUserStore) GetDeleted(id uint) (*m.User, error) {
+	var user m.User
+	if err := s.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&user).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (s *gormUserStore) Create(user *m.User) error {
+	return s.db.Create(user).Error
+}
+
+func (s *gormUserStore) Save(user *m.User) error {
+	return saveVersioned(s.db, user, &user.Version)
+}
+
+func (s *gormUserStore) SoftDelete(user *m.User) error {
+	return softDeleteVersioned(s.db, user, &user.Version)
+}
+
+func (s *gormUserStore) Restore(id uint, version int) (*m.User, error) {
+	if err := restoreVersioned(s.db, &m.User{}, id, version); err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+func (s *gormUserStore) HardDelete(user *m.User) error {
+	return s.db.Unscoped().Delete(user).Error
+}
@@ -0,0 +1,191 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Page คือการแบ่งหน้าและการเรียงลำดับของ list ถ้ามี Cursor จะใช้ keyset pagination และไม่สนใจ Offset
+type Page struct {
+	Limit  int
+	Offset int
+	// Sort คือชื่อ column ที่ใช้เรียง (ต้องผ่านการตรวจสอบจาก whitelist มาแล้ว) ถ้าว่างจะเรียงตาม id
+	Sort   string
+	Desc   bool
+	Cursor *Cursor
+}
+
+// Cursor คือตำแหน่งของแถวที่ใช้เป็นจุดเริ่มของหน้าถัดไป (หรือหน้าก่อนหน้าถ้า Before เป็น true)
+// Value คือค่าของ column ที่ใช้เรียงของแถวนั้น และ ID ใช้ตัดสินเมื่อค่าซ้ำกัน
+type Cursor struct {
+	Value  any
+	ID     uint
+	Before bool
+}
+
+// Result คือผลลัพธ์ของ list หนึ่งหน้า Total คือจำนวนแถวทั้งหมดที่ตรงกับ filter
+// More บอกว่ายังมีแถวถัดไปในทิศทางที่แบ่งหน้าอยู่หรือไม่
+type Result[T any] struct {
+	Items []T
+	Total int64
+	More  bool
+}
+
+type ProductFilter struct {
+	MinPrice    *int
+	MaxPrice    *int
+	InStock     *bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+}
+
+type OrderFilter struct {
+	Buyer       string
+	Status      string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+}
+
+type UserFilter struct {
+	Role        string
+	Approved    *bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+}
+
+// ReportFilter คือช่วงเวลาและความละเอียดของรายงาน From รวมขอบเขต ส่วน To ไม่รวม
+type ReportFilter struct {
+	// Interval คือช่วงที่ใช้รวมข้อมูล: IntervalDay, IntervalWeek หรือ IntervalMonth
+	Interval string
+	From     *time.Time
+	To       *time.Time
+}
+
+// ความละเอียดของรายงาน สัปดาห์เริ่มวันจันทร์
+const (
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+// SalesPoint คือยอดขายในหนึ่งช่วง Period คือวันแรกของช่วงในรูปแบบ 2006-01-02
+// Revenue คือ Subtotal - Discount ของ order ไม่รวมภาษีและค่าส่ง
+type SalesPoint struct {
+	Period  string
+	Orders  int64
+	Revenue int64
+}
+
+// SalesSummary คือยอดขายรวมของทั้งช่วงเวลา Revenue นับแบบเดียวกับ SalesPoint
+type SalesSummary struct {
+	Orders       int64
+	Revenue      int64
+	NewCustomers int64
+}
+
+// ProductSales คือยอดขายของสินค้าหนึ่งตัว Revenue คือผลรวม Line_Total ก่อนหักส่วนลดของ order
+type ProductSales struct {
+	ProductID uint
+	Product   string
+	Units     int64
+	Revenue   int64
+}
+
+// CustomerPoint คือจำนวนลูกค้าที่มี order แรกในหนึ่งช่วง
+type CustomerPoint struct {
+	Period    string
+	Customers int64
+}
+
+// createdBetween กรองช่วงเวลาที่สร้าง โดย from รวมขอบเขต ส่วน to ไม่รวม
+func createdBetween(db *gorm.DB, from, to *time.Time) *gorm.DB {
+	return between(db, "created_at", from, to)
+}
+
+// between กรอง column ที่เป็นเวลาให้อยู่ในช่วง from (รวม) ถึง to (ไม่รวม)
+func between(db *gorm.DB, column string, from, to *time.Time) *gorm.DB {
+	if from != nil {
+		db = db.Where(column+" >= ?", *from)
+	}
+	if to != nil {
+		db = db.Where(column+" < ?", *to)
+	}
+	return db
+}
+
+// list นับจำนวนแถวที่ตรงกับ query แล้วโหลดหนึ่งหน้าตาม page พร้อม preload ความสัมพันธ์ที่ระบุ
+// โดยโหลดเกินมาหนึ่งแถวเพื่อรู้ว่ายังมีหน้าถัดไปไหม
+func list[T any](query *gorm.DB, page Page, preloads ...string) (Result[T], error) {
+	var result Result[T]
+
+	if err := query.Session(&gorm.Session{}).Count(&result.Total).Error; err != nil {
+		return result, err
+	}
+
+	column := page.Sort
+	if column == "" {
+		column = "id"
+	}
+
+	// เมื่อย้อนไปหน้าก่อนหน้าจะเรียงกลับด้านแล้วค่อยกลับลำดับผลลัพธ์ทีหลัง
+	desc := page.Desc
+	if page.Cursor != nil && page.Cursor.Before {
+		desc = !desc
+	}
+	direction, op := "ASC", ">"
+	if desc {
+		direction, op = "DESC", "<"
+	}
+
+	if page.Cursor != nil {
+		if column == "id" {
+			query = query.Where("id "+op+" ?", page.Cursor.ID)
+		} else {
+			query = query.Where(
+				fmt.Sprintf("((%[1]s %[2]s ?) OR (%[1]s = ? AND id %[2]s ?))", column, op),
+				page.Cursor.Value, page.Cursor.Value, page.Cursor.ID,
+			)
+		}
+	} else if page.Offset > 0 {
+		query = query.Offset(page.Offset)
+	}
+
+	query = query.Order(column + " " + direction)
+	if column != "id" {
+		query = query.Order("id " + direction)
+	}
+
+	for _, preload := range preloads {
+		query = query.Preload(preload)
+	}
+	if err := query.Limit(page.Limit + 1).Find(&result.Items).Error; err != nil {
+		return result, err
+	}
+
+	if len(result.Items) > page.Limit {
+		result.Items = result.Items[:page.Limit]
+		result.More = true
+	}
+	if page.Cursor != nil && page.Cursor.Before {
+		slices.Reverse(result.Items)
+	}
+	return result, nil
+}
+
+// batchSize คือจำนวนแถวที่โหลดต่อครั้งเมื่อไล่อ่านทุกแถวด้วย each
+const batchSize = 500
+
+// each โหลดทุกแถวที่ตรงกับ query ทีละ batch เรียงตาม id พร้อม preload ความสัมพันธ์ที่ระบุ แล้วส่งให้ fn
+// ใช้กับการ export ที่ไม่ควรโหลดทุกแถวไว้ใน memory พร้อมกัน ถ้า fn คืน error จะหยุดและส่ง error นั้นกลับ
+func each[T any](query *gorm.DB, fn func(batch []T) error, preloads ...string) error {
+	for _, preload := range preloads {
+		query = query.Preload(preload)
+	}
+	var batch []T
+	return query.FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
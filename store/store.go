@@ -0,0 +1,217 @@
+package store
+
+import (
+	"context"
+	"errors"
+	m "go-fiber-test/models"
+	"time"
+)
+
+// ErrNotFound ถูกส่งกลับเมื่อไม่พบข้อมูลที่ค้นหา ไม่ว่า store จะใช้ฐานข้อมูลแบบไหน
+var ErrNotFound = errors.New("store: record not found")
+
+// ErrInsufficientStock ถูกส่งกลับเมื่อลดจำนวนสินค้าแล้วจะติดลบ ซึ่งจะไม่มีการเปลี่ยนแปลงใด ๆ
+var ErrInsufficientStock = errors.New("store: insufficient stock")
+
+// ErrVersionConflict ถูกส่งกลับเมื่อแถวถูกบันทึกโดย request อื่นไปแล้วหลังจากที่โหลดมา (version ไม่ตรง)
+// ซึ่งจะไม่มีการเปลี่ยนแปลงใด ๆ
+var ErrVersionConflict = errors.New("store: version conflict")
+
+// ErrUsageLimit ถูกส่งกลับเมื่อ coupon ถูกใช้ครบจำนวนครั้งที่กำหนดแล้ว
+var ErrUsageLimit = errors.New("store: coupon usage limit reached")
+
+// Store รวม repository ทั้งหมดที่ controller ใช้งาน
+type Store interface {
+	Products() ProductStore
+	Orders() OrderStore
+	Users() UserStore
+	Sessions() SessionStore
+	Carts() CartStore
+	Payments() PaymentStore
+	Coupons() CouponStore
+	Invoices() InvoiceStore
+	Reports() ReportStore
+	Idempotency() IdempotencyStore
+
+	// Ping ตรวจสอบว่ายังเชื่อมต่อกับฐานข้อมูลได้อยู่ ใช้กับ readiness probe
+	Ping(ctx context.Context) error
+
+	// Transaction รัน fn ใน transaction เดียว ถ้า fn คืน error ทุกอย่างที่ทำผ่าน tx จะถูก rollback
+	// และ error นั้นจะถูกส่งกลับไปตามเดิม
+	Transaction(fn func(tx Store) error) error
+}
+
+// ProductStore คือที่เก็บสินค้า ทุก method ที่เปลี่ยนจำนวนสินค้า จำนวนที่ถูกกันไว้ หรือรูปภาพจะเพิ่ม Version ของ product ด้วย
+// เพราะข้อมูลเหล่านี้อยู่ใน response ของ product และ ETag ต้องเปลี่ยนตาม
+type ProductStore interface {
+	// List คืน product หนึ่งหน้าที่ตรงกับ filter พร้อม images
+	List(filter ProductFilter, page Page) (Result[m.Product], error)
+	// Each ส่ง product ทุกตัวที่ตรงกับ filter ให้ fn ทีละ batch เรียงตาม id พร้อม images
+	Each(filter ProductFilter, fn func(batch []m.Product) error) error
+	// Get คืน product ที่ยังไม่ถูกลบพร้อม images
+	Get(id uint) (*m.Product, error)
+	// GetForUpdate เหมือน Get แต่ไม่โหลด images และ lock แถวของ product ไว้จนจบ transaction (SELECT ... FOR UPDATE)
+	GetForUpdate(id uint) (*m.Product, error)
+	GetByName(name string) (*m.Product, error)
+	// GetDeleted คืน product ที่ถูก soft delete ไปแล้วพร้อม images
+	GetDeleted(id uint) (*m.Product, error)
+	// Create สร้าง product และบันทึกจำนวนเริ่มต้นเป็น movement แรกใน ledger
+	Create(product *m.Product, actorID *uint) error
+	// Save บันทึก field ของ product โดยไม่แตะ images และ amount ซึ่งต้องเปลี่ยนผ่าน ledger
+	// แล้วเพิ่ม Version ถ้าแถวถูกบันทึกไปแล้วหลังจากโหลดมาจะคืน ErrVersionConflict
+	Save(product *m.Product) error
+	// AdjustStock เพิ่ม (Delta > 0) หรือลด (Delta < 0) จำนวนสินค้าของ move.ProductID แบบ atomic ในคำสั่งเดียว
+	// แล้วบันทึก move พร้อม Balance ลง ledger ใน transaction เดียวกัน
+	// ถ้าสินค้าไม่พอจะคืน ErrInsufficientStock ทำให้ไม่มีทางขายเกินแม้มีหลาย request พร้อมกัน
+	AdjustStock(move *m.StockMovement) error
+	// Restock คืนสินค้าเข้าคลังตาม move.Delta แม้สินค้าจะถูก soft delete ไปแล้ว เพื่อให้สต็อกถูกต้องเมื่อกู้คืน
+	// ถ้าสินค้าถูกลบถาวรไปแล้วจะไม่ทำอะไรและไม่บันทึก move
+	Restock(move *m.StockMovement) error
+	// Reserve กันสินค้าไว้ amount ชิ้น (ติดลบคือปล่อยคืน) แบบ atomic โดยไม่ให้เกินจำนวนที่ยังขายได้
+	// ถ้าสินค้าไม่พอจะคืน ErrInsufficientStock
+	Reserve(id uint, amount int) error
+	// Release ปล่อยสินค้าที่กันไว้คืน แม้สินค้าจะถูก soft delete ไปแล้ว
+	Release(id uint, amount int) error
+	// ConfirmReservation เปลี่ยนสินค้าที่กันไว้เป็นการตัดสต็อกจริง (move.Delta ติดลบ) และบันทึก move ลง ledger
+	// ถ้าสินค้าถูกลบถาวรไปแล้วจะไม่ทำอะไรและไม่บันทึก move
+	ConfirmReservation(move *m.StockMovement) error
+	// SetStock ตั้งจำนวนสินค้าเป็น amount และบันทึกส่วนต่างเป็น move.Delta ถ้าจำนวนไม่เปลี่ยนจะไม่บันทึก move
+	// ถ้า amount น้อยกว่าจำนวนที่ถูกกันไว้จะคืน ErrInsufficientStock
+	SetStock(move *m.StockMovement, amount int) error
+	// Movements คืนประวัติการเปลี่ยนแปลงสต็อกของ product หนึ่งหน้า
+	Movements(productID uint, page Page) (Result[m.StockMovement], error)
+	// LedgerBalance คืนผลรวม Delta ทั้งหมดใน ledger ของ product ซึ่งควรเท่ากับ amount
+	LedgerBalance(productID uint) (int, error)
+	// Reconcile ตั้ง amount ให้เท่ากับผลรวมใน ledger โดยไม่บันทึก move เพิ่ม แล้วคืนจำนวนนั้น
+	Reconcile(id uint) (int, error)
+	// SoftDelete soft delete product พร้อม images ถ้า product ถูกบันทึกไปแล้วหลังจากโหลดมาจะคืน ErrVersionConflict
+	SoftDelete(product *m.Product) error
+	// Restore กู้คืน product ที่ถูก soft delete พร้อม images และเพิ่ม Version เฉพาะเมื่อ Version ยังเป็น version
+	// ถ้าถูกแก้หรือกู้คืนไปแล้วจะคืน ErrVersionConflict
+	Restore(id uint, version int) (*m.Product, error)
+	// HardDelete ลบ product และ images ออกจากฐานข้อมูลถาวร
+	HardDelete(product *m.Product) error
+
+	GetImage(productID, imageID uint) (*m.ProductImage, error)
+	// AddImage และ DeleteImage เลื่อน UpdatedAt ของ product ไปด้วยเพราะรูปภาพเป็นส่วนหนึ่งของ product
+	AddImage(image *m.ProductImage) error
+	DeleteImage(image *m.ProductImage) error
+}
+
+type OrderStore interface {
+	// List คืน order หนึ่งหน้าที่ตรงกับ filter พร้อม items
+	List(filter OrderFilter, page Page) (Result[m.Order], error)
+	// Each ส่ง order ทุกตัวที่ตรงกับ filter ให้ fn ทีละ batch เรียงตาม id พร้อม items
+	Each(filter OrderFilter, fn func(batch []m.Order) error) error
+	ListByBuyer(buyer string) ([]m.Order, error)
+	Get(id uint) (*m.Order, error)
+	// GetForUpdate เหมือน Get แต่ lock แถวของ order ไว้จนจบ transaction (SELECT ... FOR UPDATE)
+	// ใช้ภายใน Transaction เพื่อไม่ให้แก้ไข order เดียวกันพร้อมกัน
+	GetForUpdate(id uint) (*m.Order, error)
+	// Create สร้าง order พร้อม items
+	Create(order *m.Order) error
+	// Save บันทึก field ของ order โดยไม่แตะ items แล้วเพิ่ม Version
+	// ถ้าแถวถูกบันทึกไปแล้วหลังจากโหลดมาจะคืน ErrVersionConflict
+	Save(order *m.Order) error
+	CreateItem(item *m.Item) error
+	SaveItem(item *m.Item) error
+	// Delete ลบ order และ items ออกจากฐานข้อมูลถาวร
+	Delete(order *m.Order) error
+	// ListExpiredReservations คืน order ที่การกันสินค้าหมดเวลาแล้ว ณ เวลา now (ไม่รวม items)
+	ListExpiredReservations(now time.Time) ([]m.Order, error)
+}
+
+type UserStore interface {
+	List(filter UserFilter, page Page) (Result[m.User], error)
+	// Each ส่ง user ทุกคนที่ตรงกับ filter ให้ fn ทีละ batch เรียงตาม id
+	Each(filter UserFilter, fn func(batch []m.User) error) error
+	Get(id uint) (*m.User, error)
+	GetByUsername(username string) (*m.User, error)
+	// GetDeleted คืน user ที่ถูก soft delete ไปแล้ว
+	GetDeleted(id uint) (*m.User, error)
+	Create(user *m.User) error
+	// Save บันทึก user แล้วเพิ่ม Version ถ้าแถวถูกบันทึกไปแล้วหลังจากโหลดมาจะคืน ErrVersionConflict
+	Save(user *m.User) error
+	// SoftDelete soft delete user ถ้า user ถูกบันทึกไปแล้วหลังจากโหลดมาจะคืน ErrVersionConflict
+	SoftDelete(user *m.User) error
+	// Restore กู้คืน user ที่ถูก soft delete และเพิ่ม Version เฉพาะเมื่อ Version ยังเป็น version
+	// ถ้าถูกแก้หรือกู้คืนไปแล้วจะคืน ErrVersionConflict
+	Restore(id uint, version int) (*m.User, error)
+	HardDelete(user *m.User) error
+}
+
+type CartStore interface {
+	// List คืนรายการในตะกร้าของ user เรียงตามลำดับที่เพิ่มเข้ามา
+	List(userID uint) ([]m.CartItem, error)
+	Get(userID, productID uint) (*m.CartItem, error)
+	// Save สร้างหรือแก้ไขรายการในตะกร้า
+	Save(item *m.CartItem) error
+	Delete(item *m.CartItem) error
+	// Clear ลบทุกรายการในตะกร้าของ user
+	Clear(userID uint) error
+}
+
+// ReportStore รวมยอดขายจาก order ที่อยู่ใน models.SalesStatuses ด้วย aggregate ในฐานข้อมูล
+// ช่วงเวลาของ order ใช้เวลาที่สร้าง order และแบ่งช่วงตาม time zone ของฐานข้อมูล
+type ReportStore interface {
+	// Sales คืนจำนวน order และยอดขายของแต่ละช่วงที่มีข้อมูล เรียงตามช่วงเวลา
+	Sales(filter ReportFilter) ([]SalesPoint, error)
+	// Summary คืนยอดรวมของทั้งช่วงเวลาโดยไม่สนใจ Interval
+	Summary(filter ReportFilter) (SalesSummary, error)
+	// TopProducts คืนสินค้าที่ขายได้มากที่สุดเรียงตาม by (units หรือ revenue) ไม่เกิน limit ตัว
+	TopProducts(filter ReportFilter, by string, limit int) ([]ProductSales, error)
+	// NewCustomers คืนจำนวนลูกค้าที่มี order แรกในแต่ละช่วงที่มีข้อมูล
+	NewCustomers(filter ReportFilter) ([]CustomerPoint, error)
+}
+
+type InvoiceStore interface {
+	// GetOrCreate คืนใบแจ้งหนี้ของ invoice.OrderID พร้อมรายการสินค้า ถ้ายังไม่เคยออกให้ order นี้
+	// จะบันทึก invoice เป็นใบใหม่และออกเลขที่ให้
+	GetOrCreate(invoice *m.Invoice) (*m.Invoice, error)
+}
+
+type PaymentStore interface {
+	Create(payment *m.Payment) error
+	Save(payment *m.Payment) error
+	// GetByProviderRef คืน payment จาก ID ของ payment intent ฝั่ง provider
+	GetByProviderRef(provider, ref string) (*m.Payment, error)
+	ListByOrder(orderID uint) ([]m.Payment, error)
+}
+
+type CouponStore interface {
+	List(page Page) (Result[m.Coupon], error)
+	Get(id uint) (*m.Coupon, error)
+	// GetAny เหมือน Get แต่รวม coupon ที่ถูกลบไปแล้ว ใช้กับ order ที่เคยใช้ coupon นั้น
+	GetAny(id uint) (*m.Coupon, error)
+	GetByCode(code string) (*m.Coupon, error)
+	// CodeExists ตรวจว่ามี code นี้อยู่แล้วหรือไม่ รวม coupon ที่ถูกลบไปแล้วเพราะ code ต้องไม่ซ้ำ
+	CodeExists(code string) (bool, error)
+	Create(coupon *m.Coupon) error
+	Save(coupon *m.Coupon) error
+	Delete(coupon *m.Coupon) error
+	// Redeem เพิ่มจำนวนครั้งที่ใช้แบบ atomic ถ้าครบ UsageLimit แล้วจะคืน ErrUsageLimit
+	Redeem(id uint) error
+	// Release คืนสิทธิ์ที่ order ใช้ coupon ไป เช่นเมื่อ order ถูกยกเลิก
+	Release(id, orderID uint) error
+	CountRedemptions(couponID, userID uint) (int64, error)
+	CreateRedemption(redemption *m.CouponRedemption) error
+}
+
+type SessionStore interface {
+	Get(userID uint) (*m.Session, error)
+	// Touch สร้าง session ถ้ายังไม่มี และตั้ง LastActive เป็นเวลาที่กำหนด
+	Touch(userID uint, at time.Time) error
+	Delete(userID uint) error
+}
+
+type IdempotencyStore interface {
+	// Begin จองคีย์ของ record.UserID ถ้ายังไม่มีใครใช้คีย์นี้ (หรือ record เดิมหมดอายุแล้ว) จะสร้าง record และคืน (record, true)
+	// ถ้ามีอยู่แล้วจะคืน record เดิมกับ false
+	Begin(record *m.IdempotencyKey, now time.Time) (*m.IdempotencyKey, bool, error)
+	// Complete บันทึก response ของ record ที่ Begin ไว้
+	Complete(record *m.IdempotencyKey) error
+	// Abandon ลบ record ที่ยังไม่เสร็จ เพื่อให้ส่ง request เดิมซ้ำได้เมื่อ request แรกล้มเหลว
+	Abandon(record *m.IdempotencyKey) error
+	// DeleteExpired ลบ record ที่หมดอายุแล้ว ณ เวลา now และคืนจำนวนที่ลบ
+	DeleteExpired(now time.Time) (int64, error)
+}
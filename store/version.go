@@ -0,0 +1,59 @@
+package store
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// saveVersioned บันทึก value ทั้งแถว (ยกเว้น column ใน omit) เฉพาะเมื่อ version ในฐานข้อมูลยังเท่ากับตอนที่โหลดมา
+// แล้วเพิ่ม *version ถ้าไม่มีแถวไหนถูกบันทึกจะคืน ErrVersionConflict และคืนค่า *version เดิม
+func saveVersioned(db *gorm.DB, value any, version *int, omit ...string) error {
+	current := *version
+	*version = current + 1
+
+	// Select("*") ทำให้ Save ไม่ fallback ไปเป็น INSERT เมื่อ WHERE ไม่ตรงกับแถวไหน
+	result := db.Select("*").Omit(omit...).Where("version = ?", current).Save(value)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrVersionConflict
+	}
+	if result.Error != nil {
+		*version = current
+	}
+	return result.Error
+}
+
+// softDeleteVersioned soft delete value และเพิ่ม *version ใน UPDATE เดียวกัน เฉพาะเมื่อ version ในฐานข้อมูลยังเท่ากับตอนที่โหลดมา
+// ETag หลังกู้คืนจึงไม่ซ้ำกับก่อนลบ ถ้าไม่มีแถวไหนถูกลบจะคืน ErrVersionConflict
+func softDeleteVersioned(db *gorm.DB, value any, version *int) error {
+	current := *version
+	result := db.Model(value).Where("version = ?", current).Updates(map[string]any{
+		"deleted_at": time.Now(),
+		"version":    current + 1,
+	})
+	// gorm คัดลอกค่าใน map กลับเข้า value ด้วย จึงตั้ง *version เองให้ตรงกับผลลัพธ์
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrVersionConflict
+	}
+	if result.Error != nil {
+		*version = current
+		return result.Error
+	}
+	*version = current + 1
+	return nil
+}
+
+// restoreVersioned กู้คืนแถว id ของ model ที่ถูก soft delete และเพิ่ม version ใน UPDATE เดียวกัน
+// เฉพาะเมื่อ version ในฐานข้อมูลยังเป็น version ถ้าไม่มีแถวไหนถูกกู้คืนจะคืน ErrVersionConflict
+func restoreVersioned(db *gorm.DB, model any, id uint, version int) error {
+	result := db.Unscoped().Model(model).
+		Where("id = ? AND version = ? AND deleted_at IS NOT NULL", id, version).
+		Updates(map[string]any{"deleted_at": nil, "version": nextVersion})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
@@ -0,0 +1,438 @@
+// Package testutil boot แอปทั้งตัวผ่าน routes.Routes บน sqlite in-memory ที่ถูกสร้างใหม่ทุก test
+// พร้อม helper สำหรับ register/approve/login, สร้างสินค้าพร้อมรูปภาพ และสั่งซื้อ โดยไม่ต้องมี MySQL
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go-fiber-test/apperr"
+	"go-fiber-test/config"
+	"go-fiber-test/database"
+	"go-fiber-test/dto"
+	"go-fiber-test/middleware"
+	"go-fiber-test/migrations"
+	m "go-fiber-test/models"
+	"go-fiber-test/payment"
+	"go-fiber-test/routes"
+	"go-fiber-test/store"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// DefaultPassword ใช้กับ user ที่ถูกสร้างผ่าน helper เมื่อไม่ได้ระบุ password
+const DefaultPassword = "secret123"
+
+// Harness คือแอปที่ถูก boot ขึ้นมาสำหรับ test หนึ่งตัว
+type Harness struct {
+	t      testing.TB
+	App    *fiber.App
+	DB     *gorm.DB
+	Store  store.Store
+	Config *config.Config
+}
+
+// Response คือผลลัพธ์ของ request ที่อ่าน body เก็บไว้แล้ว
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// JSON decode body ลงใน v และทำให้ test fail ถ้า body ไม่ใช่ JSON
+func (r *Response) JSON(t testing.TB, v any) {
+	t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		t.Fatalf("decoding response body %q: %v", r.Body, err)
+	}
+}
+
+// Map decode body เป็น map สำหรับตรวจสอบแบบไม่ต้องสร้าง struct
+func (r *Response) Map(t testing.TB) map[string]any {
+	t.Helper()
+	var out map[string]any
+	r.JSON(t, &out)
+	return out
+}
+
+// Tokens คือผลลัพธ์จากการ login
+type Tokens struct {
+	UserID       uint   `json:"userId"`
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// ItemRequest คือรายการสินค้าใน body ของ POST/PUT /order
+type ItemRequest struct {
+	ProductID uint   `json:"ProductID,omitempty"`
+	Product   string `json:"Product,omitempty"`
+	Amount    int    `json:"Amount"`
+}
+
+// Image คือไฟล์รูปภาพที่จะแนบไปกับ multipart form
+type Image struct {
+	Name    string
+	Content []byte
+}
+
+// Config คืนค่าตั้งค่าสำหรับ test: sqlite in-memory, upload dir ชั่วคราว และปิด rate limit ทั้งหมด
+func Config(t testing.TB) *config.Config {
+	cfg := config.Default()
+	cfg.App.Env = "test"
+	cfg.App.UploadDir = t.TempDir()
+	cfg.Database = config.DatabaseConfig{
+		Driver: config.DriverSQLite,
+		Name:   ":memory:",
+	}
+	cfg.Auth.AccessSecret = "test-access-secret"
+	cfg.Auth.RefreshSecret = "test-refresh-secret"
+	cfg.RateLimit.Max = 0
+	cfg.Catalog.RateLimit.Max = 0
+	cfg.Payment.WebhookSecret = "test-webhook-secret"
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return &cfg
+}
+
+// ConcurrentConfig เหมือน Config แต่ใช้ sqlite แบบไฟล์ใน temp dir ที่โหมด WAL ซึ่งเปิดได้หลาย connection
+// ใช้กับ test ที่ส่ง request พร้อมกันและต้องให้ request ทำงานซ้อนกันจริง (in-memory ใช้ได้ connection เดียว)
+func ConcurrentConfig(t testing.TB) *config.Config {
+	cfg := Config(t)
+	cfg.Database.Name = filepath.Join(t.TempDir(), "test.db") +
+		"?_pragma=foreign_keys(1)&_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)"
+	return cfg
+}
+
+// New boot แอปด้วยค่าตั้งค่าจาก Config
+func New(t testing.TB) *Harness {
+	return NewWithConfig(t, Config(t))
+}
+
+// NewWithConfig boot แอปด้วยค่าตั้งค่าที่กำหนดเอง ฐานข้อมูลจะถูก migrate และปิดเมื่อ test จบ
+func NewWithConfig(t testing.TB, cfg *config.Config) *Harness {
+	t.Helper()
+
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Logger = logger.Default.LogMode(logger.Silent)
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+
+	st := store.New(db)
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	catalog := middleware.NewCatalogCache(cfg.Catalog)
+	routes.Routes(app, cfg, st, catalog)
+
+	return &Harness{
+		t:      t,
+		App:    app,
+		DB:     db,
+		Store:  st,
+		Config: cfg,
+	}
+}
+
+// Do ส่ง request เข้าแอปโดยตรงและอ่าน body กลับมา
+func (h *Harness) Do(req *http.Request) *Response {
+	h.t.Helper()
+
+	resp, err := h.App.Test(req, -1)
+	if err != nil {
+		h.t.Fatalf("%s %s: %v", req.Method, req.URL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return &Response{Status: resp.StatusCode, Header: resp.Header, Body: body}
+}
+
+// Request ส่ง request ที่มี body เป็น JSON (ถ้า body ไม่ใช่ nil) พร้อม Bearer token (ถ้ามี)
+func (h *Harness) Request(method, path, token string, body any) *Response {
+	h.t.Helper()
+	return h.Do(h.NewRequest(method, path, token, body))
+}
+
+// NewRequest สร้าง request แบบเดียวกับ Request โดยยังไม่ส่ง เพื่อให้ test ใส่ header เพิ่มก่อนส่งด้วย Do
+func (h *Harness) NewRequest(method, path, token string, body any) *http.Request {
+	h.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			h.t.Fatal(err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", fiber.MIMEApplicationJSON)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+// Form ส่ง request แบบ multipart/form-data พร้อมไฟล์รูปภาพใน field "Images"
+func (h *Harness) Form(method, path, token string, fields map[string]string, images ...Image) *Response {
+	h.t.Helper()
+	return h.Do(h.NewForm(method, path, token, fields, images...))
+}
+
+// NewForm สร้าง request แบบเดียวกับ Form โดยยังไม่ส่ง เพื่อให้ test ใส่ header เพิ่มก่อนส่งด้วย Do
+func (h *Harness) NewForm(method, path, token string, fields map[string]string, images ...Image) *http.Request {
+	h.t.Helper()
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for key, value := range fields {
+		if err := writer.WriteField(key, value); err != nil {
+			h.t.Fatal(err)
+		}
+	}
+	for _, image := range images {
+		part, err := writer.CreateFormFile("Images", image.Name)
+		if err != nil {
+			h.t.Fatal(err)
+		}
+		if _, err := part.Write(image.Content); err != nil {
+			h.t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		h.t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+// Expect ทำให้ test fail ถ้า status ไม่ตรงกับที่คาดไว้
+func (h *Harness) Expect(resp *Response, status int) *Response {
+	h.t.Helper()
+	if resp.Status != status {
+		h.t.Fatalf("expected status %d, got %d: %s", status, resp.Status, resp.Body)
+	}
+	return resp
+}
+
+// ExpectProblem ตรวจสอบว่า response เป็น problem+json ที่มี status และ code ตามที่คาดไว้
+func (h *Harness) ExpectProblem(resp *Response, status int, code string) apperr.Problem {
+	h.t.Helper()
+	h.Expect(resp, status)
+
+	if ct := resp.Header.Get(fiber.HeaderContentType); ct != apperr.MIMEProblemJSON {
+		h.t.Fatalf("expected Content-Type %s, got %q", apperr.MIMEProblemJSON, ct)
+	}
+	var problem apperr.Problem
+	resp.JSON(h.t, &problem)
+	if problem.Status != status || problem.Code != code {
+		h.t.Fatalf("expected problem %d %s, got %d %s: %s", status, code, problem.Status, problem.Code, resp.Body)
+	}
+	return problem
+}
+
+// Register สมัครสมาชิกผ่าน POST /user/register และคืน ID ของ user ใหม่
+func (h *Harness) Register(username, password string) uint {
+	h.t.Helper()
+
+	resp := h.Expect(h.Request(http.MethodPost, "/user/register", "", map[string]string{
+		"Username":  username,
+		"Password":  password,
+		"FirstName": "First " + username,
+		"LastName":  "Last " + username,
+	}), fiber.StatusCreated)
+
+	var out struct {
+		Data struct {
+			ID uint `json:"ID"`
+		} `json:"data"`
+	}
+	resp.JSON(h.t, &out)
+	return out.Data.ID
+}
+
+// CreateAdmin สร้าง admin ที่ approve แล้วลงฐานข้อมูลโดยตรง เพราะ API ไม่มีทางสร้าง admin
+func (h *Harness) CreateAdmin(username string) uint {
+	h.t.Helper()
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(DefaultPassword), bcrypt.MinCost)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	admin := m.User{
+		Username:  username,
+		Password:  string(hashed),
+		FirstName: "Admin",
+		LastName:  username,
+		Role:      "admin",
+		Approve:   true,
+	}
+	if err := h.Store.Users().Create(&admin); err != nil {
+		h.t.Fatal(err)
+	}
+	return admin.ID
+}
+
+// Approve approve user ผ่าน PUT /user/approve ด้วย token ของ admin
+func (h *Harness) Approve(adminToken string, userID uint) {
+	h.t.Helper()
+	h.Expect(h.Form(http.MethodPut, "/user/approve", adminToken, map[string]string{
+		"UserID": strconv.FormatUint(uint64(userID), 10),
+	}), fiber.StatusCreated)
+}
+
+// Login login ผ่าน POST /user/login และคืน token
+func (h *Harness) Login(username, password string) Tokens {
+	h.t.Helper()
+
+	resp := h.Expect(h.Request(http.MethodPost, "/user/login", "", map[string]string{
+		"Username": username,
+		"Password": password,
+	}), fiber.StatusCreated)
+
+	var tokens Tokens
+	resp.JSON(h.t, &tokens)
+	return tokens
+}
+
+// LoginAdmin สร้าง admin ใหม่แล้ว login ให้
+func (h *Harness) LoginAdmin(username string) Tokens {
+	h.t.Helper()
+	h.CreateAdmin(username)
+	return h.Login(username, DefaultPassword)
+}
+
+// LoginUser สมัคร user ใหม่ ให้ admin approve แล้ว login ให้
+func (h *Harness) LoginUser(adminToken, username string) Tokens {
+	h.t.Helper()
+	id := h.Register(username, DefaultPassword)
+	h.Approve(adminToken, id)
+	return h.Login(username, DefaultPassword)
+}
+
+// CreateProduct สร้างสินค้าผ่าน POST /product ด้วย token ของ admin
+func (h *Harness) CreateProduct(adminToken, name string, price, amount int, images ...Image) m.Product {
+	h.t.Helper()
+
+	resp := h.Expect(h.Form(http.MethodPost, "/product", adminToken, map[string]string{
+		"Product_Name": name,
+		"Price":        strconv.Itoa(price),
+		"Amount":       strconv.Itoa(amount),
+	}, images...), fiber.StatusCreated)
+
+	var out struct {
+		Data m.Product `json:"data"`
+	}
+	resp.JSON(h.t, &out)
+	return out.Data
+}
+
+// PlaceOrder สั่งซื้อผ่าน POST /order/:userId และคืน order ที่ถูกสร้าง
+func (h *Harness) PlaceOrder(tokens Tokens, items ...ItemRequest) m.Order {
+	h.t.Helper()
+
+	resp := h.Expect(h.Request(http.MethodPost, fmt.Sprintf("/order/%d", tokens.UserID), tokens.AccessToken, map[string]any{
+		"Items": items,
+	}), fiber.StatusCreated)
+
+	var out struct {
+		Data m.Order `json:"data"`
+	}
+	resp.JSON(h.t, &out)
+	return out.Data
+}
+
+// Pay สร้าง payment ของ order ผ่าน POST /order/:orderId/payment และคืน payment ที่ถูกสร้าง
+func (h *Harness) Pay(tokens Tokens, orderID uint) dto.PaymentResponse {
+	h.t.Helper()
+
+	resp := h.Expect(h.Request(http.MethodPost, fmt.Sprintf("/order/%d/payment", orderID), tokens.AccessToken, nil), fiber.StatusCreated)
+
+	var out struct {
+		Data dto.PaymentResponse `json:"data"`
+	}
+	resp.JSON(h.t, &out)
+	return out.Data
+}
+
+// Webhook ส่ง event เข้า POST /payment/webhook พร้อมลายเซ็นแบบเดียวกับ fake provider
+func (h *Harness) Webhook(event payment.Event) *Response {
+	h.t.Helper()
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return h.SignedWebhook(payload, payment.NewFake(h.Config.Payment.WebhookSecret).Sign(payload))
+}
+
+// SignedWebhook ส่ง payload ดิบเข้า POST /payment/webhook พร้อมลายเซ็นที่กำหนด
+func (h *Harness) SignedWebhook(payload []byte, signature string) *Response {
+	h.t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/payment/webhook", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", fiber.MIMEApplicationJSON)
+	req.Header.Set(payment.SignatureHeader, signature)
+	return h.Do(req)
+}
+
+// Product โหลดสินค้าจากฐานข้อมูลโดยตรง สำหรับตรวจสอบจำนวนคงเหลือ
+func (h *Harness) Product(id uint) *m.Product {
+	h.t.Helper()
+	product, err := h.Store.Products().Get(id)
+	if err != nil {
+		h.t.Fatalf("loading product %d: %v", id, err)
+	}
+	return product
+}
+
+// ExpireSession ย้อน LastActive ของ session ให้เก่ากว่า session timeout
+func (h *Harness) ExpireSession(userID uint) {
+	h.t.Helper()
+	past := time.Now().Add(-2 * h.Config.Auth.SessionTimeout)
+	if err := h.Store.Sessions().Touch(userID, past); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+// PNG คือรูปภาพขนาดเล็กสำหรับใช้ upload ใน test
+func PNG(name string) Image {
+	return Image{
+		Name: name,
+		Content: []byte{
+			0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a,
+		},
+	}
+}